@@ -0,0 +1,381 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// A static file with a fingerprinted path, such as `app.js` that is
+// served as `app.3f9a1c2b.js`.
+//
+type Asset struct {
+	// the logical name used in templates, such as `app.js`
+	Name string
+
+	// the fingerprinted path, relative to the base URL
+	Path string
+
+	// the Subresource Integrity hash, such as `sha384-...`, if known
+	Integrity string
+
+	// the file system and file the asset is served from, if any
+	fsys   fs.FS
+	source string
+}
+
+//
+// Resolves the logical names of static files to their fingerprinted
+// URLs and integrity hashes. Assets are read from the manifest of a
+// build tool, which maps logical names to hashed files, or by hashing
+// the files of a file system:
+//
+//  resolver := snowmark.NewAssetResolver("/static")
+//  err := resolver.LoadManifestFS(os.DirFS("dist"), "manifest.json")
+//
+//  // or
+//  err := resolver.HashFS(os.DirFS("public"), ".")
+//
+// The resolver is also an `http.Handler` that serves the fingerprinted
+// files it knows the file system of, with far-future cache headers.
+//
+type AssetResolver struct {
+	_baseURL string
+	_assets  map[string]*Asset
+	_paths   map[string]*Asset
+	_mutex   sync.RWMutex
+}
+
+//
+// Create a new asset resolver with the base URL that fingerprinted
+// paths are served under, such as `/static` or the URL of a CDN.
+//
+func NewAssetResolver(baseURL string) *AssetResolver {
+	return &AssetResolver{
+		_baseURL: strings.TrimRight(strings.TrimSpace(baseURL), "/"),
+		_assets:  make(map[string]*Asset),
+		_paths:   make(map[string]*Asset),
+	}
+}
+
+//
+// Return the base URL of this resolver.
+//
+func (resolver *AssetResolver) BaseURL() string {
+	return resolver._baseURL
+}
+
+//
+// Add an asset with its fingerprinted path and optional integrity
+// hash. An existing asset with the same name is replaced.
+//
+func (resolver *AssetResolver) AddAsset(name string, hashedPath string, integrity string) error {
+	return resolver.addAsset(&Asset{
+		Name:      name,
+		Path:      hashedPath,
+		Integrity: integrity,
+	})
+}
+
+func (resolver *AssetResolver) addAsset(asset *Asset) error {
+	asset.Name = cleanAssetPath(asset.Name)
+	if asset.Name == "" {
+		return errors.New("Asset name cannot be empty")
+	}
+
+	asset.Path = cleanAssetPath(asset.Path)
+	if asset.Path == "" {
+		return errors.New("Asset path cannot be empty: " + asset.Name)
+	}
+
+	resolver._mutex.Lock()
+	defer resolver._mutex.Unlock()
+
+	existing, exists := resolver._assets[asset.Name]
+	if exists {
+		delete(resolver._paths, existing.Path)
+	}
+
+	resolver._assets[asset.Name] = asset
+	resolver._paths[asset.Path] = asset
+	return nil
+}
+
+//
+// Read a JSON manifest that maps logical names to fingerprinted paths.
+// An entry is either the path, or an object with the path under `file`
+// and an optional `integrity` hash:
+//
+//  {
+//    "app.js": "app.3f9a1c2b.js",
+//    "app.css": { "file": "app.7d2e4f10.css", "integrity": "sha384-..." }
+//  }
+//
+func (resolver *AssetResolver) LoadManifest(reader io.Reader) error {
+	return resolver.loadManifest(reader, nil)
+}
+
+//
+// Read the JSON manifest with the given name from the file system. The
+// paths of the manifest are relative to the root of the file system,
+// which is also used to compute the integrity hashes that the manifest
+// does not have, and to serve the files.
+//
+func (resolver *AssetResolver) LoadManifestFS(fsys fs.FS, name string) error {
+	if fsys == nil {
+		return errors.New("File system is required to read the asset manifest")
+	}
+
+	file, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return resolver.loadManifest(file, fsys)
+}
+
+func (resolver *AssetResolver) loadManifest(reader io.Reader, fsys fs.FS) error {
+	if reader == nil {
+		return errors.New("Reader is required to read the asset manifest")
+	}
+
+	var entries map[string]interface{}
+	err := json.NewDecoder(reader).Decode(&entries)
+	if err != nil {
+		return err
+	}
+
+	assets := make([]*Asset, 0, len(entries))
+	for name, entry := range entries {
+		asset := &Asset{Name: name}
+
+		switch value := entry.(type) {
+		case string:
+			asset.Path = value
+
+		case map[string]interface{}:
+			asset.Path, _ = value["file"].(string)
+			asset.Integrity, _ = value["integrity"].(string)
+
+		default:
+			return errors.New("Invalid asset manifest entry: " + name)
+		}
+
+		if fsys != nil {
+			asset.fsys = fsys
+			asset.source = cleanAssetPath(asset.Path)
+
+			if asset.Integrity == "" {
+				data, err := fs.ReadFile(fsys, asset.source)
+				if err == nil {
+					asset.Integrity = integrityOf(data)
+				}
+			}
+		}
+
+		assets = append(assets, asset)
+	}
+
+	for _, asset := range assets {
+		err := resolver.addAsset(asset)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//
+// Hash all files in the given directory of the file system, and its
+// sub-directories. The logical name of a file is its path relative to
+// the directory, such as `js/app.js`, and its fingerprint is added to
+// the name of the file, such as `js/app.3f9a1c2b.js`. The files are
+// not renamed, and are served by the resolver under their fingerprinted
+// path.
+//
+func (resolver *AssetResolver) HashFS(fsys fs.FS, dir string) error {
+	if fsys == nil {
+		return errors.New("File system is required to hash assets")
+	}
+
+	if dir == "" {
+		dir = "."
+	}
+
+	return fs.WalkDir(fsys, dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			return nil
+		}
+
+		data, err := fs.ReadFile(fsys, filePath)
+		if err != nil {
+			return err
+		}
+
+		name := filePath
+		if dir != "." {
+			name = strings.TrimPrefix(filePath, strings.TrimSuffix(dir, "/")+"/")
+		}
+
+		return resolver.addAsset(&Asset{
+			Name:      name,
+			Path:      fingerprintPath(name, data),
+			Integrity: integrityOf(data),
+			fsys:      fsys,
+			source:    filePath,
+		})
+	})
+}
+
+//
+// Return the asset with the given logical name.
+//
+func (resolver *AssetResolver) Lookup(name string) (*Asset, error) {
+	resolver._mutex.RLock()
+	defer resolver._mutex.RUnlock()
+
+	asset, exists := resolver._assets[cleanAssetPath(name)]
+	if !exists {
+		return nil, errors.New("Asset not found: " + name)
+	}
+
+	return asset, nil
+}
+
+//
+// Return the fingerprinted URL of the asset with the given logical
+// name, under the base URL of the resolver.
+//
+func (resolver *AssetResolver) URL(name string) (string, error) {
+	asset, err := resolver.Lookup(name)
+	if err != nil {
+		return "", err
+	}
+
+	return resolver._baseURL + "/" + asset.Path, nil
+}
+
+//
+// Serve the fingerprinted files that the resolver knows the file
+// system of. The path of the request is the fingerprinted path, so
+// the handler is usually mounted with `http.StripPrefix` under the
+// base URL. As the content of a fingerprinted path never changes, the
+// response may be cached forever.
+//
+func (resolver *AssetResolver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	resolver._mutex.RLock()
+	asset, exists := resolver._paths[cleanAssetPath(r.URL.Path)]
+	resolver._mutex.RUnlock()
+
+	if !exists || asset.fsys == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	data, err := fs.ReadFile(asset.fsys, asset.source)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	http.ServeContent(w, r, asset.Path, time.Time{}, bytes.NewReader(data))
+}
+
+//
+// Set the asset resolver that the asset tags and functions use. A
+// `nil` resolver removes the resolver.
+//
+func (pageProcessor *HtmlPageProcessor) SetAssetResolver(resolver *AssetResolver) {
+	pageProcessor._assets = resolver
+}
+
+//
+// Return the asset resolver of this processor, if any.
+//
+func (pageProcessor *HtmlPageProcessor) GetAssetResolver() *AssetResolver {
+	return pageProcessor._assets
+}
+
+//
+// Return the fingerprinted URL of the asset with the given logical
+// name, with the base path of the processor prefixed to absolute URLs.
+//
+func (pageProcessor *HtmlPageProcessor) AssetURL(name string) (string, error) {
+	result, _, err := pageProcessor.resolveAsset(name)
+	return result, err
+}
+
+func (pageProcessor *HtmlPageProcessor) resolveAsset(name string) (string, *Asset, error) {
+	if pageProcessor._assets == nil {
+		return "", nil, errors.New("No asset resolver is set on the processor")
+	}
+
+	asset, err := pageProcessor._assets.Lookup(name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	result := pageProcessor._assets._baseURL + "/" + asset.Path
+	return pageProcessor.withBasePath(result), asset, nil
+}
+
+//
+// Return the Subresource Integrity hash of the data.
+//
+func integrityOf(data []byte) string {
+	sum := sha512.Sum384(data)
+	return "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+//
+// Add the fingerprint of the data to the name of the file, before its
+// extension: `js/app.js` becomes `js/app.3f9a1c2b.js`.
+//
+func fingerprintPath(name string, data []byte) string {
+	sum := sha512.Sum384(data)
+	fingerprint := hex.EncodeToString(sum[:4])
+
+	extension := path.Ext(name)
+	return strings.TrimSuffix(name, extension) + "." + fingerprint + extension
+}
+
+//
+// Clean the path of an asset, removing any leading slash.
+//
+func cleanAssetPath(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ""
+	}
+
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
@@ -0,0 +1,149 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssetResolverManifest(t *testing.T) {
+	resolver := NewAssetResolver("/static/")
+	assert.Equal(t, "/static", resolver.BaseURL())
+
+	err := resolver.LoadManifest(strings.NewReader(`{
+		"app.js": "app.3f9a1c2b.js",
+		"app.css": { "file": "/css/app.7d2e4f10.css", "integrity": "sha384-abc" }
+	}`))
+	assert.NoError(t, err)
+
+	result, err := resolver.URL("app.js")
+	assert.NoError(t, err)
+	assert.Equal(t, "/static/app.3f9a1c2b.js", result)
+
+	asset, err := resolver.Lookup("/app.css")
+	assert.NoError(t, err)
+	assert.Equal(t, "css/app.7d2e4f10.css", asset.Path)
+	assert.Equal(t, "sha384-abc", asset.Integrity)
+
+	_, err = resolver.URL("missing.js")
+	assert.Error(t, err)
+
+	assert.Error(t, resolver.LoadManifest(strings.NewReader(`{"a.js": 1}`)))
+	assert.Error(t, resolver.LoadManifest(strings.NewReader(`{"a.js": ""}`)))
+	assert.Error(t, resolver.LoadManifest(strings.NewReader(`[`)))
+	assert.Error(t, resolver.AddAsset("", "a.js", ""))
+}
+
+func TestAssetResolverFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"public/js/app.js":     {Data: []byte("console.log('hi');")},
+		"public/app.css":       {Data: []byte("body { margin: 0 }")},
+		"dist/manifest.json":   {Data: []byte(`{"app.js": "dist/app.1234abcd.js"}`)},
+		"dist/app.1234abcd.js": {Data: []byte("alert(1);")},
+	}
+
+	resolver := NewAssetResolver("")
+	assert.NoError(t, resolver.HashFS(fsys, "public"))
+
+	asset, err := resolver.Lookup("js/app.js")
+	assert.NoError(t, err)
+	assert.Regexp(t, `^js/app\.[0-9a-f]{8}\.js$`, asset.Path)
+	assert.Equal(t, integrityOf([]byte("console.log('hi');")), asset.Integrity)
+	assert.True(t, strings.HasPrefix(asset.Integrity, "sha384-"))
+
+	// serve the fingerprinted file
+	recorder := httptest.NewRecorder()
+	resolver.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/"+asset.Path, nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "console.log('hi');", recorder.Body.String())
+	assert.Contains(t, recorder.Header().Get("Cache-Control"), "immutable")
+
+	recorder = httptest.NewRecorder()
+	resolver.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/js/app.js", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	// manifest in a file system computes the integrity
+	resolver = NewAssetResolver("https://cdn.example.com")
+	assert.NoError(t, resolver.LoadManifestFS(fsys, "dist/manifest.json"))
+
+	asset, err = resolver.Lookup("app.js")
+	assert.NoError(t, err)
+	assert.Equal(t, integrityOf([]byte("alert(1);")), asset.Integrity)
+
+	result, _ := resolver.URL("app.js")
+	assert.Equal(t, "https://cdn.example.com/dist/app.1234abcd.js", result)
+
+	assert.Error(t, resolver.LoadManifestFS(fsys, "missing.json"))
+	assert.Error(t, resolver.HashFS(nil, "."))
+}
+
+func TestAssetTags(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "")
+	processor.ImportWithDefaultPrefix(AssetLibrary())
+	processor.SetStrictMode(true)
+
+	model := NewModel()
+	model.Put("script", "app.js")
+
+	// no resolver
+	_, err := processor.MergeHtml(`<head><asset:script src="app.js" /></head>`, model)
+	assert.Error(t, err)
+
+	resolver := NewAssetResolver("/static")
+	resolver.AddAsset("app.js", "app.3f9a1c2b.js", "sha384-abc")
+	resolver.AddAsset("app.css", "app.7d2e4f10.css", "")
+	processor.SetAssetResolver(resolver)
+	processor.SetBasePath("/shop")
+	assert.Equal(t, resolver, processor.GetAssetResolver())
+
+	html, err := processor.MergeHtml(`<head><asset:script expr:src="script" defer="" /></head>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<head><script src="/shop/static/app.3f9a1c2b.js" defer="" integrity="sha384-abc" crossorigin="anonymous"></script></head>`, html)
+
+	html, err = processor.MergeHtml(`<head><asset:script src="app.js" crossorigin="use-credentials" /></head>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<head><script src="/shop/static/app.3f9a1c2b.js" crossorigin="use-credentials" integrity="sha384-abc"></script></head>`, html)
+
+	// attribute values are escaped
+	model.Put("media", `print" onload="alert(1)`)
+	html, err = processor.MergeHtml(`<head><asset:style href="app.css" expr:media="media" /></head>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<head><link rel="stylesheet" href="/shop/static/app.7d2e4f10.css" media="print&#34; onload=&#34;alert(1)" /></head>`, html)
+
+	html, err = processor.MergeHtml(`<head><asset:style href="app.css" media="print" /></head>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<head><link rel="stylesheet" href="/shop/static/app.7d2e4f10.css" media="print" /></head>`, html)
+
+	html, err = processor.MergeHtml(`<head><link rel="preload" expr:href='asset("app.js")' expr:integrity='assetIntegrity("app.js")' /></head>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<head><link rel="preload" href="/shop/static/app.3f9a1c2b.js" integrity="sha384-abc" /></head>`, html)
+
+	for _, template := range []string{
+		`<head><asset:script src="missing.js" /></head>`,
+		`<head><asset:script /></head>`,
+		`<head><get var='asset()' /></head>`,
+		`<head><get var='asset(1)' /></head>`,
+		`<head><get var='assetIntegrity("missing.js")' /></head>`,
+	} {
+		_, err = processor.MergeHtml(template, model)
+		assert.Error(t, err, template)
+	}
+
+	assert.Equal(t, []string{"script", "style"}, AssetLibrary().TagNames())
+}
@@ -0,0 +1,190 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"errors"
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/sangupta/lhtml"
+)
+
+//
+// Directive attribute that sets the `lang` and `dir` attributes of an
+// element from a locale. An empty value uses the locale of the merge,
+// otherwise the value is an expression for the locale:
+//
+//  <html s:lang>
+//  <blockquote s:lang="comment.locale">
+//
+// Attributes that are already present on the element are kept.
+//
+const LangDirective = "s:lang"
+
+//
+// Directive attribute that sets the `dir` attribute of an element from
+// the direction of a text, given as an expression, such as the content
+// the element shows:
+//
+//  <p s:dir="comment.body">
+//
+const DirDirective = "s:dir"
+
+//
+// The directions of text.
+//
+const (
+	DirectionLeftToRight = "ltr"
+	DirectionRightToLeft = "rtl"
+	DirectionAuto        = "auto"
+)
+
+//
+// Languages written from right to left, unless a script is given.
+//
+var rightToLeftLanguages = map[string]bool{
+	"ar":  true,
+	"ckb": true,
+	"dv":  true,
+	"fa":  true,
+	"he":  true,
+	"iw":  true,
+	"ks":  true,
+	"ps":  true,
+	"sd":  true,
+	"ug":  true,
+	"ur":  true,
+	"yi":  true,
+}
+
+//
+// Scripts written from right to left.
+//
+var rightToLeftScripts = map[string]bool{
+	"Adlm": true,
+	"Arab": true,
+	"Hebr": true,
+	"Nkoo": true,
+	"Rohg": true,
+	"Syrc": true,
+	"Thaa": true,
+}
+
+//
+// Check if the locale is written from right to left, such as Arabic
+// and Hebrew. A script in the locale, such as `az-Arab`, takes
+// precedence over the language.
+//
+func IsRightToLeft(locale string) bool {
+	parts := strings.Split(NormalizeLocale(locale), "-")
+	for _, part := range parts[1:] {
+		if len(part) == 4 {
+			return rightToLeftScripts[part]
+		}
+	}
+
+	return rightToLeftLanguages[parts[0]]
+}
+
+//
+// Return the direction of the locale, `rtl` or `ltr`.
+//
+func Direction(locale string) string {
+	if IsRightToLeft(locale) {
+		return DirectionRightToLeft
+	}
+
+	return DirectionLeftToRight
+}
+
+//
+// Return the direction of a text from its first strong character:
+// `rtl` for Hebrew or Arabic letters, `ltr` for other letters, and
+// `auto` if the text has no letters at all.
+//
+func DetectDirection(text string) string {
+	for _, r := range text {
+		if unicode.In(r, unicode.Hebrew, unicode.Arabic, unicode.Syriac, unicode.Thaana, unicode.Nko, unicode.Adlam) {
+			return DirectionRightToLeft
+		}
+
+		if unicode.IsLetter(r) {
+			return DirectionLeftToRight
+		}
+	}
+
+	return DirectionAuto
+}
+
+//
+// Return the direction of the locale of the current merge.
+//
+func (evaluator *Evaluator) Direction() string {
+	return Direction(evaluator.Locale())
+}
+
+//
+// Return the `lang` and `dir` attributes that the directives of the
+// node add, skipping attributes the node already has.
+//
+func (evaluator *Evaluator) directiveAttributes(node *lhtml.HtmlNode, model *Model) ([]*lhtml.HtmlAttribute, error) {
+	lang := node.GetAttribute(LangDirective)
+	dir := node.GetAttribute(DirDirective)
+	if lang == nil && dir == nil {
+		return nil, nil
+	}
+
+	hasAttribute := func(name string) bool {
+		return node.GetAttribute(name) != nil || node.GetAttribute(PREFIX+name) != nil
+	}
+
+	attributes := make([]*lhtml.HtmlAttribute, 0, 2)
+	direction := ""
+
+	if dir != nil && strings.TrimSpace(dir.Value) != "" {
+		text, err := evaluator.EvaluateExpressionAsString(dir.Value, model)
+		if err != nil {
+			return nil, err
+		}
+		direction = DetectDirection(text)
+	}
+
+	if lang != nil {
+		locale := evaluator.Locale()
+		if strings.TrimSpace(lang.Value) != "" {
+			value, err := evaluator.EvaluateExpressionAsString(lang.Value, model)
+			if err != nil {
+				return nil, err
+			}
+			locale = NormalizeLocale(value)
+			if locale != "" && !isWellFormedLocale(locale) {
+				return nil, errors.New("Invalid locale in '" + LangDirective + "': " + value)
+			}
+		}
+
+		if locale != "" && !hasAttribute("lang") {
+			attributes = append(attributes, &lhtml.HtmlAttribute{Name: "lang", Value: html.EscapeString(locale)})
+		}
+
+		if locale != "" && direction == "" {
+			direction = Direction(locale)
+		}
+	}
+
+	if direction != "" && !hasAttribute("dir") {
+		attributes = append(attributes, &lhtml.HtmlAttribute{Name: "dir", Value: direction})
+	}
+
+	return attributes, nil
+}
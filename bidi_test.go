@@ -0,0 +1,128 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirection(t *testing.T) {
+	assert.True(t, IsRightToLeft("ar"))
+	assert.True(t, IsRightToLeft("he-IL"))
+	assert.True(t, IsRightToLeft("fa_IR"))
+	assert.True(t, IsRightToLeft("az-Arab"))
+	assert.False(t, IsRightToLeft("az-Latn"))
+	assert.False(t, IsRightToLeft("en"))
+	assert.False(t, IsRightToLeft(""))
+
+	assert.Equal(t, "rtl", Direction("ar-EG"))
+	assert.Equal(t, "ltr", Direction("pt-BR"))
+
+	assert.Equal(t, "rtl", DetectDirection("123 שלום world"))
+	assert.Equal(t, "ltr", DetectDirection("  hello مرحبا"))
+	assert.Equal(t, "rtl", DetectDirection("مرحبا"))
+	assert.Equal(t, "auto", DetectDirection("123 !"))
+}
+
+func TestDirectives(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "")
+	processor.SetStrictMode(true)
+
+	model := NewModel()
+	model.Put("comment", map[string]interface{}{"locale": "he", "body": "שלום"})
+
+	// locale of the merge
+	html, err := processor.MergeHtmlWithOptions(`<html s:lang><p>x</p></html>`, model, &MergeOptions{Locale: "ar-EG"})
+	assert.NoError(t, err)
+	assert.Equal(t, `<html lang="ar-EG" dir="rtl"><p>x</p></html>`, html)
+
+	html, err = processor.MergeHtml(`<html s:lang=""><p>x</p></html>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<html lang="en" dir="ltr"><p>x</p></html>`, html)
+
+	// locale from an expression
+	html, err = processor.MergeHtml(`<blockquote s:lang="comment.locale">x</blockquote>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<blockquote lang="he" dir="rtl">x</blockquote>`, html)
+
+	// attributes of the element are kept
+	html, err = processor.MergeHtmlWithOptions(`<html s:lang dir="ltr"><p>x</p></html>`, model, &MergeOptions{Locale: "ar"})
+	assert.NoError(t, err)
+	assert.Equal(t, `<html dir="ltr" lang="ar"><p>x</p></html>`, html)
+
+	html, err = processor.MergeHtmlWithOptions(`<html s:lang expr:lang='"fa"'><p>x</p></html>`, model, &MergeOptions{Locale: "ar"})
+	assert.NoError(t, err)
+	assert.Equal(t, `<html lang="fa" dir="rtl"><p>x</p></html>`, html)
+
+	// direction of the content
+	html, err = processor.MergeHtml(`<p s:dir="comment.body"><get var="comment.body" /></p>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<p dir="rtl">שלום</p>`, html)
+
+	html, err = processor.MergeHtmlWithOptions(`<p s:lang s:dir='"hello"'>x</p>`, model, &MergeOptions{Locale: "ar"})
+	assert.NoError(t, err)
+	assert.Equal(t, `<p lang="ar" dir="ltr">x</p>`, html)
+
+	_, err = processor.MergeHtml(`<p s:dir="comment.(">x</p>`, model)
+	assert.Error(t, err)
+
+	_, err = processor.MergeHtml(`<p s:lang="missing.locale">x</p>`, model)
+	assert.Error(t, err)
+
+	// locales that are not language tags
+	model.Put("user", map[string]interface{}{"loc": `en" onmouseover="alert(1)`})
+	_, err = processor.MergeHtml(`<div s:lang="user.loc">x</div>`, model)
+	assert.Error(t, err)
+
+	html, err = processor.MergeHtmlWithOptions(`<p s:lang>x</p>`, model, &MergeOptions{Locale: `en"><script>`})
+	assert.NoError(t, err)
+	assert.NotContains(t, html, "<script>")
+
+	// directives with invalid expressions are reported by validation
+	diagnostics := processor.Validate(`<p s:lang s:dir="comment.(">x</p>`)
+	assert.Equal(t, 1, len(diagnostics))
+}
+
+func TestBidiTag(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "")
+	processor.Import(I18nLibrary(), "")
+	processor.SetStrictMode(true)
+
+	model := NewModel()
+	model.Put("author", "<דוד>")
+
+	html, err := processor.MergeHtml(`<p>By <bidi var="author" />!</p>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<p>By <bdi>&lt;דוד&gt;</bdi>!</p>`, html)
+
+	html, err = processor.MergeHtml(`<p>By <bidi dir="rtl"><b>x</b></bidi>!</p>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<p>By <bdi dir="rtl"><b>x</b></bdi>!</p>`, html)
+
+	html, err = processor.MergeHtml(`<p>By <bidi var="author" mode="unicode" />!</p>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>By \u2068&lt;דוד&gt;\u2069!</p>", html)
+
+	html, err = processor.MergeHtml(`<p>By <bidi mode="unicode" dir="ltr">x</bidi>|<bidi mode="unicode" dir="rtl">y</bidi></p>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>By \u2066x\u2069|\u2067y\u2069</p>", html)
+
+	_, err = processor.MergeHtml(`<p><bidi var="author" mode="fancy" /></p>`, model)
+	assert.Error(t, err)
+
+	_, err = processor.MergeHtml(`<p><bidi var="author" dir="up" /></p>`, model)
+	assert.Error(t, err)
+}
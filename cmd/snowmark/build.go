@@ -0,0 +1,377 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/sangupta/snowmark"
+	"gopkg.in/yaml.v3"
+)
+
+//
+// Default name of the manifest written to the output directory.
+//
+const defaultManifest = "snowmark-manifest.json"
+
+//
+// The model key under which the data of the current page is
+// available, such as `page.path` and the front matter values.
+//
+const pageModelKey = "page"
+
+//
+// Options of the build command.
+//
+type buildOptions struct {
+	options
+	source   string
+	manifest string
+}
+
+//
+// A file written by the build, as listed in the manifest.
+//
+type manifestEntry struct {
+	Path   string `json:"path"`
+	Type   string `json:"type"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+//
+// The manifest of a build, listing all files of the output
+// directory sorted by path.
+//
+type manifest struct {
+	Files []*manifestEntry `json:"files"`
+}
+
+func parseBuildOptions(args []string, stderr io.Writer) (*buildOptions, error) {
+	opts := &buildOptions{}
+
+	flags := flag.NewFlagSet("snowmark build", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&opts.model, "model", "", "site-wide model file in JSON, YAML or TOML format")
+	flags.StringVar(&opts.prefix, "prefix", "s", "prefix of the standard tags, such as `s` for `<s:get>`; empty for none")
+	flags.StringVar(&opts.manifest, "manifest", defaultManifest, "name of the manifest written to the output directory; empty for none")
+	flags.BoolVar(&opts.strict, "strict", false, "stop at the first error instead of skipping the failing tag")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: snowmark build [flags] <source directory> <output directory>")
+		flags.PrintDefaults()
+	}
+
+	err := flags.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return nil, errors.New("Source and output directories are required")
+	}
+
+	opts.source = flags.Arg(0)
+	opts.output = flags.Arg(1)
+	opts.mode = modeRender
+	return opts, nil
+}
+
+//
+// Build a static site from the source directory into the output
+// directory. Every page template is rendered with the site-wide model
+// and the front matter of the page. Files and directories whose name
+// starts with an underscore, such as partials and layouts, are skipped;
+// all other files are copied as they are. Files listed in the manifest
+// of a previous build that are no longer produced are removed.
+//
+func build(opts *buildOptions, stderr io.Writer) error {
+	info, err := os.Stat(opts.source)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return errors.New("Source must be a directory: " + opts.source)
+	}
+
+	source, err := filepath.Abs(opts.source)
+	if err != nil {
+		return err
+	}
+
+	output, err := filepath.Abs(opts.output)
+	if err != nil {
+		return err
+	}
+
+	if output == source || strings.HasPrefix(output, source+string(filepath.Separator)) {
+		return errors.New("Output directory cannot be inside the source directory")
+	}
+
+	processor, err := newProcessor(&opts.options, source)
+	if err != nil {
+		return err
+	}
+
+	site, err := readModel(opts.model)
+	if err != nil {
+		return err
+	}
+
+	previous := readManifest(filepath.Join(output, opts.manifest), opts.manifest != "")
+	current := &manifest{
+		Files: make([]*manifestEntry, 0),
+	}
+
+	// WalkDir visits files in lexical order, keeping the build deterministic
+	err = filepath.WalkDir(source, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path == source {
+			return nil
+		}
+
+		if strings.HasPrefix(entry.Name(), "_") || strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if entry.IsDir() {
+			return nil
+		}
+
+		relative, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+
+		name := filepath.ToSlash(relative)
+		if name == opts.manifest {
+			return errors.New("Source file conflicts with the manifest: " + name)
+		}
+
+		var data []byte
+		fileType := "asset"
+		if isPage(entry.Name()) {
+			fileType = "page"
+			data, err = buildPage(processor, site, path, name, warningOptions(stderr))
+		} else {
+			data, err = os.ReadFile(path)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		err = writeFile(filepath.Join(output, relative), data)
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(data)
+		current.Files = append(current.Files, &manifestEntry{
+			Path:   name,
+			Type:   fileType,
+			Size:   len(data),
+			SHA256: hex.EncodeToString(sum[:]),
+		})
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	removeStaleFiles(output, previous, current)
+
+	if opts.manifest != "" {
+		sort.Slice(current.Files, func(i, j int) bool {
+			return current.Files[i].Path < current.Files[j].Path
+		})
+
+		data, err := json.MarshalIndent(current, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		err = writeFile(filepath.Join(output, opts.manifest), append(data, '\n'))
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(stderr, "built %d file(s) into %s\n", len(current.Files), opts.output)
+	return nil
+}
+
+//
+// Render a single page with the site model, overridden by the front
+// matter of the page. The front matter, along with the output path of
+// the page, is also available under the `page` key.
+//
+func buildPage(processor *snowmark.HtmlPageProcessor, site *snowmark.Model, path string, name string, options *snowmark.MergeOptions) ([]byte, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	front, body, err := splitFrontMatter(string(source))
+	if err != nil {
+		return nil, errors.New(name + ": " + err.Error())
+	}
+
+	template, err := snowmark.ParseTemplate(name, body)
+	if err != nil {
+		return nil, err
+	}
+
+	model := snowmark.NewModel()
+	for key, value := range site.GetMap() {
+		model.Put(key, value)
+	}
+
+	page := make(map[string]interface{})
+	for key, value := range front {
+		model.Put(key, value)
+		page[key] = value
+	}
+
+	page["path"] = name
+	model.Put(pageModelKey, page)
+
+	html, err := processor.MergeTemplateWithOptions(template, model, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(html), nil
+}
+
+//
+// Split the front matter from the body of a page. Front matter is
+// YAML between two `---` lines, or TOML between two `+++` lines, at the
+// very start of the page. The front matter is replaced by empty lines
+// in the body so that positions in errors still match the file.
+//
+func splitFrontMatter(source string) (map[string]interface{}, string, error) {
+	values := make(map[string]interface{})
+
+	delimiter := ""
+	if strings.HasPrefix(source, "---\n") || strings.HasPrefix(source, "---\r\n") {
+		delimiter = "---"
+	} else if strings.HasPrefix(source, "+++\n") || strings.HasPrefix(source, "+++\r\n") {
+		delimiter = "+++"
+	} else {
+		return values, source, nil
+	}
+
+	lines := strings.SplitAfter(source, "\n")
+	end := -1
+	for index := 1; index < len(lines); index++ {
+		if strings.TrimRight(lines[index], "\r\n") == delimiter {
+			end = index
+			break
+		}
+	}
+
+	if end < 0 {
+		return nil, "", errors.New("Front matter is not closed with " + delimiter)
+	}
+
+	data := strings.Join(lines[1:end], "")
+
+	var err error
+	if delimiter == "---" {
+		err = yaml.Unmarshal([]byte(data), &values)
+	} else {
+		err = toml.Unmarshal([]byte(data), &values)
+	}
+
+	if err != nil {
+		return nil, "", errors.New("Unable to read front matter: " + err.Error())
+	}
+
+	values = snowmark.NewModelFromMap(values).GetMap()
+
+	body := strings.Repeat("\n", end+1) + strings.Join(lines[end+1:], "")
+	return values, body, nil
+}
+
+//
+// Read the manifest of a previous build, if any.
+//
+func readManifest(path string, enabled bool) *manifest {
+	if !enabled {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	previous := &manifest{}
+	if json.Unmarshal(data, previous) != nil {
+		return nil
+	}
+
+	return previous
+}
+
+//
+// Remove the files of the previous build that were not produced
+// by the current build.
+//
+func removeStaleFiles(output string, previous *manifest, current *manifest) {
+	if previous == nil {
+		return
+	}
+
+	produced := make(map[string]bool)
+	for _, entry := range current.Files {
+		produced[entry.Path] = true
+	}
+
+	for _, entry := range previous.Files {
+		if produced[entry.Path] || !fs.ValidPath(entry.Path) {
+			continue
+		}
+
+		os.Remove(filepath.Join(output, filepath.FromSlash(entry.Path)))
+	}
+}
+
+func writeFile(path string, data []byte) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
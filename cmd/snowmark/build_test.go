@@ -0,0 +1,131 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuild(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"site.yaml":                 "title: My Site\nauthor: Jane\n",
+		"src/index.html":            "---\ntitle: Home\n---\n<main><s:include template='_partials/header.html' /><p><s:get var='author' /></p></main>",
+		"src/blog/post.html":        "+++\ntitle = \"Post\"\nwords = 120\n+++\n<article><s:get var='page.title' />/<s:get var='page.words / 2' />/<s:get var='page.path' /></article>",
+		"src/_partials/header.html": "<h1><s:get var='title' /></h1>",
+		"src/_layout.html":          "<html />",
+		"src/css/site.css":          "body { margin: 0 }",
+		"src/.hidden":               "secret",
+	})
+
+	src := filepath.Join(dir, "src")
+	out := filepath.Join(dir, "out")
+	stderr := &bytes.Buffer{}
+	code := run([]string{"build", "-model", filepath.Join(dir, "site.yaml"), src, out}, &bytes.Buffer{}, stderr)
+	assert.Equal(t, 0, code, stderr.String())
+
+	html, _ := os.ReadFile(filepath.Join(out, "index.html"))
+	assert.Equal(t, "<main><h1>Home</h1><p>Jane</p></main>", string(html))
+
+	html, _ = os.ReadFile(filepath.Join(out, "blog", "post.html"))
+	assert.Equal(t, "<article>Post/60/blog/post.html</article>", string(html))
+
+	css, _ := os.ReadFile(filepath.Join(out, "css", "site.css"))
+	assert.Equal(t, "body { margin: 0 }", string(css))
+
+	for _, skipped := range []string{"_partials", "_layout.html", ".hidden"} {
+		_, err := os.Stat(filepath.Join(out, skipped))
+		assert.True(t, os.IsNotExist(err), skipped)
+	}
+
+	data, err := os.ReadFile(filepath.Join(out, defaultManifest))
+	assert.NoError(t, err)
+
+	built := &manifest{}
+	assert.NoError(t, json.Unmarshal(data, built))
+	paths := make([]string, 0)
+	for _, entry := range built.Files {
+		paths = append(paths, entry.Path+" "+entry.Type)
+	}
+	assert.Equal(t, []string{"blog/post.html page", "css/site.css asset", "index.html page"}, paths)
+	assert.Equal(t, 64, len(built.Files[0].SHA256))
+
+	// building again produces the same output
+	code = run([]string{"build", "-model", filepath.Join(dir, "site.yaml"), src, out}, &bytes.Buffer{}, &bytes.Buffer{})
+	assert.Equal(t, 0, code)
+
+	again, _ := os.ReadFile(filepath.Join(out, defaultManifest))
+	assert.Equal(t, string(data), string(again))
+
+	// files that are no longer produced are removed
+	os.Remove(filepath.Join(src, "css", "site.css"))
+	code = run([]string{"build", "-model", filepath.Join(dir, "site.yaml"), src, out}, &bytes.Buffer{}, &bytes.Buffer{})
+	assert.Equal(t, 0, code)
+
+	_, err = os.Stat(filepath.Join(out, "css", "site.css"))
+	assert.True(t, os.IsNotExist(err))
+
+	again, _ = os.ReadFile(filepath.Join(out, defaultManifest))
+	assert.False(t, strings.Contains(string(again), "site.css"))
+}
+
+func TestBuildErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"src/index.html":  "---\ntitle: Home\n<p />",
+		"src/strict.html": "<p><s:get expr:var='1 +' /></p>",
+	})
+
+	src := filepath.Join(dir, "src")
+	stderr := &bytes.Buffer{}
+	code := run([]string{"build", src, filepath.Join(dir, "out")}, &bytes.Buffer{}, stderr)
+	assert.Equal(t, 1, code)
+	assert.True(t, strings.Contains(stderr.String(), "index.html: Front matter is not closed"))
+
+	// skipped errors are printed as warnings
+	os.WriteFile(filepath.Join(src, "index.html"), []byte("<p />"), 0644)
+	stderr.Reset()
+	code = run([]string{"build", src, filepath.Join(dir, "out")}, &bytes.Buffer{}, stderr)
+	assert.Equal(t, 0, code)
+	assert.True(t, strings.Contains(stderr.String(), "snowmark: warning: strict.html:1:"), stderr.String())
+
+	os.WriteFile(filepath.Join(src, "index.html"), []byte("---\ntitle: Home\n---\n<p><s:get expr:var='1 +' /></p>"), 0644)
+	stderr.Reset()
+	code = run([]string{"build", "-strict", src, filepath.Join(dir, "out")}, &bytes.Buffer{}, stderr)
+	assert.Equal(t, 1, code)
+
+	// positions still match the file
+	assert.True(t, strings.Contains(stderr.String(), "index.html:4:"), stderr.String())
+
+	assert.Equal(t, 1, run([]string{"build", src, filepath.Join(src, "out")}, &bytes.Buffer{}, &bytes.Buffer{}))
+	assert.Equal(t, 2, run([]string{"build", src}, &bytes.Buffer{}, &bytes.Buffer{}))
+}
+
+func TestSplitFrontMatter(t *testing.T) {
+	values, body, err := splitFrontMatter("<p />")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(values))
+	assert.Equal(t, "<p />", body)
+
+	values, body, err = splitFrontMatter("---\ncount: 2\n---\n<p />")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, values["count"])
+	assert.Equal(t, "\n\n\n<p />", body)
+
+	_, _, err = splitFrontMatter("+++\ncount = \n+++\n")
+	assert.Error(t, err)
+}
@@ -0,0 +1,149 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+//
+// Command snowmark renders snowmark templates with a model read from
+// a JSON, YAML or TOML file, with the standard tag library registered.
+//
+//  snowmark -model site.json -out index.html index.html
+//  snowmark -model site.yaml -out public/ templates/
+//
+// If the template is a directory, every `.html` file inside it is
+// rendered to the output directory, except those whose name starts
+// with an underscore, which are only used by other templates.
+//
+// The build command generates a static site from a directory tree,
+// rendering pages with their front matter and copying static assets:
+//
+//  snowmark build -model site.yaml src/ out/
+//
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+//
+// Options of the render command.
+//
+type options struct {
+	template string
+	model    string
+	output   string
+	prefix   string
+	mode     string
+	strict   bool
+	watch    bool
+	interval time.Duration
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+//
+// Run the command with the given arguments, and return the exit code.
+//
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "build" {
+		return runBuild(args[1:], stderr)
+	}
+
+	opts, err := parseOptions(args, stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+
+		fmt.Fprintln(stderr, "snowmark:", err)
+		return 2
+	}
+
+	if opts.watch {
+		err = watch(opts, stdout, stderr, nil)
+	} else {
+		err = render(opts, stdout, stderr)
+	}
+
+	if err != nil {
+		fmt.Fprintln(stderr, "snowmark:", err)
+		return 1
+	}
+
+	return 0
+}
+
+//
+// Run the build command with the given arguments, and return the
+// exit code.
+//
+func runBuild(args []string, stderr io.Writer) int {
+	opts, err := parseBuildOptions(args, stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+
+		fmt.Fprintln(stderr, "snowmark:", err)
+		return 2
+	}
+
+	err = build(opts, stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, "snowmark:", err)
+		return 1
+	}
+
+	return 0
+}
+
+func parseOptions(args []string, stderr io.Writer) (*options, error) {
+	opts := &options{}
+
+	flags := flag.NewFlagSet("snowmark", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&opts.model, "model", "", "model file in JSON, YAML or TOML format")
+	flags.StringVar(&opts.output, "out", "-", "output file, or directory if the template is a directory; `-` writes to stdout")
+	flags.StringVar(&opts.prefix, "prefix", "s", "prefix of the standard tags, such as `s` for `<s:get>`; empty for none")
+	flags.StringVar(&opts.mode, "mode", modeRender, "output mode: `render` writes the merged HTML, `validate` reports problems in the templates, `reference` writes the tag reference")
+	flags.BoolVar(&opts.strict, "strict", false, "stop at the first error instead of skipping the failing tag")
+	flags.BoolVar(&opts.watch, "watch", false, "render again whenever a template or the model changes")
+	flags.DurationVar(&opts.interval, "interval", 500*time.Millisecond, "how often to check for changes in watch mode")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: snowmark [flags] <template file or directory>")
+		flags.PrintDefaults()
+	}
+
+	err := flags.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	switch opts.mode {
+	case modeRender, modeValidate, modeReference:
+
+	default:
+		return nil, errors.New("Unknown output mode: " + opts.mode)
+	}
+
+	if flags.NArg() != 1 && opts.mode != modeReference {
+		flags.Usage()
+		return nil, errors.New("Exactly one template file or directory is required")
+	}
+
+	opts.template = flags.Arg(0)
+	return opts, nil
+}
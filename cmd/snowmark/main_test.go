@@ -0,0 +1,209 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	return dir
+}
+
+func TestRenderFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"page.html":  "<p><s:get var='title' />/<s:get var='count / 2' /></p>",
+		"model.json": `{"title": "Hello", "count": 3}`,
+		"model.yaml": "title: Hello\ncount: 3\n",
+		"model.toml": "title = \"Hello\"\ncount = 3\n",
+	})
+
+	for _, model := range []string{"model.json", "model.yaml", "model.toml"} {
+		stdout := &bytes.Buffer{}
+		stderr := &bytes.Buffer{}
+		code := run([]string{"-model", filepath.Join(dir, model), filepath.Join(dir, "page.html")}, stdout, stderr)
+		assert.Equal(t, 0, code, stderr.String())
+		assert.Equal(t, "<p>Hello/1</p>", stdout.String(), model)
+	}
+
+	// to a file, without prefix
+	dir = writeFiles(t, map[string]string{
+		"page.html": "<p><get var='title' /></p>",
+		"site.json": `{"title": "Plain"}`,
+	})
+
+	output := filepath.Join(dir, "out", "page.html")
+	code := run([]string{"-prefix", "", "-model", filepath.Join(dir, "site.json"), "-out", output, filepath.Join(dir, "page.html")}, &bytes.Buffer{}, &bytes.Buffer{})
+	assert.Equal(t, 0, code)
+
+	html, err := os.ReadFile(output)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>Plain</p>", string(html))
+
+	// skipped errors are printed as warnings
+	os.WriteFile(filepath.Join(dir, "page.html"), []byte("<p><get var='title' /><get var='1 +' /></p>"), 0644)
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	code = run([]string{"-prefix", "", "-model", filepath.Join(dir, "site.json"), filepath.Join(dir, "page.html")}, stdout, stderr)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "<p>Plain</p>", stdout.String())
+	assert.True(t, strings.Contains(stderr.String(), "snowmark: warning: page.html:1:"), stderr.String())
+}
+
+func TestRenderDirectory(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"templates/index.html":     "<main><s:include template='_header.html' /></main>",
+		"templates/blog/post.html": "<article><s:get var='title' /></article>",
+		"templates/_header.html":   "<h1><s:get var='title' /></h1>",
+		"templates/notes.txt":      "ignored",
+		"model.json":               `{"title": "Site"}`,
+	})
+
+	out := filepath.Join(dir, "public")
+	stderr := &bytes.Buffer{}
+	code := run([]string{"-model", filepath.Join(dir, "model.json"), "-out", out, filepath.Join(dir, "templates")}, &bytes.Buffer{}, stderr)
+	assert.Equal(t, 0, code, stderr.String())
+
+	html, _ := os.ReadFile(filepath.Join(out, "index.html"))
+	assert.Equal(t, "<main><h1>Site</h1></main>", string(html))
+
+	html, _ = os.ReadFile(filepath.Join(out, "blog", "post.html"))
+	assert.Equal(t, "<article>Site</article>", string(html))
+
+	_, err := os.Stat(filepath.Join(out, "_header.html"))
+	assert.True(t, os.IsNotExist(err))
+
+	// directories need an output directory
+	code = run([]string{filepath.Join(dir, "templates")}, &bytes.Buffer{}, &bytes.Buffer{})
+	assert.Equal(t, 1, code)
+}
+
+func TestRenderModes(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"good.html":   "<p><s:get var='title' /></p>",
+		"bad.html":    "<p><s:get /></p>",
+		"model.ini":   "title=Hello",
+		"strict.html": "<p><s:get expr:var='1 +' /></p>",
+	})
+
+	stdout := &bytes.Buffer{}
+	code := run([]string{"-mode", "validate", filepath.Join(dir, "good.html")}, stdout, &bytes.Buffer{})
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "", stdout.String())
+
+	code = run([]string{"-mode", "validate", filepath.Join(dir, "bad.html")}, stdout, &bytes.Buffer{})
+	assert.Equal(t, 1, code)
+	assert.True(t, strings.Contains(stdout.String(), "bad.html:1:4: error:"))
+
+	stdout.Reset()
+	code = run([]string{"-mode", "reference"}, stdout, &bytes.Buffer{})
+	assert.Equal(t, 0, code)
+	assert.True(t, strings.Contains(stdout.String(), "s:foreach"))
+
+	// strict mode stops at errors
+	code = run([]string{filepath.Join(dir, "strict.html")}, &bytes.Buffer{}, &bytes.Buffer{})
+	assert.Equal(t, 0, code)
+
+	code = run([]string{"-strict", filepath.Join(dir, "strict.html")}, &bytes.Buffer{}, &bytes.Buffer{})
+	assert.Equal(t, 1, code)
+
+	// usage errors
+	assert.Equal(t, 2, run([]string{"-mode", "pdf", filepath.Join(dir, "good.html")}, &bytes.Buffer{}, &bytes.Buffer{}))
+	assert.Equal(t, 2, run([]string{}, &bytes.Buffer{}, &bytes.Buffer{}))
+	assert.Equal(t, 1, run([]string{"-model", filepath.Join(dir, "model.ini"), filepath.Join(dir, "good.html")}, &bytes.Buffer{}, &bytes.Buffer{}))
+}
+
+func TestWatch(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"page.html":  "<p><s:get var='title' /></p>",
+		"model.json": `{"title": "one"}`,
+	})
+
+	output := filepath.Join(dir, "out.html")
+	opts := &options{
+		template: filepath.Join(dir, "page.html"),
+		model:    filepath.Join(dir, "model.json"),
+		output:   output,
+		prefix:   "s",
+		mode:     modeRender,
+		interval: 10 * time.Millisecond,
+	}
+
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- watch(opts, &bytes.Buffer{}, &bytes.Buffer{}, stop)
+	}()
+
+	read := func() string {
+		html, _ := os.ReadFile(output)
+		return string(html)
+	}
+
+	assert.Eventually(t, func() bool { return read() == "<p>one</p>" }, 2*time.Second, 10*time.Millisecond)
+
+	// make sure the modification time changes
+	later := time.Now().Add(time.Minute)
+	os.WriteFile(opts.model, []byte(`{"title": "two"}`), 0644)
+	os.Chtimes(opts.model, later, later)
+
+	assert.Eventually(t, func() bool { return read() == "<p>two</p>" }, 2*time.Second, 10*time.Millisecond)
+
+	close(stop)
+	assert.NoError(t, <-done)
+}
+
+func TestWatchIgnoresOutput(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"page.html":  "<p><s:get var='title' /></p>",
+		"model.json": `{"title": "one"}`,
+	})
+
+	// the output is next to the template
+	output := filepath.Join(dir, "out.html")
+	opts := &options{
+		template: filepath.Join(dir, "page.html"),
+		model:    filepath.Join(dir, "model.json"),
+		output:   output,
+		prefix:   "s",
+		mode:     modeRender,
+		interval: 10 * time.Millisecond,
+	}
+
+	stderr := &bytes.Buffer{}
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- watch(opts, &bytes.Buffer{}, stderr, stop)
+	}()
+
+	// wait for a number of polls without changes
+	time.Sleep(200 * time.Millisecond)
+	close(stop)
+	assert.NoError(t, <-done)
+
+	assert.Equal(t, 1, strings.Count(stderr.String(), "rendered"))
+}
@@ -0,0 +1,62 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/sangupta/snowmark"
+)
+
+//
+// Read the model from the given file. The format is detected from
+// the extension of the file: `.json`, `.yaml`, `.yml` or `.toml`. An
+// empty path returns an empty model.
+//
+func readModel(path string) (*snowmark.Model, error) {
+	if path == "" {
+		return snowmark.NewModel(), nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var model *snowmark.Model
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		model, err = snowmark.NewModelFromJSON(file)
+
+	case ".yaml", ".yml":
+		model, err = snowmark.NewModelFromYAML(file)
+
+	case ".toml":
+		values := make(map[string]interface{})
+		_, err = toml.NewDecoder(file).Decode(&values)
+		model = snowmark.NewModelFromMap(values)
+
+	default:
+		return nil, errors.New("Unsupported model format, use .json, .yaml, .yml or .toml: " + path)
+	}
+
+	if err != nil {
+		return nil, errors.New("Unable to read model " + path + ": " + err.Error())
+	}
+
+	return model, nil
+}
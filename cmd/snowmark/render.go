@@ -0,0 +1,263 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sangupta/snowmark"
+)
+
+// Output modes
+const (
+	modeRender    = "render"
+	modeValidate  = "validate"
+	modeReference = "reference"
+)
+
+//
+// The templates to render: the directory they are loaded from and
+// the slash-separated names of the pages inside it.
+//
+type targets struct {
+	root      string
+	names     []string
+	directory bool
+}
+
+//
+// Create a processor with the standard tag library and the feature
+// libraries registered under the configured prefix, loading templates
+// from the given directory.
+//
+func newProcessor(opts *options, root string) (*snowmark.HtmlPageProcessor, error) {
+	processor := snowmark.NewHtmlPageProcessor()
+
+	libraries := []*snowmark.TagLibrary{
+		snowmark.StandardLibrary(),
+		snowmark.IncludeLibrary(),
+		snowmark.I18nLibrary(),
+		snowmark.URLLibrary(),
+		snowmark.SanitizeLibrary(),
+		snowmark.MarkdownLibrary(),
+		snowmark.JSONLibrary(),
+	}
+
+	for _, library := range libraries {
+		err := processor.Import(library, opts.prefix)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	processor.SetStrictMode(opts.strict)
+
+	processor.SetTemplateLoader(snowmark.NewDirectoryTemplateLoader(root))
+
+	return processor, nil
+}
+
+//
+// Return the merge options that print the errors skipped when not in
+// strict mode as warnings, along with their location.
+//
+func warningOptions(stderr io.Writer) *snowmark.MergeOptions {
+	return &snowmark.MergeOptions{
+		OnWarning: func(err error) {
+			fmt.Fprintln(stderr, "snowmark: warning:", err)
+		},
+	}
+}
+
+//
+// Find the templates to render. For a directory, all `.html` files
+// are returned except those whose name starts with an underscore.
+//
+func findTargets(template string) (*targets, error) {
+	info, err := os.Stat(template)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return &targets{
+			root:  filepath.Dir(template),
+			names: []string{filepath.Base(template)},
+		}, nil
+	}
+
+	names := make([]string, 0)
+	err = filepath.WalkDir(template, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() || !isPage(entry.Name()) {
+			return nil
+		}
+
+		name, err := filepath.Rel(template, path)
+		if err != nil {
+			return err
+		}
+
+		names = append(names, filepath.ToSlash(name))
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(names)
+	return &targets{
+		root:      template,
+		names:     names,
+		directory: true,
+	}, nil
+}
+
+//
+// Check if the file is a page that is rendered on its own, rather
+// than a partial that is only included by other templates.
+//
+func isPage(name string) bool {
+	return strings.HasSuffix(strings.ToLower(name), ".html") && !strings.HasPrefix(name, "_")
+}
+
+//
+// Render, validate or document the templates as per the options.
+//
+func render(opts *options, stdout io.Writer, stderr io.Writer) error {
+	if opts.mode == modeReference {
+		processor, err := newProcessor(opts, ".")
+		if err != nil {
+			return err
+		}
+
+		return writeOutput(opts.output, stdout, func(writer io.Writer) error {
+			return processor.WriteTagReference(writer)
+		})
+	}
+
+	found, err := findTargets(opts.template)
+	if err != nil {
+		return err
+	}
+
+	processor, err := newProcessor(opts, found.root)
+	if err != nil {
+		return err
+	}
+
+	if opts.mode == modeValidate {
+		return validate(processor, found, stdout)
+	}
+
+	model, err := readModel(opts.model)
+	if err != nil {
+		return err
+	}
+
+	if found.directory && opts.output == "-" {
+		return errors.New("Output directory is required to render a directory of templates")
+	}
+
+	for _, name := range found.names {
+		html, err := processor.MergeNamedWithOptions(name, model, warningOptions(stderr))
+		if err != nil {
+			return err
+		}
+
+		output := opts.output
+		if found.directory {
+			output = filepath.Join(opts.output, filepath.FromSlash(name))
+		}
+
+		err = writeOutput(output, stdout, func(writer io.Writer) error {
+			_, err := io.WriteString(writer, html)
+			return err
+		})
+
+		if err != nil {
+			return err
+		}
+
+		if output != "-" {
+			fmt.Fprintln(stderr, "rendered", output)
+		}
+	}
+
+	return nil
+}
+
+//
+// Validate all templates and print the diagnostics. Returns an error
+// if any of the templates has an error.
+//
+func validate(processor *snowmark.HtmlPageProcessor, found *targets, stdout io.Writer) error {
+	errorCount := 0
+	for _, name := range found.names {
+		source, err := os.ReadFile(filepath.Join(found.root, filepath.FromSlash(name)))
+		if err != nil {
+			return err
+		}
+
+		for _, diagnostic := range processor.ValidateTemplate(name, string(source)) {
+			fmt.Fprintln(stdout, diagnostic.String())
+			if diagnostic.Severity == snowmark.SeverityError {
+				errorCount++
+			}
+		}
+	}
+
+	if errorCount > 0 {
+		return fmt.Errorf("%d error(s) found", errorCount)
+	}
+
+	return nil
+}
+
+//
+// Write to the given output file, creating the parent directories,
+// or to stdout if the output is `-`.
+//
+func writeOutput(output string, stdout io.Writer, write func(writer io.Writer) error) error {
+	if output == "-" || output == "" {
+		return write(stdout)
+	}
+
+	err := os.MkdirAll(filepath.Dir(output), 0755)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+
+	err = write(file)
+	closeErr := file.Close()
+	if err != nil {
+		return err
+	}
+
+	return closeErr
+}
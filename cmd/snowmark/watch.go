@@ -0,0 +1,126 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+//
+// Render the templates, and render them again whenever a template
+// or the model file changes. Changes are detected by polling the
+// modification times, and every render starts with freshly parsed
+// templates. The output is not watched, even if it is next to the
+// templates, as every render changes it. Runs until the `stop` channel
+// is closed, or forever if it is `nil`. Render errors are reported but
+// do not stop watching.
+//
+func watch(opts *options, stdout io.Writer, stderr io.Writer, stop <-chan struct{}) error {
+	roots := []string{opts.template}
+	if opts.model != "" {
+		roots = append(roots, opts.model)
+	}
+
+	if info, err := os.Stat(opts.template); err == nil && !info.IsDir() {
+		// included templates live next to the template
+		roots[0] = filepath.Dir(opts.template)
+	}
+
+	excluded := ""
+	if opts.output != "-" && opts.output != "" {
+		excluded, _ = filepath.Abs(opts.output)
+	}
+
+	last := snapshot(roots, excluded)
+	renderAndReport(opts, stdout, stderr)
+
+	ticker := time.NewTicker(opts.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return nil
+
+		case <-ticker.C:
+			current := snapshot(roots, excluded)
+			if !sameSnapshot(last, current) {
+				last = current
+				renderAndReport(opts, stdout, stderr)
+			}
+		}
+	}
+}
+
+func renderAndReport(opts *options, stdout io.Writer, stderr io.Writer) {
+	err := render(opts, stdout, stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, "snowmark:", err)
+	}
+}
+
+//
+// Return the modification times of all files under the given paths,
+// except the excluded file or directory, given as an absolute path.
+//
+func snapshot(roots []string, excluded string) map[string]time.Time {
+	times := make(map[string]time.Time)
+	for _, root := range roots {
+		filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+
+			if excluded != "" {
+				absolute, _ := filepath.Abs(path)
+				if absolute == excluded {
+					if entry.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+			}
+
+			if entry.IsDir() {
+				return nil
+			}
+
+			info, err := entry.Info()
+			if err == nil {
+				times[path] = info.ModTime()
+			}
+			return nil
+		})
+	}
+
+	return times
+}
+
+func sameSnapshot(first map[string]time.Time, second map[string]time.Time) bool {
+	if len(first) != len(second) {
+		return false
+	}
+
+	for path, modTime := range first {
+		other, exists := second[path]
+		if !exists || !other.Equal(modTime) {
+			return false
+		}
+	}
+
+	return true
+}
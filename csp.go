@@ -0,0 +1,130 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"errors"
+	"html"
+	"strings"
+
+	"github.com/sangupta/lhtml"
+)
+
+//
+// The model key under which the Content Security Policy nonce of the
+// merge is available, such as for the `Content-Security-Policy` header
+// of the response or scripts added by custom tags.
+//
+const NonceModelKey = "cspNonce"
+
+//
+// Enable or disable the rejection of inline event handler attributes,
+// such as `onclick`, which a strict Content Security Policy blocks.
+// When enabled, elements with such attributes fail to render, and the
+// validator reports them.
+//
+func (pageProcessor *HtmlPageProcessor) SetRejectInlineHandlers(reject bool) {
+	pageProcessor._rejectInlineHandlers = reject
+}
+
+//
+// Check if the processor rejects inline event handler attributes.
+//
+func (pageProcessor *HtmlPageProcessor) IsRejectInlineHandlers() bool {
+	return pageProcessor._rejectInlineHandlers
+}
+
+//
+// Return the Content Security Policy nonce of the current merge, or an
+// empty string if there is none.
+//
+func (evaluator *Evaluator) Nonce() string {
+	if evaluator.options == nil {
+		return ""
+	}
+
+	return evaluator.options.Nonce
+}
+
+//
+// Return the nonce of the merge that the model belongs to, if any. The
+// nonce is read in place of any `cspNonce` value of the model or the
+// globals, so that the model cannot replace it.
+//
+func (model *Model) mergeNonce() (string, bool) {
+	if model._evaluator == nil {
+		return "", false
+	}
+
+	nonce := model._evaluator.Nonce()
+	return nonce, nonce != ""
+}
+
+//
+// Check if the attribute is an inline event handler, such as `onclick`
+// or `expr:onload`.
+//
+func isEventHandler(name string) bool {
+	name = strings.ToLower(strings.TrimPrefix(name, PREFIX))
+	if len(name) <= 2 || !strings.HasPrefix(name, "on") {
+		return false
+	}
+
+	for _, r := range name[2:] {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+
+	return true
+}
+
+//
+// Check if the node is an inline script or style element, which needs
+// the nonce of the merge to run under a strict Content Security Policy.
+//
+func isInlineScriptOrStyle(node *lhtml.HtmlNode) bool {
+	switch node.NodeName() {
+	case "script":
+		return !node.HasAttribute("src") && !node.HasAttribute(PREFIX+"src")
+
+	case "style":
+		return true
+	}
+
+	return false
+}
+
+//
+// Return the `nonce` attribute to add to the node, if any, or an error
+// if the node has an inline event handler that the processor rejects.
+//
+func (evaluator *Evaluator) securityAttributes(node *lhtml.HtmlNode) ([]*lhtml.HtmlAttribute, error) {
+	if evaluator.processor != nil && evaluator.processor._rejectInlineHandlers {
+		for _, attr := range node.Attributes {
+			if isEventHandler(attr.Name) {
+				return nil, errors.New("Inline event handler '" + attr.Name + "' is not allowed")
+			}
+		}
+	}
+
+	nonce := evaluator.Nonce()
+	if nonce == "" || !isInlineScriptOrStyle(node) {
+		return nil, nil
+	}
+
+	if node.HasAttribute("nonce") || node.HasAttribute(PREFIX+"nonce") {
+		return nil, nil
+	}
+
+	return []*lhtml.HtmlAttribute{{Name: "nonce", Value: html.EscapeString(nonce)}}, nil
+}
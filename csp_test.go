@@ -0,0 +1,126 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"testing"
+
+	"github.com/sangupta/lhtml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNonce(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "")
+	processor.SetStrictMode(true)
+
+	model := NewModel()
+	model.Freeze()
+	options := &MergeOptions{Nonce: "r4nd0m"}
+
+	html, err := processor.MergeHtmlWithOptions(`<head><script>init();</script><style>p { color: red }</style><script src="/app.js"></script></head>`, model, options)
+	assert.NoError(t, err)
+	assert.Equal(t, `<head><script nonce="r4nd0m">init();</script><style nonce="r4nd0m">p { color: red }</style><script src="/app.js" /></head>`, html)
+
+	// existing nonce is kept
+	html, err = processor.MergeHtmlWithOptions(`<head><script nonce="other">init();</script></head>`, model, options)
+	assert.NoError(t, err)
+	assert.Equal(t, `<head><script nonce="other">init();</script></head>`, html)
+
+	// available in the model
+	html, err = processor.MergeHtmlWithOptions(`<p><get var="cspNonce" /></p>`, model, options)
+	assert.NoError(t, err)
+	assert.Equal(t, `<p>r4nd0m</p>`, html)
+
+	// the nonce of the merge wins over the model and the globals
+	processor.SetGlobal(NonceModelKey, "global")
+	other := NewModel()
+	other.Put(NonceModelKey, "evil")
+	html, err = processor.MergeHtmlWithOptions(`<p><get var="cspNonce" /></p>`, other, options)
+	assert.NoError(t, err)
+	assert.Equal(t, `<p>r4nd0m</p>`, html)
+	processor.RemoveGlobal(NonceModelKey)
+
+	// custom tags read it from the model too
+	processor.AddCustomTag("nonce", func(node *lhtml.HtmlNode, model *Model, evaluator *Evaluator) error {
+		evaluator.WriteString(model.GetString(NonceModelKey, "") + "/" + evaluator.Nonce())
+		return nil
+	})
+	html, err = processor.MergeHtmlWithOptions(`<p><nonce /></p>`, other, options)
+	assert.NoError(t, err)
+	assert.Equal(t, `<p>r4nd0m/r4nd0m</p>`, html)
+	assert.Equal(t, "evil", other.GetString(NonceModelKey, ""))
+
+	// no nonce
+	html, err = processor.MergeHtml(`<head><script>init();</script></head>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<head><script>init();</script></head>`, html)
+}
+
+func TestNonceWrites(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "")
+	processor.SetStrictMode(true)
+
+	// values set during the merge reach the model with or without a nonce
+	for _, options := range []*MergeOptions{nil, {Nonce: "r4nd0m"}} {
+		model := NewModel()
+		html, err := processor.MergeHtmlWithOptions(`<p><set var="x" value="y" /><get var="x" /></p>`, model, options)
+		assert.NoError(t, err)
+		assert.Equal(t, `<p>y</p>`, html)
+		assert.Equal(t, "y", model.GetString("x", ""))
+		assert.Equal(t, 1, model.Size())
+	}
+}
+
+func TestRejectInlineHandlers(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	processor.SetStrictMode(true)
+
+	template := `<p><button onclick="buy()">Buy</button></p>`
+	html, err := processor.MergeHtml(template, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, template, html)
+	assert.Empty(t, processor.Validate(template))
+
+	processor.SetRejectInlineHandlers(true)
+	assert.True(t, processor.IsRejectInlineHandlers())
+
+	_, err = processor.MergeHtml(template, nil)
+	assert.Error(t, err)
+
+	_, err = processor.MergeHtml(`<p><img expr:onload="handler" /></p>`, nil)
+	assert.Error(t, err)
+
+	html, err = processor.MergeHtml(`<p><a on="2" data-onclick="x">x</a></p>`, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, `<p><a on="2" data-onclick="x">x</a></p>`, html)
+
+	diagnostics := processor.Validate(template)
+	assert.Len(t, diagnostics, 1)
+	assert.Contains(t, diagnostics[0].Message, "onclick")
+
+	// lenient mode skips the element
+	processor.SetStrictMode(false)
+	html, err = processor.MergeHtml(template, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, `<p></p>`, html)
+}
+
+func TestIsEventHandler(t *testing.T) {
+	assert.True(t, isEventHandler("onclick"))
+	assert.True(t, isEventHandler("ONLOAD"))
+	assert.True(t, isEventHandler("expr:onmouseover"))
+	assert.False(t, isEventHandler("on"))
+	assert.False(t, isEventHandler("one-time"))
+	assert.False(t, isEventHandler("class"))
+}
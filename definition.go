@@ -0,0 +1,598 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sangupta/lhtml"
+)
+
+//
+// Enum to define the type of value an attribute accepts.
+//
+type AttributeType uint32
+
+// Enumeration
+const (
+	AttributeString AttributeType = iota
+	AttributeExpression
+	AttributeNumber
+	AttributeBool
+)
+
+//
+// Return the attribute type as a lower-case string.
+//
+func (attributeType AttributeType) String() string {
+	switch attributeType {
+	case AttributeExpression:
+		return "expression"
+
+	case AttributeNumber:
+		return "number"
+
+	case AttributeBool:
+		return "bool"
+	}
+
+	return "string"
+}
+
+//
+// Enum to define whether a tag has a body or not.
+//
+type BodyType uint32
+
+// Enumeration
+const (
+	BodyOptional BodyType = iota
+	BodyRequired
+	BodyEmpty
+)
+
+//
+// Return the body type as a lower-case string.
+//
+func (bodyType BodyType) String() string {
+	switch bodyType {
+	case BodyRequired:
+		return "required"
+
+	case BodyEmpty:
+		return "self-closing"
+	}
+
+	return "optional"
+}
+
+//
+// Describes a single attribute accepted by a custom tag.
+//
+type AttributeDefinition struct {
+	Name            string
+	Description     string
+	Required        bool
+	Type            AttributeType
+	Default         string
+	AllowExpression bool
+}
+
+//
+// Describes a child clause of a custom tag, such as the
+// `then` and `else` clauses of the `if` tag.
+//
+type ChildDefinition struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+//
+// Describes a custom tag: the attributes it accepts, the child
+// clauses it allows and whether it takes a body. If `Children` is
+// empty, any content is allowed inside the tag, otherwise only the
+// listed clauses are allowed as child elements.
+//
+type TagDefinition struct {
+	Name        string
+	Description string
+	Attributes  []*AttributeDefinition
+	Children    []*ChildDefinition
+	Body        BodyType
+}
+
+//
+// Return the definition of the attribute with given name, if any.
+//
+func (definition *TagDefinition) GetAttribute(name string) *AttributeDefinition {
+	for _, attribute := range definition.Attributes {
+		if strings.EqualFold(attribute.Name, name) {
+			return attribute
+		}
+	}
+
+	return nil
+}
+
+//
+// Return the definition of the child clause that the given node
+// name represents, if any.
+//
+func (definition *TagDefinition) GetChild(nodeName string) *ChildDefinition {
+	for _, child := range definition.Children {
+		if matchesClause(nodeName, child.Name) {
+			return child
+		}
+	}
+
+	return nil
+}
+
+//
+// Check the usage of the tag in the given node against this
+// definition. An error is returned for the first problem found.
+//
+func (definition *TagDefinition) Check(node *lhtml.HtmlNode) error {
+	if node == nil {
+		return errors.New("Node is required to check against definition")
+	}
+
+	nodeName := node.NodeName()
+
+	for _, attribute := range definition.Attributes {
+		plain := node.GetAttribute(attribute.Name)
+		expression := node.GetAttribute(PREFIX + attribute.Name)
+
+		if expression != nil && !attribute.AllowExpression {
+			return errors.New("Attribute '" + attribute.Name + "' of tag <" + nodeName + "> does not allow expressions")
+		}
+
+		if plain == nil && expression == nil {
+			if attribute.Required && attribute.Default == "" {
+				return errors.New("Tag <" + nodeName + "> is missing required attribute '" + attribute.Name + "'")
+			}
+			continue
+		}
+
+		// expressions are evaluated by goval, which reports its own
+		// errors, while `Validate` checks their syntax ahead of time
+		if plain != nil && attribute.Type != AttributeExpression {
+			err := attribute.checkValue(plain.Value)
+			if err != nil {
+				return errors.New("Attribute '" + attribute.Name + "' of tag <" + nodeName + "> " + err.Error())
+			}
+		}
+	}
+
+	hasElements := false
+	for _, child := range node.Children() {
+		if child.NodeType != lhtml.ElementNode {
+			continue
+		}
+
+		hasElements = true
+		if len(definition.Children) > 0 && definition.GetChild(child.NodeName()) == nil {
+			return errors.New("Tag <" + nodeName + "> does not allow child <" + child.NodeName() + ">")
+		}
+	}
+
+	for _, child := range definition.Children {
+		if child.Required && getClause(node, child.Name) == nil {
+			return errors.New("Tag <" + nodeName + "> does not have a '" + child.Name + "' clause")
+		}
+	}
+
+	switch definition.Body {
+	case BodyRequired:
+		if !node.HasChildren() {
+			return errors.New("Tag <" + nodeName + "> requires a body")
+		}
+
+	case BodyEmpty:
+		if hasElements || strings.TrimSpace(getText(node)) != "" {
+			return errors.New("Tag <" + nodeName + "> must be self-closing")
+		}
+	}
+
+	return nil
+}
+
+//
+// Add the default value of every attribute that has one and is not
+// present on the node, so that tag processors can read them like
+// any other attribute.
+//
+func (definition *TagDefinition) ApplyDefaults(node *lhtml.HtmlNode) {
+	for _, attribute := range definition.Attributes {
+		if attribute.Default == "" {
+			continue
+		}
+
+		if node.HasAttribute(attribute.Name) || node.HasAttribute(PREFIX+attribute.Name) {
+			continue
+		}
+
+		node.AddAttribute(attribute.Name, attribute.Default)
+	}
+}
+
+//
+// Check if a literal value is acceptable for the attribute type.
+//
+func (attribute *AttributeDefinition) checkValue(value string) error {
+	switch attribute.Type {
+	case AttributeNumber:
+		_, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return errors.New("must be a number")
+		}
+
+	case AttributeBool:
+		_, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return errors.New("must be a boolean")
+		}
+
+	case AttributeExpression:
+		err := CheckExpressionSyntax(value)
+		if err != nil {
+			return errors.New("has an invalid expression: " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+//
+// Return the concatenated text of all text children of the node.
+//
+func getText(node *lhtml.HtmlNode) string {
+	builder := strings.Builder{}
+	for _, child := range node.Children() {
+		if child.NodeType == lhtml.TextNode {
+			builder.WriteString(child.Data)
+		}
+	}
+
+	return builder.String()
+}
+
+//
+// Definition of `GetVariableTag`.
+//
+var GetVariableTagDefinition = &TagDefinition{
+	Name:        "get",
+	Description: "Write the value of an expression evaluated against the model.",
+	Attributes: []*AttributeDefinition{
+		{Name: "var", Description: "Expression to evaluate", Required: true, Type: AttributeExpression, AllowExpression: true},
+		{Name: "filter", Description: "Filters to apply, separated by `|`", Type: AttributeString},
+	},
+	Body: BodyEmpty,
+}
+
+//
+// Definition of `SetVariableTag`.
+//
+var SetVariableTagDefinition = &TagDefinition{
+	Name:        "set",
+	Description: "Set a variable in the model, either for the rest of the template or only for the body of the tag.",
+	Attributes: []*AttributeDefinition{
+		{Name: "var", Description: "Name of the variable to set", Required: true, Type: AttributeString},
+		{Name: "value", Description: "Value to set", Required: true, Type: AttributeString, AllowExpression: true},
+	},
+	Body: BodyOptional,
+}
+
+//
+// Definition of `IfElseTag`.
+//
+var IfElseTagDefinition = &TagDefinition{
+	Name:        "if",
+	Description: "Render the `then` clause if the condition holds, the `else` clause otherwise.",
+	Attributes: []*AttributeDefinition{
+		{Name: "condition", Description: "Condition to evaluate", Required: true, Type: AttributeExpression},
+	},
+	Children: []*ChildDefinition{
+		{Name: "then", Description: "Rendered when the condition is `true`", Required: true},
+		{Name: "else", Description: "Rendered when the condition is `false`"},
+	},
+	Body: BodyRequired,
+}
+
+//
+// Definition of `ForEachTag`.
+//
+var ForEachTagDefinition = &TagDefinition{
+	Name:        "foreach",
+	Description: "Render the body once for every item of a slice or every entry of a map.",
+	Attributes: []*AttributeDefinition{
+		{Name: "collection", Description: "Expression that returns the collection", Required: true, Type: AttributeExpression},
+		{Name: "var", Description: "Name of the variable that holds the current item", Required: true, Type: AttributeString},
+	},
+	Body: BodyOptional,
+}
+
+//
+// Definition of `IncludeTag`.
+//
+var IncludeTagDefinition = &TagDefinition{
+	Name:        "include",
+	Description: "Include another template, loaded by name using the template loader.",
+	Attributes: []*AttributeDefinition{
+		{Name: "template", Description: "Name of the template to include", Required: true, Type: AttributeString, AllowExpression: true},
+	},
+	Body: BodyEmpty,
+}
+
+//
+// Definition of `MessageTag`.
+//
+var MessageTagDefinition = &TagDefinition{
+	Name:        "msg",
+	Description: "Write a message of the message bundle, translated into the locale of the merge.",
+	Attributes: []*AttributeDefinition{
+		{Name: "key", Description: "Key of the message", Required: true, Type: AttributeString, AllowExpression: true},
+		{Name: "count", Description: "Expression for the number that selects the plural form", Type: AttributeExpression},
+		{Name: "args", Description: "Expression for an object with the values of the placeholders", Type: AttributeExpression},
+		{Name: "locale", Description: "Locale to translate into, instead of the locale of the merge", Type: AttributeString, AllowExpression: true},
+	},
+	Body: BodyEmpty,
+}
+
+//
+// Definition of `FormatNumberTag`.
+//
+var FormatNumberTagDefinition = &TagDefinition{
+	Name:        "formatNumber",
+	Description: "Write a number formatted for the locale of the merge.",
+	Attributes: []*AttributeDefinition{
+		{Name: "value", Description: "Expression for the number", Required: true, Type: AttributeExpression},
+		{Name: "decimals", Description: "Number of fraction digits", Type: AttributeNumber},
+		{Name: "style", Description: "`decimal` or `percent`", Type: AttributeString, Default: "decimal"},
+	},
+	Body: BodyEmpty,
+}
+
+//
+// Definition of `FormatCurrencyTag`.
+//
+var FormatCurrencyTagDefinition = &TagDefinition{
+	Name:        "formatCurrency",
+	Description: "Write an amount of money formatted for the locale of the merge.",
+	Attributes: []*AttributeDefinition{
+		{Name: "value", Description: "Expression for the amount", Required: true, Type: AttributeExpression},
+		{Name: "code", Description: "ISO 4217 code of the currency, such as `EUR`", Required: true, Type: AttributeString, AllowExpression: true},
+	},
+	Body: BodyEmpty,
+}
+
+//
+// Definition of `FormatDateTag`.
+//
+var FormatDateTagDefinition = &TagDefinition{
+	Name:        "formatDate",
+	Description: "Write a date formatted for the locale and in the time zone of the merge.",
+	Attributes: []*AttributeDefinition{
+		{Name: "value", Description: "Expression for the date", Required: true, Type: AttributeExpression},
+		{Name: "pattern", Description: "CLDR date pattern, or `short`, `medium`, `long` or `full`", Type: AttributeString, AllowExpression: true},
+	},
+	Body: BodyEmpty,
+}
+
+//
+// Definition of `FormatRelativeTimeTag`.
+//
+var FormatRelativeTimeTagDefinition = &TagDefinition{
+	Name:        "formatRelativeTime",
+	Description: "Write a date relative to now, such as `2 hours ago`, in the locale of the merge.",
+	Attributes: []*AttributeDefinition{
+		{Name: "value", Description: "Expression for the date", Required: true, Type: AttributeExpression},
+		{Name: "now", Description: "Expression for the date to compare with, instead of now", Type: AttributeExpression},
+	},
+	Body: BodyEmpty,
+}
+
+//
+// Definition of `BidiTag`.
+//
+var BidiTagDefinition = &TagDefinition{
+	Name:        "bidi",
+	Description: "Isolate text whose direction may differ from the page, such as user-generated text.",
+	Attributes: []*AttributeDefinition{
+		{Name: "var", Description: "Expression for the text, instead of the body", Type: AttributeExpression},
+		{Name: "dir", Description: "`auto`, `ltr` or `rtl`", Type: AttributeString, Default: "auto"},
+		{Name: "mode", Description: "`markup` for a `<bdi>` element, or `unicode` for isolate characters", Type: AttributeString, Default: "markup"},
+	},
+}
+
+//
+// Definition of `URLTag`.
+//
+var URLTagDefinition = &TagDefinition{
+	Name:        "url",
+	Description: "Write a URL built from a path or a named route, with escaped parameters.",
+	Attributes: []*AttributeDefinition{
+		{Name: "path", Description: "Path of the URL, with optional placeholders such as `{id}`", Type: AttributeString, AllowExpression: true},
+		{Name: "route", Description: "Name of a route of the processor, instead of the path", Type: AttributeString, AllowExpression: true},
+		{Name: "params", Description: "Expression for an object with the parameters", Type: AttributeExpression},
+		{Name: "var", Description: "Name of the variable to store the URL in, instead of writing it", Type: AttributeString},
+	},
+	Body: BodyEmpty,
+}
+
+//
+// Definition of `SanitizeTag`.
+//
+var SanitizeTagDefinition = &TagDefinition{
+	Name:        "sanitize",
+	Description: "Write user-authored HTML with only the markup that a sanitize policy allows.",
+	Attributes: []*AttributeDefinition{
+		{Name: "var", Description: "Expression for the HTML", Required: true, Type: AttributeExpression},
+		{Name: "policy", Description: "Name of the sanitize policy, such as `basic` or `strict`", Type: AttributeString, Default: "basic"},
+	},
+	Body: BodyEmpty,
+}
+
+//
+// Definition of `MarkdownTag`.
+//
+var MarkdownTagDefinition = &TagDefinition{
+	Name:        "markdown",
+	Description: "Convert Markdown, given by an expression or as the body, to HTML.",
+	Attributes: []*AttributeDefinition{
+		{Name: "var", Description: "Expression for the Markdown, instead of the body", Type: AttributeExpression},
+		{Name: "policy", Description: "Name of the sanitize policy for the result, such as `markdown`", Type: AttributeString},
+	},
+}
+
+//
+// Definition of `JSONTag`.
+//
+var JSONTagDefinition = &TagDefinition{
+	Name:        "json",
+	Description: "Write a value as JSON that is safe to embed in HTML, optionally as a `<script type=\"application/json\">` block.",
+	Attributes: []*AttributeDefinition{
+		{Name: "var", Description: "Expression for the value", Required: true, Type: AttributeExpression},
+		{Name: "id", Description: "Id of the `<script>` block to write the JSON in", Type: AttributeString, AllowExpression: true},
+	},
+	Body: BodyEmpty,
+}
+
+//
+// Definition of `AssetScriptTag`.
+//
+var AssetScriptTagDefinition = &TagDefinition{
+	Name:        "script",
+	Description: "Write a `<script>` element with the fingerprinted URL and integrity hash of an asset.",
+	Attributes: []*AttributeDefinition{
+		{Name: "src", Description: "Logical name of the asset, such as `app.js`", Required: true, Type: AttributeString, AllowExpression: true},
+	},
+	Body: BodyEmpty,
+}
+
+//
+// Definition of `AssetStyleTag`.
+//
+var AssetStyleTagDefinition = &TagDefinition{
+	Name:        "style",
+	Description: "Write a stylesheet `<link>` element with the fingerprinted URL and integrity hash of an asset.",
+	Attributes: []*AttributeDefinition{
+		{Name: "href", Description: "Logical name of the asset, such as `app.css`", Required: true, Type: AttributeString, AllowExpression: true},
+	},
+	Body: BodyEmpty,
+}
+
+//
+// Definitions of the tags that ship with snowmark. The definitions
+// are looked up using the function that is registered as the tag
+// processor, so that they apply irrespective of the name the tag
+// is registered with. `MarkdownTag` is left out, so that programs that
+// do not use it do not link the Markdown converter; `MarkdownLibrary`
+// passes its definition instead.
+//
+var standardTagDefinitions = map[uintptr]*TagDefinition{
+	funcPointer(GetVariableTag):        GetVariableTagDefinition,
+	funcPointer(SetVariableTag):        SetVariableTagDefinition,
+	funcPointer(IfElseTag):             IfElseTagDefinition,
+	funcPointer(ForEachTag):            ForEachTagDefinition,
+	funcPointer(IncludeTag):            IncludeTagDefinition,
+	funcPointer(MessageTag):            MessageTagDefinition,
+	funcPointer(FormatNumberTag):       FormatNumberTagDefinition,
+	funcPointer(FormatCurrencyTag):     FormatCurrencyTagDefinition,
+	funcPointer(FormatDateTag):         FormatDateTagDefinition,
+	funcPointer(FormatRelativeTimeTag): FormatRelativeTimeTagDefinition,
+	funcPointer(BidiTag):               BidiTagDefinition,
+	funcPointer(URLTag):                URLTagDefinition,
+	funcPointer(SanitizeTag):           SanitizeTagDefinition,
+	funcPointer(JSONTag):               JSONTagDefinition,
+	funcPointer(AssetScriptTag):        AssetScriptTagDefinition,
+	funcPointer(AssetStyleTag):         AssetStyleTagDefinition,
+}
+
+func funcPointer(tagProcessor CustomTagProcessor) uintptr {
+	return reflect.ValueOf(tagProcessor).Pointer()
+}
+
+//
+// Write a Markdown reference of all custom tags that have a
+// definition registered with this processor.
+//
+func (pageProcessor *HtmlPageProcessor) WriteTagReference(writer io.Writer) error {
+	if writer == nil {
+		return errors.New("Writer is required to write tag reference")
+	}
+
+	names := make([]string, 0, len(pageProcessor._definitions))
+	for name := range pageProcessor._definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	builder := strings.Builder{}
+	for _, name := range names {
+		writeTagReference(&builder, name, pageProcessor._definitions[name])
+	}
+
+	_, err := io.WriteString(writer, builder.String())
+	return err
+}
+
+func writeTagReference(builder *strings.Builder, name string, definition *TagDefinition) {
+	builder.WriteString("## `<" + name + ">`\n\n")
+	if definition.Description != "" {
+		builder.WriteString(definition.Description + "\n\n")
+	}
+
+	builder.WriteString("Body: " + definition.Body.String() + "\n\n")
+
+	if len(definition.Attributes) > 0 {
+		builder.WriteString("| Attribute | Type | Required | Default | Expression | Description |\n")
+		builder.WriteString("|-----------|------|----------|---------|------------|-------------|\n")
+		for _, attribute := range definition.Attributes {
+			builder.WriteString("| `" + attribute.Name + "` | " + attribute.Type.String() + " | " + yesNo(attribute.Required) + " | ")
+			if attribute.Default != "" {
+				builder.WriteString("`" + attribute.Default + "`")
+			}
+			builder.WriteString(" | " + yesNo(attribute.AllowExpression) + " | " + attribute.Description + " |\n")
+		}
+		builder.WriteString("\n")
+	}
+
+	if len(definition.Children) > 0 {
+		builder.WriteString("Children:\n\n")
+		for _, child := range definition.Children {
+			builder.WriteString("* `" + child.Name + "`")
+			if child.Required {
+				builder.WriteString(" (required)")
+			}
+			if child.Description != "" {
+				builder.WriteString(": " + child.Description)
+			}
+			builder.WriteString("\n")
+		}
+		builder.WriteString("\n")
+	}
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+
+	return "no"
+}
@@ -0,0 +1,139 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sangupta/lhtml"
+	"github.com/stretchr/testify/assert"
+)
+
+var greetTagDefinition = &TagDefinition{
+	Name:        "greet",
+	Description: "Greet someone.",
+	Attributes: []*AttributeDefinition{
+		{Name: "name", Description: "Whom to greet", Required: true, AllowExpression: true},
+		{Name: "greeting", Description: "The greeting", Default: "Hello"},
+		{Name: "times", Description: "Number of greetings", Type: AttributeNumber, Default: "1"},
+	},
+	Body: BodyEmpty,
+}
+
+func greetTag(node *lhtml.HtmlNode, model *Model, evaluator *Evaluator) error {
+	name, err := evaluator.GetAttributeValueAsString(node, "name", model)
+	if err != nil {
+		return err
+	}
+
+	greeting, _ := node.GetAttributeValue("greeting")
+	evaluator.WriteString(greeting + " " + name)
+	return nil
+}
+
+func TestTagDefinitionDefaults(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	_, err := processor.AddCustomTagWithDefinition("greet", greetTag, greetTagDefinition)
+	assert.NoError(t, err)
+
+	definition, exists := processor.GetTagDefinition("GREET")
+	assert.True(t, exists)
+	assert.Equal(t, greetTagDefinition, definition)
+
+	model := NewModel()
+	model.Put("user", "world")
+
+	html, err := processor.MergeHtml("<greet expr:name='user' />", model)
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello world", html)
+
+	html, err = processor.MergeHtml("<greet name='you' greeting='Hi' />", model)
+	assert.NoError(t, err)
+	assert.Equal(t, "Hi you", html)
+
+	processor.RemoveCustomTag("greet")
+	_, exists = processor.GetTagDefinition("greet")
+	assert.False(t, exists)
+}
+
+func TestTagDefinitionCheck(t *testing.T) {
+	check := func(html string) error {
+		elements, err := lhtml.ParseHtmlString(html)
+		assert.NoError(t, err)
+		return greetTagDefinition.Check(elements.First())
+	}
+
+	assert.NoError(t, check("<greet name='a' />"))
+	assert.EqualError(t, check("<greet />"), "Tag <greet> is missing required attribute 'name'")
+	assert.EqualError(t, check("<greet name='a' times='x' />"), "Attribute 'times' of tag <greet> must be a number")
+	assert.EqualError(t, check("<greet name='a' expr:greeting='x' />"), "Attribute 'greeting' of tag <greet> does not allow expressions")
+	assert.EqualError(t, check("<greet name='a'>body</greet>"), "Tag <greet> must be self-closing")
+
+	elements, _ := lhtml.ParseHtmlString("<if condition='a'><else /></if>")
+	assert.EqualError(t, IfElseTagDefinition.Check(elements.First()), "Tag <if> does not have a 'then' clause")
+
+	elements, _ = lhtml.ParseHtmlString("<if condition='a'><then /><div /></if>")
+	assert.EqualError(t, IfElseTagDefinition.Check(elements.First()), "Tag <if> does not allow child <div>")
+
+	assert.Error(t, IfElseTagDefinition.Check(nil))
+}
+
+func TestStandardTagDefinitions(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	processor.AddCustomTag("c:if", IfElseTag)
+	processor.AddCustomTag("c:get", GetVariableTag)
+
+	definition, exists := processor.GetTagDefinition("c:if")
+	assert.True(t, exists)
+	assert.Equal(t, IfElseTagDefinition, definition)
+
+	_, err := processor.MergeHtml("<div><c:get /></div>", NewModel())
+	assert.NoError(t, err)
+
+	diagnostics := processor.Validate("<c:get var='a' unknown='b' /><c:if condition='a'><c:then /><c:other /></c:if>")
+	assert.Equal(t, 2, len(diagnostics))
+	assert.Equal(t, SeverityWarning, diagnostics[0].Severity)
+	assert.Equal(t, "Tag <c:get> does not define attribute 'unknown'", diagnostics[0].Message)
+	assert.Equal(t, "Tag <c:if> does not allow child <c:other>", diagnostics[1].Message)
+
+	// usages that do not match fail, unless the tag is added without
+	// a definition
+	processor.SetStrictMode(true)
+	_, err = processor.MergeHtml("<div><c:if condition='false'><div /></c:if></div>", NewModel())
+	assert.Error(t, err)
+
+	processor.AddCustomTagWithDefinition("d:if", IfElseTag, nil)
+	_, exists = processor.GetTagDefinition("d:if")
+	assert.False(t, exists)
+
+	html, err := processor.MergeHtml("<div><d:if condition='false'><div /></d:if></div>", NewModel())
+	assert.NoError(t, err)
+	assert.Equal(t, "<div></div>", html)
+}
+
+func TestWriteTagReference(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	processor.AddCustomTagWithDefinition("greet", greetTag, greetTagDefinition)
+	processor.AddCustomTag("if", IfElseTag)
+
+	builder := strings.Builder{}
+	err := processor.WriteTagReference(&builder)
+	assert.NoError(t, err)
+
+	reference := builder.String()
+	assert.True(t, strings.Index(reference, "## `<greet>`") < strings.Index(reference, "## `<if>`"))
+	assert.Contains(t, reference, "| `greeting` | string | no | `Hello` | no | The greeting |")
+	assert.Contains(t, reference, "* `then` (required): Rendered when the condition is `true`")
+
+	assert.Error(t, processor.WriteTagReference(nil))
+}
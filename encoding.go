@@ -0,0 +1,268 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+//
+// Create a new model from a JSON object. Integers are kept as `int`
+// and all other numbers become `float64`.
+//
+func NewModelFromJSON(reader io.Reader) (*Model, error) {
+	if reader == nil {
+		return nil, errors.New("Reader is required to read model")
+	}
+
+	values := make(map[string]interface{})
+	decoder := json.NewDecoder(reader)
+	decoder.UseNumber()
+
+	err := decoder.Decode(&values)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return NewModel(), nil
+		}
+
+		return nil, err
+	}
+
+	return NewModelFromMap(values), nil
+}
+
+//
+// Create a new model from a YAML mapping. Integers are kept as `int`
+// and all other numbers become `float64`.
+//
+func NewModelFromYAML(reader io.Reader) (*Model, error) {
+	if reader == nil {
+		return nil, errors.New("Reader is required to read model")
+	}
+
+	values := make(map[string]interface{})
+	err := yaml.NewDecoder(reader).Decode(&values)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return NewModel(), nil
+		}
+
+		return nil, err
+	}
+
+	return NewModelFromMap(values), nil
+}
+
+//
+// Create a new model from the given map. The values are copied and
+// converted to the types that expressions work with: all integers
+// become `int`, other numbers `float64`, slices `[]interface{}`, and
+// maps and structs become `map[string]interface{}`. The given map is
+// not modified.
+//
+func NewModelFromMap(values map[string]interface{}) *Model {
+	model := NewModel()
+	for key, value := range values {
+		model._map[key] = normalizeValue(reflect.ValueOf(value))
+	}
+
+	return model
+}
+
+//
+// Create a new model from the exported fields of the given struct,
+// or pointer to a struct. Fields are named as per their `json` tag
+// if present, and fields tagged `json:"-"` are skipped. Values are
+// converted in the same way as `NewModelFromMap`.
+//
+func NewModelFromStruct(value interface{}) (*Model, error) {
+	reflected := reflect.ValueOf(value)
+	for reflected.Kind() == reflect.Ptr || reflected.Kind() == reflect.Interface {
+		if reflected.IsNil() {
+			return nil, errors.New("Struct cannot be nil")
+		}
+		reflected = reflected.Elem()
+	}
+
+	if reflected.Kind() != reflect.Struct {
+		return nil, errors.New("Value must be a struct or a pointer to a struct")
+	}
+
+	model := NewModel()
+	model._map = structToMap(reflected)
+	return model, nil
+}
+
+//
+// Create a new model from the environment variables that start with
+// the given prefix. The prefix is removed from the keys, such that
+// `APP_NAME` is available as `NAME` for prefix `APP_`. An empty prefix
+// returns all environment variables.
+//
+func NewModelFromEnv(prefix string) *Model {
+	model := NewModel()
+	for _, variable := range os.Environ() {
+		key, value, found := strings.Cut(variable, "=")
+		if !found || !strings.HasPrefix(key, prefix) || key == prefix {
+			continue
+		}
+
+		model._map[strings.TrimPrefix(key, prefix)] = value
+	}
+
+	return model
+}
+
+//
+// Write the model as a JSON object, such as for debugging. The values
+// of the parents of the model are included, as expressions see them.
+//
+func (model *Model) MarshalJSON() ([]byte, error) {
+	return json.Marshal(model.values())
+}
+
+//
+// Convert the value to the types that expressions work with.
+//
+func normalizeValue(value reflect.Value) interface{} {
+	if !value.IsValid() {
+		return nil
+	}
+
+	switch typed := value.Interface().(type) {
+	case json.Number:
+		integer, err := typed.Int64()
+		if err == nil && integer >= math.MinInt && integer <= math.MaxInt {
+			return int(integer)
+		}
+
+		float, _ := typed.Float64()
+		return float
+
+	case time.Time, time.Duration, *Model, *Lazy:
+		return typed
+
+	case func() (interface{}, error):
+		return wrapLazy(typed)
+	}
+
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		integer := value.Int()
+		if integer >= math.MinInt && integer <= math.MaxInt {
+			return int(integer)
+		}
+		return float64(integer)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		integer := value.Uint()
+		if integer <= math.MaxInt {
+			return int(integer)
+		}
+		return float64(integer)
+
+	case reflect.Float32, reflect.Float64:
+		return value.Float()
+
+	case reflect.Bool:
+		return value.Bool()
+
+	case reflect.String:
+		return value.String()
+
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+		return normalizeValue(value.Elem())
+
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return nil
+		}
+
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			// keep bytes as they are
+			return value.Interface()
+		}
+
+		items := make([]interface{}, value.Len())
+		for index := range items {
+			items[index] = normalizeValue(value.Index(index))
+		}
+		return items
+
+	case reflect.Map:
+		if value.IsNil() {
+			return nil
+		}
+
+		values := make(map[string]interface{}, value.Len())
+		iterator := value.MapRange()
+		for iterator.Next() {
+			values[fmt.Sprint(iterator.Key().Interface())] = normalizeValue(iterator.Value())
+		}
+		return values
+
+	case reflect.Struct:
+		return structToMap(value)
+	}
+
+	return value.Interface()
+}
+
+//
+// Convert the exported fields of the struct to a map. Fields of
+// embedded structs are added as if they were fields of the struct.
+//
+func structToMap(value reflect.Value) map[string]interface{} {
+	values := make(map[string]interface{})
+	structType := value.Type()
+
+	for index := 0; index < structType.NumField(); index++ {
+		field := structType.Field(index)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+			for key, item := range structToMap(value.Field(index)) {
+				values[key] = item
+			}
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Name
+		tag := strings.Split(field.Tag.Get("json"), ",")[0]
+		if tag == "-" {
+			continue
+		}
+
+		if tag != "" {
+			name = tag
+		}
+
+		values[name] = normalizeValue(value.Field(index))
+	}
+
+	return values
+}
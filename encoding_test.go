@@ -0,0 +1,200 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewModelFromJSON(t *testing.T) {
+	model, err := NewModelFromJSON(strings.NewReader(`{"count": 3, "price": 2.5, "big": 12345678901234567890, "tags": ["a", 1], "user": {"age": 40}}`))
+	assert.NoError(t, err)
+
+	value, _ := model.Get("count")
+	assert.Equal(t, 3, value)
+
+	value, _ = model.Get("price")
+	assert.Equal(t, 2.5, value)
+
+	value, _ = model.Get("big")
+	assert.Equal(t, 12345678901234567890.0, value)
+
+	value, _ = model.Get("tags")
+	assert.Equal(t, []interface{}{"a", 1}, value)
+
+	value, _ = model.Get("user")
+	assert.Equal(t, map[string]interface{}{"age": 40}, value)
+
+	model, err = NewModelFromJSON(strings.NewReader(""))
+	assert.NoError(t, err)
+	assert.True(t, model.IsEmpty())
+
+	_, err = NewModelFromJSON(strings.NewReader(`[1, 2]`))
+	assert.Error(t, err)
+
+	_, err = NewModelFromJSON(nil)
+	assert.Error(t, err)
+}
+
+func TestNewModelFromYAML(t *testing.T) {
+	model, err := NewModelFromYAML(strings.NewReader("count: 3\nprice: 2.5\nitems:\n  - name: one\n    qty: 2\n"))
+	assert.NoError(t, err)
+
+	value, _ := model.Get("count")
+	assert.Equal(t, 3, value)
+
+	value, _ = model.Get("items")
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "one", "qty": 2}}, value)
+
+	model, err = NewModelFromYAML(strings.NewReader(""))
+	assert.NoError(t, err)
+	assert.True(t, model.IsEmpty())
+
+	_, err = NewModelFromYAML(strings.NewReader("- 1\n- 2\n"))
+	assert.Error(t, err)
+}
+
+func TestNewModelFromMap(t *testing.T) {
+	values := map[string]interface{}{
+		"small":  int64(7),
+		"ratio":  float32(0.5),
+		"names":  []string{"a", "b"},
+		"scores": map[string]int64{"x": 1},
+		"bytes":  []byte("hi"),
+		"when":   time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC),
+		"none":   nil,
+	}
+
+	model := NewModelFromMap(values)
+
+	value, _ := model.Get("small")
+	assert.Equal(t, 7, value)
+
+	value, _ = model.Get("ratio")
+	assert.Equal(t, 0.5, value)
+
+	value, _ = model.Get("names")
+	assert.Equal(t, []interface{}{"a", "b"}, value)
+
+	value, _ = model.Get("scores")
+	assert.Equal(t, map[string]interface{}{"x": 1}, value)
+
+	value, _ = model.Get("bytes")
+	assert.Equal(t, []byte("hi"), value)
+
+	value, _ = model.Get("when")
+	assert.Equal(t, values["when"], value)
+
+	value, exists := model.Get("none")
+	assert.True(t, exists)
+	assert.Nil(t, value)
+
+	// the given map is not modified
+	assert.Equal(t, int64(7), values["small"])
+}
+
+type testAddress struct {
+	City string `json:"city"`
+}
+
+type testBase struct {
+	ID int
+}
+
+type testUser struct {
+	testBase
+	Name     string       `json:"name"`
+	Age      uint8        `json:"age,omitempty"`
+	Address  *testAddress `json:"address"`
+	Password string       `json:"-"`
+	internal string
+}
+
+func TestNewModelFromStruct(t *testing.T) {
+	model, err := NewModelFromStruct(&testUser{
+		testBase: testBase{ID: 9},
+		Name:     "Jane",
+		Age:      40,
+		Address:  &testAddress{City: "Delhi"},
+		Password: "secret",
+		internal: "hidden",
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"ID":      9,
+		"name":    "Jane",
+		"age":     40,
+		"address": map[string]interface{}{"city": "Delhi"},
+	}, model.GetMap())
+
+	_, err = NewModelFromStruct(map[string]interface{}{})
+	assert.Error(t, err)
+
+	var user *testUser
+	_, err = NewModelFromStruct(user)
+	assert.Error(t, err)
+
+	// usable in expressions
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "")
+	model, _ = NewModelFromStruct(testUser{Name: "Jane", Age: 40, Address: &testAddress{City: "Delhi"}})
+	html, err := processor.MergeHtml("<p><get var='age / 3' />/<get var='address.city' /></p>", model)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>13/Delhi</p>", html)
+}
+
+func TestNewModelFromEnv(t *testing.T) {
+	os.Setenv("SNOWMARK_TEST_NAME", "snowmark")
+	defer os.Unsetenv("SNOWMARK_TEST_NAME")
+
+	model := NewModelFromEnv("SNOWMARK_TEST_")
+	assert.Equal(t, 1, model.Size())
+	assert.Equal(t, "snowmark", model.GetString("NAME", ""))
+}
+
+func TestModelMarshalJSON(t *testing.T) {
+	model := NewModel()
+	model.Put("count", 3)
+	model.Put("tags", []string{"a"})
+
+	data, err := json.Marshal(model)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"count":3,"tags":["a"]}`, string(data))
+
+	// values of the parents are included
+	page := NewModel()
+	page.Overlay(model)
+	page.Put("count", 4)
+	page.Put("title", "Home")
+
+	data, err = json.Marshal(page)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"count":4,"tags":["a"],"title":"Home"}`, string(data))
+
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "")
+	processor.Import(JSONLibrary(), "")
+	processor.SetStrictMode(true)
+
+	outer := NewModel()
+	outer.Put("page", page)
+	html, err := processor.MergeHtml(`<div><json var="page" /></div>`, outer)
+	assert.NoError(t, err)
+	assert.Equal(t, `<div>{"count":4,"tags":["a"],"title":"Home"}</div>`, html)
+}
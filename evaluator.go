@@ -14,6 +14,7 @@ package snowmark
 import (
 	"errors"
 	"strings"
+	"time"
 
 	"github.com/maja42/goval"
 	"github.com/sangupta/berry"
@@ -22,12 +23,29 @@ import (
 
 const PREFIX = "expr:"
 
+//
+// The maximum depth of templates included within each other.
+//
+const maxTemplateDepth = 32
+
 //
 // The Evaluator instance.
 //
 type Evaluator struct {
 	builder   *strings.Builder
 	processor *HtmlPageProcessor
+	model     *Model
+	options   *MergeOptions
+	functions map[string]goval.ExpressionFunction
+	template  *Template
+	current   *lhtml.HtmlNode
+	event     *RenderEvent
+	depth     int
+	variables map[string][]string
+	started   time.Time
+	nesting   int
+	lazy      map[*Lazy]*lazyResult
+	lazyError error
 }
 
 //
@@ -63,24 +81,205 @@ func (evaluator *Evaluator) GetEvaluation() string {
 }
 
 //
-// Evaluate the given node against the model.
+// Evaluate the given node against the model. Errors are reported
+// along with the location of the node in the template. Unless the
+// processor is in strict mode, the error is passed to the `OnWarning`
+// function of the merge options, and the merge continues with the next
+// node.
 //
 func (evaluator *Evaluator) EvaluateNode(node *lhtml.HtmlNode, model *Model) error {
 	if node == nil {
 		return nil
 	}
 
+	previous := evaluator.current
+	evaluator.current = node
+	err := evaluator.evaluateSandboxedNode(node, model)
+	evaluator.current = previous
+
+	if err == nil {
+		return nil
+	}
+
+	err = evaluator.wrapError(node, "", err)
+
+	// sandbox violations always abort the merge
+	var sandboxError *SandboxError
+	if errors.As(err, &sandboxError) {
+		return err
+	}
+
+	if evaluator.processor != nil && !evaluator.processor.IsStrictMode() {
+		evaluator.warn(err)
+		return nil
+	}
+
+	return err
+}
+
+//
+// Report an error that is skipped as the processor is not in strict
+// mode.
+//
+func (evaluator *Evaluator) warn(err error) {
+	if evaluator.options != nil && evaluator.options.OnWarning != nil {
+		evaluator.options.OnWarning(err)
+	}
+}
+
+//
+// Evaluate the node within the sandbox of the processor, if any.
+//
+func (evaluator *Evaluator) evaluateSandboxedNode(node *lhtml.HtmlNode, model *Model) error {
+	if evaluator.processor == nil || evaluator.processor._sandbox == nil {
+		return evaluator.evaluateNode(node, model)
+	}
+
+	if node.NodeType == lhtml.ElementNode {
+		evaluator.nesting++
+		defer func() {
+			evaluator.nesting--
+		}()
+	}
+
+	err := evaluator.checkSandboxLimits()
+	if err != nil {
+		return err
+	}
+
+	err = evaluator.processor._sandbox.checkNode(node, evaluator.processor.HasCustomTag(node.NodeName()))
+	if err != nil {
+		return err
+	}
+
+	return evaluator.evaluateNode(node, model)
+}
+
+func (evaluator *Evaluator) evaluateNode(node *lhtml.HtmlNode, model *Model) error {
 	nodeName := node.NodeName()
 
 	// custom tag, process it differently?
 	customTag, exists := evaluator.processor.GetCustomTag(nodeName)
 	if exists {
-		return customTag(node, model, evaluator)
+		definition, hasDefinition := evaluator.processor.GetTagDefinition(nodeName)
+		if hasDefinition {
+			// templates get their defaults before they are merged
+			if evaluator.template == nil {
+				definition.ApplyDefaults(node)
+			}
+
+			err := definition.Check(node)
+			if err != nil {
+				return err
+			}
+		}
+
+		event := evaluator.BeginEvent(EventTag, nodeName, node)
+
+		// errors of lazy values read using `Model.Get` fail the tag
+		previous := evaluator.lazyError
+		evaluator.lazyError = nil
+		err := customTag(node, model, evaluator)
+		if err == nil {
+			err = evaluator.lazyError
+		}
+		evaluator.lazyError = previous
+
+		if err != nil {
+			err = evaluator.wrapError(node, "", err)
+		}
+
+		evaluator.EndEvent(event, err)
+		return err
 	}
 
 	// process a normal tag
-	evaluator.processNormalNode(node, model)
-	return nil
+	return evaluator.processNormalNode(node, model)
+}
+
+//
+// Return the position of the given node in the template being
+// merged. An invalid position is returned if the node is not part
+// of the template.
+//
+func (evaluator *Evaluator) PositionOf(node *lhtml.HtmlNode) Position {
+	return evaluator.template.PositionOf(node)
+}
+
+//
+// Attach the location of the node, or of the given attribute of the
+// node, to the error. Errors that already carry a location are
+// returned as is.
+//
+func (evaluator *Evaluator) wrapError(node *lhtml.HtmlNode, attributeName string, err error) error {
+	var templateError *TemplateError
+	if errors.As(err, &templateError) {
+		return err
+	}
+
+	if evaluator.template == nil || node == nil {
+		return err
+	}
+
+	offset, found := evaluator.template.attributePosition(node, attributeName)
+	if !found {
+		return err
+	}
+
+	// point inside the expression, if we know where it failed
+	var syntaxError *ExpressionSyntaxError
+	if attributeName != "" && errors.As(err, &syntaxError) {
+		offset += syntaxError.Offset
+	}
+
+	return evaluator.template.newError(offset, err)
+}
+
+//
+// Find the attribute of the node whose value is the given expression.
+//
+func findExpressionAttribute(node *lhtml.HtmlNode, expr string) string {
+	if node == nil {
+		return ""
+	}
+
+	for _, attr := range node.Attributes {
+		if attr.Value == expr {
+			return attr.Name
+		}
+	}
+
+	return ""
+}
+
+//
+// Evaluate all nodes of the given template against the model, as
+// part of the current merge. This is used to merge the main template
+// as well as included templates, and the given event kind is raised
+// for the template.
+//
+func (evaluator *Evaluator) EvaluateTemplate(template *Template, model *Model, kind EventKind) error {
+	if template == nil {
+		return errors.New("Template is required to evaluate")
+	}
+
+	if evaluator.depth >= maxTemplateDepth {
+		return errors.New("Templates are nested too deep, check for recursive includes: " + template.Name)
+	}
+
+	template.prepare(evaluator.processor)
+
+	previous := evaluator.template
+	evaluator.template = template
+	evaluator.depth++
+
+	event := evaluator.BeginEvent(kind, template.Name, nil)
+	err := evaluator.EvaluateNodes(template.elements.Nodes(), model)
+	evaluator.EndEvent(event, err)
+
+	evaluator.depth--
+	evaluator.template = previous
+	return err
 }
 
 //
@@ -101,6 +300,21 @@ func (evaluator *Evaluator) EvaluateNodes(nodes []*lhtml.HtmlNode, model *Model)
 	return nil
 }
 
+//
+// Evaluate multiple nodes against the model and return what they
+// write, instead of writing it to the page.
+//
+func (evaluator *Evaluator) CaptureNodes(nodes []*lhtml.HtmlNode, model *Model) (string, error) {
+	previous := evaluator.builder
+	builder := strings.Builder{}
+
+	evaluator.builder = &builder
+	err := evaluator.EvaluateNodes(nodes, model)
+	evaluator.builder = previous
+
+	return builder.String(), err
+}
+
 //
 // Evaluate an expression against the model.
 //
@@ -109,8 +323,51 @@ func (evaluator *Evaluator) EvaluateExpression(expr string, model *Model) (inter
 		return "", nil
 	}
 
-	eval := goval.NewEvaluator()
-	return eval.Evaluate(expr, model._map, nil)
+	event := evaluator.BeginEvent(EventExpression, expr, evaluator.current)
+
+	values, err := evaluator.resolveVariables(expr, model.values())
+	if err == nil && evaluator.processor != nil && evaluator.processor._sandbox != nil {
+		err = evaluator.checkSandboxExpression(expr, values)
+	}
+
+	var value interface{}
+	if err == nil {
+		eval := goval.NewEvaluator()
+		value, err = eval.Evaluate(expr, values, evaluator.getFunctions())
+	}
+
+	if err == nil && evaluator.processor != nil && evaluator.processor._sandbox != nil {
+		err = checkSandboxValue(value)
+	}
+
+	if err != nil {
+		// report where the expression is used
+		err = evaluator.wrapError(evaluator.current, findExpressionAttribute(evaluator.current, expr), err)
+		value = nil
+	}
+
+	evaluator.EndEvent(event, err)
+	return value, err
+}
+
+//
+// Return the functions of the processor bound to this evaluator,
+// in the form that `goval` expects.
+//
+func (evaluator *Evaluator) getFunctions() map[string]goval.ExpressionFunction {
+	if evaluator.functions != nil || evaluator.processor == nil {
+		return evaluator.functions
+	}
+
+	evaluator.functions = make(map[string]goval.ExpressionFunction, len(evaluator.processor._functions))
+	for name, function := range evaluator.processor._functions {
+		bound := function
+		evaluator.functions[name] = func(args ...interface{}) (interface{}, error) {
+			return bound(evaluator, args...)
+		}
+	}
+
+	return evaluator.functions
 }
 
 //
@@ -147,7 +404,7 @@ func (evaluator *Evaluator) GetAttributeValue(node *lhtml.HtmlNode, attributeNam
 
 	attr = node.GetAttribute("expr:" + attributeName)
 	if attr == nil {
-		return "", errors.New("Missing attribute 'value'")
+		return "", errors.New("Missing attribute '" + attributeName + "'")
 	}
 
 	// evaluate expression
@@ -177,10 +434,25 @@ func (evaluator *Evaluator) processNormalNode(node *lhtml.HtmlNode, model *Model
 		return nil
 	}
 
-	// this is an element node
-	// start building
-	builder.WriteString("<")
-	builder.WriteString(node.NodeName())
+	// lang and dir from the directives
+	directives, err := evaluator.directiveAttributes(node, model)
+	if err != nil {
+		return err
+	}
+
+	// nonce for inline scripts and styles
+	security, err := evaluator.securityAttributes(node)
+	if err != nil {
+		return err
+	}
+	directives = append(directives, security...)
+
+	// this is an element node, whose start tag is written only once
+	// all attributes are evaluated, so that a failing attribute leaves
+	// no partial markup behind
+	start := strings.Builder{}
+	start.WriteString("<")
+	start.WriteString(node.NodeName())
 
 	// attributes
 	if node.ContainsAttributes() {
@@ -188,6 +460,10 @@ func (evaluator *Evaluator) processNormalNode(node *lhtml.HtmlNode, model *Model
 			name := attr.Name
 			value := attr.Value
 
+			if name == LangDirective || name == DirDirective {
+				continue
+			}
+
 			if strings.HasPrefix(name, PREFIX) {
 				// evaluate expression
 				updatedValue, err := evaluator.EvaluateExpressionAsString(value, model)
@@ -200,14 +476,31 @@ func (evaluator *Evaluator) processNormalNode(node *lhtml.HtmlNode, model *Model
 				value = updatedValue
 			}
 
-			builder.WriteString(" ")
-			builder.WriteString(name)
-			builder.WriteString("=\"")
-			builder.WriteString(value)
-			builder.WriteString("\"")
+			if evaluator.processor._sandbox != nil {
+				err := evaluator.processor._sandbox.checkAttribute(node.NodeName(), name, value)
+				if err != nil {
+					return err
+				}
+			}
+
+			start.WriteString(" ")
+			start.WriteString(name)
+			start.WriteString("=\"")
+			start.WriteString(value)
+			start.WriteString("\"")
 		}
 	}
 
+	for _, attr := range directives {
+		start.WriteString(" ")
+		start.WriteString(attr.Name)
+		start.WriteString("=\"")
+		start.WriteString(attr.Value)
+		start.WriteString("\"")
+	}
+
+	builder.WriteString(start.String())
+
 	// self-closing?
 	if !node.HasChildren() {
 		builder.WriteString(" />")
@@ -215,10 +508,9 @@ func (evaluator *Evaluator) processNormalNode(node *lhtml.HtmlNode, model *Model
 		builder.WriteString(">")
 
 		// work on children
-		if node.HasChildren() {
-			for _, child := range node.Children() {
-				evaluator.EvaluateNode(child, model)
-			}
+		err := evaluator.EvaluateNodes(node.Children(), model)
+		if err != nil {
+			return err
 		}
 
 		// close
@@ -0,0 +1,534 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"go/scanner"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+//
+// Error returned when an expression cannot be parsed. The `Offset`
+// is the byte offset inside the expression where the parser gave up.
+//
+type ExpressionSyntaxError struct {
+	Expression string
+	Offset     int
+	Message    string
+}
+
+//
+// Return the error message.
+//
+func (err *ExpressionSyntaxError) Error() string {
+	return err.Message + " at offset " + strconv.Itoa(err.Offset)
+}
+
+//
+// Information gathered while checking an expression. `Variables`
+// contains the top-level variable names that the expression reads,
+// and `Functions` the names of all functions it calls.
+//
+type expressionInfo struct {
+	Variables []string
+	Functions []string
+}
+
+//
+// A single lexical token of an expression.
+//
+type expressionToken struct {
+	offset int
+	tok    token.Token
+	lit    string
+}
+
+//
+// A recursive descent recognizer for the expression language
+// supported by `goval`. `goval` evaluates while it parses and stops
+// at the first unknown variable, thus it cannot be used to check the
+// syntax of an expression without a model. This recognizer accepts
+// the same grammar and does not evaluate anything.
+//
+type expressionParser struct {
+	expression string
+	tokens     []expressionToken
+	current    int
+	info       *expressionInfo
+}
+
+//
+// Check the syntax of the given expression and return the variables
+// and functions it references.
+//
+func parseExpression(expression string) (*expressionInfo, error) {
+	parser := &expressionParser{
+		expression: expression,
+		info:       &expressionInfo{},
+	}
+
+	err := parser.tokenize()
+	if err != nil {
+		return nil, err
+	}
+
+	if parser.peek().tok == token.EOF {
+		return nil, parser.errorf("Expression is empty")
+	}
+
+	err = parser.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+
+	if parser.peek().tok != token.EOF {
+		return nil, parser.unexpected()
+	}
+
+	return parser.info, nil
+}
+
+//
+// Check the syntax of the given expression without evaluating it.
+//
+func CheckExpressionSyntax(expression string) error {
+	_, err := parseExpression(expression)
+	return err
+}
+
+func (parser *expressionParser) tokenize() error {
+	src := []byte(parser.expression)
+	fileSet := token.NewFileSet()
+	file := fileSet.AddFile("", fileSet.Base(), len(src))
+
+	var firstError *ExpressionSyntaxError
+	errorHandler := func(pos token.Position, msg string) {
+		// illegal characters are checked on the tokens below
+		if firstError == nil && !strings.HasPrefix(msg, "illegal character") {
+			firstError = &ExpressionSyntaxError{
+				Expression: parser.expression,
+				Offset:     pos.Offset,
+				Message:    msg,
+			}
+		}
+	}
+
+	var s scanner.Scanner
+	s.Init(file, src, errorHandler, 0)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.SEMICOLON && lit == "\n" {
+			// inserted automatically by the go scanner
+			continue
+		}
+
+		if tok.IsKeyword() {
+			// goval scans go keywords, such as `type` and `range`, as
+			// identifiers
+			tok = token.IDENT
+		}
+
+		offset := file.Offset(pos)
+		if tok == token.ILLEGAL && lit != "?" && lit != ":" && lit != "~" {
+			return &ExpressionSyntaxError{
+				Expression: parser.expression,
+				Offset:     offset,
+				Message:    "Illegal character " + strconv.Quote(lit),
+			}
+		}
+
+		parser.tokens = append(parser.tokens, expressionToken{
+			offset: offset,
+			tok:    tok,
+			lit:    lit,
+		})
+
+		if tok == token.EOF {
+			break
+		}
+	}
+
+	if firstError != nil {
+		return firstError
+	}
+
+	return nil
+}
+
+func (parser *expressionParser) peek() expressionToken {
+	return parser.tokens[parser.current]
+}
+
+func (parser *expressionParser) next() expressionToken {
+	current := parser.tokens[parser.current]
+	if current.tok != token.EOF {
+		parser.current++
+	}
+
+	return current
+}
+
+//
+// Check if the current token is the given punctuation. `?`, `:` and
+// `~` are reported as illegal tokens by the go scanner, and as a
+// `COLON` for `:`, thus they are matched on their literal.
+//
+func (parser *expressionParser) is(symbol string) bool {
+	current := parser.peek()
+	switch current.tok {
+	case token.ILLEGAL:
+		return current.lit == symbol
+
+	case token.COLON:
+		return symbol == ":"
+
+	case token.TILDE:
+		return symbol == "~"
+
+	case token.ARROW:
+		// go scans `<-` as one token, goval treats it as `<` and `-`
+		return symbol == "<"
+
+	case token.EOF, token.IDENT, token.INT, token.FLOAT, token.STRING, token.CHAR, token.IMAG:
+		return false
+	}
+
+	return current.tok.String() == symbol
+}
+
+func (parser *expressionParser) accept(symbol string) bool {
+	if !parser.is(symbol) {
+		return false
+	}
+
+	current := parser.peek()
+	if current.tok == token.ARROW {
+		// keep the unary minus that follows
+		parser.tokens[parser.current] = expressionToken{
+			offset: current.offset + 1,
+			tok:    token.SUB,
+			lit:    "",
+		}
+		return true
+	}
+
+	parser.next()
+	return true
+}
+
+func (parser *expressionParser) expect(symbol string) error {
+	if !parser.accept(symbol) {
+		return parser.unexpected()
+	}
+
+	return nil
+}
+
+func (parser *expressionParser) errorf(message string) error {
+	return &ExpressionSyntaxError{
+		Expression: parser.expression,
+		Offset:     parser.peek().offset,
+		Message:    message,
+	}
+}
+
+func (parser *expressionParser) unexpected() error {
+	current := parser.peek()
+	if current.tok == token.EOF {
+		return parser.errorf("Unexpected end of expression")
+	}
+
+	text := current.lit
+	if text == "" {
+		text = current.tok.String()
+	}
+
+	return parser.errorf("Unexpected token " + strconv.Quote(text))
+}
+
+//
+// Binary operators grouped by their precedence, lowest first.
+//
+var binaryOperators = [][]string{
+	{"||"},
+	{"&&"},
+	{"|"},
+	{"^"},
+	{"&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (parser *expressionParser) parseTernary() error {
+	err := parser.parseBinary(0)
+	if err != nil {
+		return err
+	}
+
+	if !parser.accept("?") {
+		return nil
+	}
+
+	err = parser.parseTernary()
+	if err != nil {
+		return err
+	}
+
+	err = parser.expect(":")
+	if err != nil {
+		return err
+	}
+
+	return parser.parseTernary()
+}
+
+func (parser *expressionParser) parseBinary(level int) error {
+	if level == len(binaryOperators) {
+		return parser.parseUnary()
+	}
+
+	err := parser.parseBinary(level + 1)
+	if err != nil {
+		return err
+	}
+
+	for parser.acceptAny(binaryOperators[level]) {
+		err = parser.parseBinary(level + 1)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (parser *expressionParser) acceptAny(symbols []string) bool {
+	for _, symbol := range symbols {
+		if parser.accept(symbol) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (parser *expressionParser) parseUnary() error {
+	if parser.acceptAny([]string{"!", "-", "~"}) {
+		return parser.parseUnary()
+	}
+
+	err := parser.parsePostfix()
+	if err != nil {
+		return err
+	}
+
+	for parser.isIn() {
+		parser.next()
+		err = parser.parsePostfix()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (parser *expressionParser) isIn() bool {
+	current := parser.peek()
+	return current.tok == token.IDENT && (current.lit == "in" || current.lit == "IN")
+}
+
+func (parser *expressionParser) parsePostfix() error {
+	err := parser.parsePrimary()
+	if err != nil {
+		return err
+	}
+
+	for {
+		if parser.accept(".") {
+			if parser.peek().tok != token.IDENT {
+				return parser.unexpected()
+			}
+
+			parser.next()
+			if parser.is("(") {
+				return parser.errorf("Method calls are not supported")
+			}
+			continue
+		}
+
+		if parser.accept("[") {
+			err = parser.parseIndex()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		return nil
+	}
+}
+
+//
+// Parse an index or a slice expression after the opening bracket.
+//
+func (parser *expressionParser) parseIndex() error {
+	if !parser.is(":") {
+		err := parser.parseTernary()
+		if err != nil {
+			return err
+		}
+
+		if parser.accept("]") {
+			return nil
+		}
+	}
+
+	err := parser.expect(":")
+	if err != nil {
+		return err
+	}
+
+	if parser.accept("]") {
+		return nil
+	}
+
+	err = parser.parseTernary()
+	if err != nil {
+		return err
+	}
+
+	return parser.expect("]")
+}
+
+func (parser *expressionParser) parsePrimary() error {
+	current := parser.peek()
+
+	switch current.tok {
+	case token.INT, token.FLOAT, token.STRING:
+		parser.next()
+		return nil
+
+	case token.IDENT:
+		if parser.isIn() {
+			return parser.unexpected()
+		}
+
+		parser.next()
+		switch current.lit {
+		case "nil", "true", "false":
+			return nil
+		}
+
+		if parser.accept("(") {
+			parser.info.Functions = appendUnique(parser.info.Functions, current.lit)
+			return parser.parseList(")")
+		}
+
+		parser.info.Variables = appendUnique(parser.info.Variables, current.lit)
+		return nil
+	}
+
+	if parser.accept("(") {
+		err := parser.parseTernary()
+		if err != nil {
+			return err
+		}
+
+		return parser.expect(")")
+	}
+
+	if parser.accept("[") {
+		return parser.parseList("]")
+	}
+
+	if parser.accept("{") {
+		return parser.parseObject()
+	}
+
+	return parser.unexpected()
+}
+
+//
+// Parse a comma separated list of expressions until the closing symbol.
+//
+func (parser *expressionParser) parseList(closing string) error {
+	if parser.accept(closing) {
+		return nil
+	}
+
+	for {
+		err := parser.parseTernary()
+		if err != nil {
+			return err
+		}
+
+		if parser.accept(closing) {
+			return nil
+		}
+
+		err = parser.expect(",")
+		if err != nil {
+			return err
+		}
+	}
+}
+
+//
+// Parse the members of an object literal after the opening brace.
+//
+func (parser *expressionParser) parseObject() error {
+	if parser.accept("}") {
+		return nil
+	}
+
+	for {
+		err := parser.parseBinary(0)
+		if err != nil {
+			return err
+		}
+
+		err = parser.expect(":")
+		if err != nil {
+			return err
+		}
+
+		err = parser.parseTernary()
+		if err != nil {
+			return err
+		}
+
+		if parser.accept("}") {
+			return nil
+		}
+
+		err = parser.expect(",")
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+
+	return append(values, value)
+}
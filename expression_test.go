@@ -0,0 +1,101 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckExpressionSyntaxValid(t *testing.T) {
+	valid := []string{
+		"name",
+		"age > 10",
+		"user.address.city",
+		"items[2].name",
+		"items[1:] + items[:2] + items[:]",
+		"a ? b : c ? d : e",
+		"!(a && b) || c <= -1",
+		"x <-1",
+		"len(\"text\") + max(1, 2, 3)",
+		"[1, [\"a\", false], 4.2]",
+		"{\"a\": 1, \"b\": {\"c\": 3}}",
+		"1 in [1, 2] && ~flags & 0xFF",
+		"a << 2 >> 1 ^ b | c % 2",
+		"`raw` + \"text\"",
+		"nil == nil",
+	}
+
+	for _, expression := range valid {
+		assert.NoError(t, CheckExpressionSyntax(expression), expression)
+	}
+}
+
+func TestCheckExpressionSyntaxInvalid(t *testing.T) {
+	invalid := []string{
+		"",
+		"a +",
+		"a +* b",
+		"(a",
+		"a)",
+		"a ? b",
+		"items[",
+		"user.",
+		"user.name()",
+		"'c'",
+		"a $ b",
+		"{\"a\" 1}",
+		"f(1,)",
+	}
+
+	for _, expression := range invalid {
+		assert.Error(t, CheckExpressionSyntax(expression), expression)
+	}
+}
+
+func TestCheckExpressionSyntaxOffset(t *testing.T) {
+	err := CheckExpressionSyntax("age > > 10")
+	assert.Error(t, err)
+
+	syntaxError, ok := err.(*ExpressionSyntaxError)
+	assert.True(t, ok)
+	assert.Equal(t, 6, syntaxError.Offset)
+}
+
+func TestParseExpressionReferences(t *testing.T) {
+	info, err := parseExpression("upper(user.name) + title + len(items[0]) + user.age")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user", "title", "items"}, info.Variables)
+	assert.Equal(t, []string{"upper", "len"}, info.Functions)
+}
+
+func TestCheckExpressionSyntaxKeywords(t *testing.T) {
+	// goval treats go keywords as identifiers
+	valid := []string{
+		"item.type",
+		"item.range + 1",
+		"type",
+		"map[\"key\"]",
+		"func(item.go)",
+		"items[0].default == item.select",
+	}
+
+	for _, expression := range valid {
+		assert.NoError(t, CheckExpressionSyntax(expression), expression)
+	}
+
+	info, err := parseExpression("secret(item.type) + range")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"item", "range"}, info.Variables)
+	assert.Equal(t, []string{"secret"}, info.Functions)
+}
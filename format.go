@@ -0,0 +1,506 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sangupta/berry"
+)
+
+//
+// The model key that holds the time zone of a merge, either as a
+// `*time.Location` or as a name like `Europe/Berlin`, unless the time
+// zone is given in the merge options.
+//
+const TimeZoneModelKey = "timeZone"
+
+//
+// Format a number with the decimal and grouping separators of the
+// locale, such as `1,234.5` in English and `1.234,5` in German. The
+// number is rounded half to even to the given number of fraction
+// digits; a negative number of digits shows up to three fraction digits
+// as needed.
+//
+func FormatNumber(locale string, value interface{}, decimals int) (string, error) {
+	minFraction, maxFraction := decimals, decimals
+	if decimals < 0 {
+		minFraction, maxFraction = 0, 3
+	}
+
+	digits, err := decimalString(value, 1, maxFraction)
+	if err != nil {
+		return "", err
+	}
+
+	return localizeDecimal(getLocaleData(locale), digits, minFraction), nil
+}
+
+//
+// Format a fraction as a percentage in the locale, such that `0.25`
+// is `25%` in English and `25 %` in German. A negative number of digits
+// rounds to whole percents.
+//
+func FormatPercent(locale string, value interface{}, decimals int) (string, error) {
+	if decimals < 0 {
+		decimals = 0
+	}
+
+	digits, err := decimalString(value, 100, decimals)
+	if err != nil {
+		return "", err
+	}
+
+	data := getLocaleData(locale)
+	negative := strings.HasPrefix(digits, "-")
+	number := localizeDecimal(data, strings.TrimPrefix(digits, "-"), decimals)
+
+	result := strings.Replace(data.percent, "{0}", number, 1)
+	if negative {
+		return "-" + result, nil
+	}
+
+	return result, nil
+}
+
+//
+// Format an amount of money in the given currency, such as `USD`, with
+// the currency symbol and pattern of the locale: `$1,234.50` in English
+// and `1.234,50 $` in German. The amount is rounded to the fraction
+// digits of the currency.
+//
+func FormatCurrency(locale string, value interface{}, code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 || strings.IndexFunc(code, func(r rune) bool { return r < 'A' || r > 'Z' }) >= 0 {
+		return "", errors.New("Invalid currency code: " + code)
+	}
+
+	fraction, exists := currencyDigits[code]
+	if !exists {
+		fraction = 2
+	}
+
+	digits, err := decimalString(value, 1, fraction)
+	if err != nil {
+		return "", err
+	}
+
+	data := getLocaleData(locale)
+	negative := strings.HasPrefix(digits, "-")
+	number := localizeDecimal(data, strings.TrimPrefix(digits, "-"), fraction)
+
+	symbol, exists := data.symbols[code]
+	if !exists {
+		symbol, exists = currencySymbols[code]
+	}
+	if !exists {
+		symbol = code
+	}
+
+	// keep symbols made of letters apart from the number
+	pattern := data.currency
+	last, _ := utf8.DecodeLastRuneInString(symbol)
+	if strings.HasPrefix(pattern, "¤{0}") && unicode.IsLetter(last) {
+		pattern = strings.Replace(pattern, "¤", "¤\u00a0", 1)
+	}
+
+	result := strings.Replace(strings.Replace(pattern, "{0}", number, 1), "¤", symbol, 1)
+	if negative {
+		return "-" + result, nil
+	}
+
+	return result, nil
+}
+
+//
+// Format a time in the locale using a CLDR date pattern, such as
+// `d MMMM y, HH:mm`, or one of the styles `short`, `medium`, `long` and
+// `full`. An empty pattern uses the `medium` style. The pattern fields
+// are:
+//
+//   y, yy      year, or year of the century
+//   M ... MMMM month number, padded number, short name, full name
+//   d, dd      day of the month
+//   E ... EEEE short and full name of the weekday
+//   H, HH      hour (0-23)
+//   h, hh      hour (1-12)
+//   m, mm      minute
+//   s, ss      second
+//   S ... SSS  fraction of the second
+//   a          AM or PM marker
+//   z, Z       time zone abbreviation and offset
+//
+// Text within single quotes is written as is, and `''` writes a
+// single quote.
+//
+func FormatDate(locale string, value time.Time, pattern string) string {
+	data := getLocaleData(locale)
+
+	if pattern == "" {
+		pattern = DateStyleMedium
+	}
+
+	style, exists := data.dateStyles[pattern]
+	if exists {
+		pattern = style
+	}
+
+	builder := strings.Builder{}
+	runes := []rune(pattern)
+	for index := 0; index < len(runes); index++ {
+		current := runes[index]
+
+		if current == '\'' {
+			// quoted text, or an escaped quote
+			if index+1 < len(runes) && runes[index+1] == '\'' {
+				builder.WriteRune('\'')
+				index++
+				continue
+			}
+
+			for index++; index < len(runes); index++ {
+				if runes[index] != '\'' {
+					builder.WriteRune(runes[index])
+					continue
+				}
+
+				if index+1 < len(runes) && runes[index+1] == '\'' {
+					builder.WriteRune('\'')
+					index++
+					continue
+				}
+				break
+			}
+			continue
+		}
+
+		if !(current >= 'a' && current <= 'z') && !(current >= 'A' && current <= 'Z') {
+			builder.WriteRune(current)
+			continue
+		}
+
+		count := 1
+		for index+1 < len(runes) && runes[index+1] == current {
+			count++
+			index++
+		}
+
+		builder.WriteString(formatDateField(data, value, current, count))
+	}
+
+	return builder.String()
+}
+
+//
+// Format a time relative to now in the locale, such as `in 3 days` or
+// `2 hours ago` in English. The largest unit that fits is used, from
+// seconds up to years, and the amount is rounded down.
+//
+func FormatRelativeTime(locale string, value time.Time, now time.Time) string {
+	relative := getLocaleData(locale).relative
+
+	difference := value.Sub(now)
+	future := difference >= 0
+	if !future {
+		difference = -difference
+	}
+
+	unit := "second"
+	amount := int64(difference / time.Second)
+	days := int64(difference / (24 * time.Hour))
+
+	switch {
+	case difference >= 365*24*time.Hour:
+		unit, amount = "year", days/365
+
+	case difference >= 30*24*time.Hour:
+		unit, amount = "month", days/30
+
+	case difference >= 7*24*time.Hour:
+		unit, amount = "week", days/7
+
+	case difference >= 24*time.Hour:
+		unit, amount = "day", days
+
+	case difference >= time.Hour:
+		unit, amount = "hour", int64(difference/time.Hour)
+
+	case difference >= time.Minute:
+		unit, amount = "minute", int64(difference/time.Minute)
+	}
+
+	if amount == 0 {
+		return relative.now
+	}
+
+	names := relative.units[unit]
+	name := names[len(names)-1]
+	category := PluralCategory(locale, amount)
+	for index, candidate := range relative.categories {
+		if candidate == category && index < len(names) {
+			name = names[index]
+			break
+		}
+	}
+
+	phrase := relative.past
+	if future {
+		phrase = relative.future
+	}
+
+	number, _ := FormatNumber(locale, amount, 0)
+	phrase = strings.Replace(phrase, "%s", name, 1)
+	return strings.Replace(phrase, "{0}", number, 1)
+}
+
+//
+// Format a single field of a date pattern.
+//
+func formatDateField(data *localeData, value time.Time, field rune, count int) string {
+	switch field {
+	case 'y':
+		if count == 2 {
+			return padNumber(value.Year()%100, 2)
+		}
+		return padNumber(value.Year(), count)
+
+	case 'M', 'L':
+		switch {
+		case count >= 4:
+			return data.months[value.Month()-1]
+
+		case count == 3:
+			return data.shortMonths[value.Month()-1]
+		}
+		return padNumber(int(value.Month()), count)
+
+	case 'd':
+		return padNumber(value.Day(), count)
+
+	case 'E':
+		if count >= 4 {
+			return data.days[value.Weekday()]
+		}
+		return data.shortDays[value.Weekday()]
+
+	case 'H':
+		return padNumber(value.Hour(), count)
+
+	case 'h':
+		hour := value.Hour() % 12
+		if hour == 0 {
+			hour = 12
+		}
+		return padNumber(hour, count)
+
+	case 'm':
+		return padNumber(value.Minute(), count)
+
+	case 's':
+		return padNumber(value.Second(), count)
+
+	case 'S':
+		fraction := padNumber(value.Nanosecond(), 9)
+		if count > 9 {
+			count = 9
+		}
+		return fraction[:count]
+
+	case 'a':
+		if value.Hour() < 12 {
+			return data.am
+		}
+		return data.pm
+
+	case 'z':
+		return value.Format("MST")
+
+	case 'Z':
+		return value.Format("-0700")
+	}
+
+	return strings.Repeat(string(field), count)
+}
+
+func padNumber(value int, width int) string {
+	s := strconv.Itoa(value)
+	if len(s) < width {
+		s = strings.Repeat("0", width-len(s)) + s
+	}
+
+	return s
+}
+
+//
+// Convert a number, or a numeric string, to its decimal digits with
+// exactly the given number of fraction digits, after multiplying it.
+//
+func decimalString(value interface{}, multiplier int, fraction int) (string, error) {
+	reflected := reflect.ValueOf(value)
+	switch reflected.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		integer := reflected.Int()
+		if integer <= math.MaxInt64/100 && integer >= math.MinInt64/100 {
+			s := strconv.FormatInt(integer*int64(multiplier), 10)
+			if fraction > 0 {
+				s += "." + strings.Repeat("0", fraction)
+			}
+			return s, nil
+		}
+		return strconv.FormatFloat(float64(integer)*float64(multiplier), 'f', fraction, 64), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatFloat(float64(reflected.Uint())*float64(multiplier), 'f', fraction, 64), nil
+
+	case reflect.Float32, reflect.Float64:
+		float := reflected.Float()
+		if math.IsNaN(float) || math.IsInf(float, 0) {
+			return "", errors.New("Number is not finite")
+		}
+		return strconv.FormatFloat(float*float64(multiplier), 'f', fraction, 64), nil
+
+	case reflect.String:
+		float, err := strconv.ParseFloat(strings.TrimSpace(reflected.String()), 64)
+		if err == nil && !math.IsNaN(float) && !math.IsInf(float, 0) {
+			return strconv.FormatFloat(float*float64(multiplier), 'f', fraction, 64), nil
+		}
+	}
+
+	return "", errors.New("Value is not a number: " + berry.ConvertToString(value))
+}
+
+//
+// Write the decimal digits with the separators of the locale, removing
+// trailing zeros of the fraction beyond the minimum.
+//
+func localizeDecimal(data *localeData, digits string, minFraction int) string {
+	negative := strings.HasPrefix(digits, "-")
+	digits = strings.TrimPrefix(digits, "-")
+
+	integer, fraction, _ := strings.Cut(digits, ".")
+	for len(fraction) > minFraction && strings.HasSuffix(fraction, "0") {
+		fraction = fraction[:len(fraction)-1]
+	}
+
+	// no negative zero
+	if negative && strings.Trim(integer+fraction, "0") == "" {
+		negative = false
+	}
+
+	builder := strings.Builder{}
+	if negative {
+		builder.WriteString("-")
+	}
+
+	builder.WriteString(groupDigits(data, integer))
+	if fraction != "" {
+		builder.WriteString(data.decimal)
+		builder.WriteString(fraction)
+	}
+
+	return builder.String()
+}
+
+//
+// Insert the grouping separators of the locale into the integer digits.
+//
+func groupDigits(data *localeData, integer string) string {
+	if len(integer) < 3+data.minimumGrouping {
+		return integer
+	}
+
+	// the last group has three digits, the others may be smaller
+	groups := []string{integer[len(integer)-3:]}
+	rest := integer[:len(integer)-3]
+
+	size := 3
+	if data.secondaryGrouping > 0 {
+		size = data.secondaryGrouping
+	}
+
+	for len(rest) > size {
+		groups = append(groups, rest[len(rest)-size:])
+		rest = rest[:len(rest)-size]
+	}
+
+	if rest != "" {
+		groups = append(groups, rest)
+	}
+
+	// reverse
+	for i, j := 0, len(groups)-1; i < j; i, j = i+1, j-1 {
+		groups[i], groups[j] = groups[j], groups[i]
+	}
+
+	return strings.Join(groups, data.group)
+}
+
+//
+// Return the time zone of the current merge: the time zone of the merge
+// options, else the `timeZone` value of the model. If neither is set,
+// `nil` is returned and times keep their own time zone.
+//
+func (evaluator *Evaluator) TimeZone() (*time.Location, error) {
+	if evaluator.options != nil && evaluator.options.TimeZone != nil {
+		return evaluator.options.TimeZone, nil
+	}
+
+	if evaluator.model == nil {
+		return nil, nil
+	}
+
+	value, exists := evaluator.model.Get(TimeZoneModelKey)
+	if !exists || value == nil {
+		return nil, nil
+	}
+
+	switch typed := value.(type) {
+	case *time.Location:
+		return typed, nil
+
+	case string:
+		if typed == "" {
+			return nil, nil
+		}
+		return time.LoadLocation(typed)
+	}
+
+	return nil, errors.New("Time zone must be a location or a name")
+}
+
+//
+// Convert the value to a time in the time zone of the merge.
+//
+func (evaluator *Evaluator) toLocalTime(value interface{}) (time.Time, error) {
+	converted, ok := convertToTime(value)
+	if !ok {
+		return time.Time{}, errors.New("Value is not a time: " + berry.ConvertToString(value))
+	}
+
+	location, err := evaluator.TimeZone()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if location != nil {
+		converted = converted.In(location)
+	}
+
+	return converted, nil
+}
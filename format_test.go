@@ -0,0 +1,250 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		locale   string
+		value    interface{}
+		decimals int
+		expected string
+	}{
+		{"en", 1234.5, -1, "1,234.5"},
+		{"en", 1234567, -1, "1,234,567"},
+		{"en", 0.12345, -1, "0.123"},
+		{"en", 1234.5, 2, "1,234.50"},
+		{"en", -1234.6, 0, "-1,235"},
+		{"en", -0.0001, 2, "0.00"},
+		{"en", "42.10", -1, "42.1"},
+		{"en", int64(9007199254740993), -1, "9,007,199,254,740,993"},
+		{"de", 1234.5, -1, "1.234,5"},
+		{"de-CH", 1234.5, -1, "1’234.5"},
+		{"fr", 1234567.891, 2, "1\u202f234\u202f567,89"},
+		{"ru", 1234.5, -1, "1\u00a0234,5"},
+		{"es", 1234, -1, "1234"},
+		{"es", 12345, -1, "12.345"},
+		{"pl", 1234, -1, "1234"},
+		{"en-IN", 1234567, -1, "12,34,567"},
+		{"ja", 1234.5, -1, "1,234.5"},
+		{"xx", 1234.5, -1, "1,234.5"},
+	}
+
+	for _, test := range tests {
+		result, err := FormatNumber(test.locale, test.value, test.decimals)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, result, "%s %v", test.locale, test.value)
+	}
+
+	_, err := FormatNumber("en", "abc", -1)
+	assert.Error(t, err)
+
+	_, err = FormatNumber("en", nil, -1)
+	assert.Error(t, err)
+}
+
+func TestFormatPercent(t *testing.T) {
+	result, _ := FormatPercent("en", 0.256, -1)
+	assert.Equal(t, "26%", result)
+
+	result, _ = FormatPercent("en", 0.256, 1)
+	assert.Equal(t, "25.6%", result)
+
+	result, _ = FormatPercent("de", 0.25, -1)
+	assert.Equal(t, "25\u00a0%", result)
+
+	result, _ = FormatPercent("fr", -0.5, -1)
+	assert.Equal(t, "-50\u202f%", result)
+
+	result, _ = FormatPercent("en", 12, -1)
+	assert.Equal(t, "1,200%", result)
+}
+
+func TestFormatCurrency(t *testing.T) {
+	tests := []struct {
+		locale   string
+		value    interface{}
+		code     string
+		expected string
+	}{
+		{"en", 1234.5, "USD", "$1,234.50"},
+		{"en", 1234.5, "EUR", "€1,234.50"},
+		{"en", -5, "GBP", "-£5.00"},
+		{"en", 1234.6, "JPY", "¥1,235"},
+		{"en", 10, "CHF", "CHF\u00a010.00"},
+		{"en", 10, "eur", "€10.00"},
+		{"en-GB", 10, "USD", "US$10.00"},
+		{"de", 1234.5, "EUR", "1.234,50\u00a0€"},
+		{"de", -1234.5, "USD", "-1.234,50\u00a0$"},
+		{"de-CH", 1234.5, "CHF", "CHF\u00a01’234.50"},
+		{"fr", 1234.5, "EUR", "1\u202f234,50\u00a0€"},
+		{"pt-BR", 1234.5, "BRL", "R$\u00a01.234,50"},
+		{"pt-PT", 1234.5, "EUR", "1234,50\u00a0€"},
+		{"ru", 1234.5, "RUB", "1\u00a0234,50\u00a0₽"},
+		{"ja", 1234, "JPY", "￥1,234"},
+		{"en", 1.2346, "KWD", "KWD\u00a01.235"},
+	}
+
+	for _, test := range tests {
+		result, err := FormatCurrency(test.locale, test.value, test.code)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, result, "%s %v %s", test.locale, test.value, test.code)
+	}
+
+	_, err := FormatCurrency("en", 1, "EURO")
+	assert.Error(t, err)
+
+	_, err = FormatCurrency("en", 1, "E1R")
+	assert.Error(t, err)
+}
+
+func TestFormatDate(t *testing.T) {
+	date := time.Date(2022, time.September, 21, 14, 5, 9, 120000000, time.UTC)
+
+	tests := []struct {
+		locale   string
+		pattern  string
+		expected string
+	}{
+		{"en", "", "Sep 21, 2022"},
+		{"en", "short", "9/21/22"},
+		{"en", "full", "Wednesday, September 21, 2022"},
+		{"en", "h:mm a", "2:05 PM"},
+		{"en", "yyyy-MM-dd'T'HH:mm:ss.SSS Z", "2022-09-21T14:05:09.120 +0000"},
+		{"en", "'o''clock' hh 'at' z", "o'clock 02 at UTC"},
+		{"en-GB", "long", "21 September 2022"},
+		{"de", "full", "Mittwoch, 21. September 2022"},
+		{"de", "short", "21.09.22"},
+		{"fr", "long", "21 septembre 2022"},
+		{"es", "long", "21 de septiembre de 2022"},
+		{"pt-BR", "medium", "21 de set. de 2022"},
+		{"ru", "long", "21 сентября 2022 г."},
+		{"pl", "EEEE, d MMMM", "środa, 21 września"},
+		{"ja", "full", "2022年9月21日水曜日"},
+		{"zh", "ah:mm", "下午2:05"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, FormatDate(test.locale, date, test.pattern), "%s %s", test.locale, test.pattern)
+	}
+}
+
+func TestFormatRelativeTime(t *testing.T) {
+	now := time.Date(2022, time.September, 21, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		locale   string
+		offset   time.Duration
+		expected string
+	}{
+		{"en", 0, "now"},
+		{"en", 30 * time.Second, "in 30 seconds"},
+		{"en", -time.Minute, "1 minute ago"},
+		{"en", -90 * time.Minute, "1 hour ago"},
+		{"en", 3 * 24 * time.Hour, "in 3 days"},
+		{"en", -15 * 24 * time.Hour, "2 weeks ago"},
+		{"en", 60 * 24 * time.Hour, "in 2 months"},
+		{"en", -800 * 24 * time.Hour, "2 years ago"},
+		{"de", -2 * time.Hour, "vor 2 Stunden"},
+		{"de", 24 * time.Hour, "in 1 Tag"},
+		{"fr", -24 * time.Hour, "il y a 1 jour"},
+		{"es", 5 * time.Minute, "dentro de 5 minutos"},
+		{"pt-BR", -3 * 24 * time.Hour, "há 3 dias"},
+		{"ru", -2 * time.Hour, "2 часа назад"},
+		{"ru", 5 * 24 * time.Hour, "через 5 дней"},
+		{"ru", -21 * time.Minute, "21 минуту назад"},
+		{"pl", 2 * 365 * 24 * time.Hour, "za 2 lata"},
+		{"ja", -3 * time.Hour, "3 時間前"},
+		{"zh", 2 * 24 * time.Hour, "2天后"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, FormatRelativeTime(test.locale, now.Add(test.offset), now), "%s %v", test.locale, test.offset)
+	}
+}
+
+func TestFormatTags(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "")
+	processor.Import(I18nLibrary(), "")
+	processor.SetStrictMode(true)
+
+	model := NewModel()
+	model.Put("total", 1234.5)
+	model.Put("share", 0.25)
+	model.Put("placed", time.Date(2022, time.September, 21, 23, 30, 0, 0, time.UTC))
+	model.Put("now", time.Date(2022, time.September, 22, 1, 30, 0, 0, time.UTC))
+
+	template := `<p><formatNumber value="total" />|<formatNumber value="total" decimals="2" />|<formatNumber value="share" style="percent" />|` +
+		`<formatCurrency value="total" code="EUR" />|<formatDate value="placed" />|<formatDate value="placed" pattern="d MMM HH:mm" />|` +
+		`<formatRelativeTime value="placed" now="now" /></p>`
+
+	html, err := processor.MergeHtml(template, model)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>1,234.5|1,234.50|25%|€1,234.50|Sep 21, 2022|21 Sep 23:30|2 hours ago</p>", html)
+
+	// locale and time zone from the model
+	model.Put("locale", "de")
+	model.Put("timeZone", "Europe/Berlin")
+	html, err = processor.MergeHtml(template, model)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>1.234,5|1.234,50|25\u00a0%|1.234,50\u00a0€|22.09.2022|22 Sept. 01:30|vor 2 Stunden</p>", html)
+
+	// merge options win over the model
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	html, err = processor.MergeHtmlWithOptions(`<p><formatDate value="placed" pattern="HH:mm" /></p>`, model, &MergeOptions{TimeZone: tokyo})
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>08:30</p>", html)
+
+	_, err = processor.MergeHtml(`<p><formatNumber value="total" style="fancy" /></p>`, model)
+	assert.Error(t, err)
+
+	_, err = processor.MergeHtml(`<p><formatCurrency value="total" code="EURO" /></p>`, model)
+	assert.Error(t, err)
+
+	_, err = processor.MergeHtml(`<p><formatDate value="total" /></p>`, model)
+	assert.Error(t, err)
+
+	model.Put("timeZone", "Nowhere/Nothing")
+	_, err = processor.MergeHtml(`<p><formatDate value="placed" /></p>`, model)
+	assert.Error(t, err)
+}
+
+func TestFormatFunctions(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "")
+	processor.Import(I18nLibrary(), "")
+	processor.SetStrictMode(true)
+
+	model := NewModel()
+	model.Put("total", 1234.5)
+	model.Put("placed", time.Date(2022, time.September, 21, 23, 30, 0, 0, time.UTC))
+	model.Put("now", time.Date(2022, time.September, 24, 23, 30, 0, 0, time.UTC))
+
+	template := `<p><get var='formatNumber(total)' />|<get var='formatNumber(total, 0)' />|<get var='formatPercent(0.5)' />|` +
+		`<get var='formatCurrency(total, "USD")' />|<get var='formatDate(placed, "long")' />|<get var='formatRelativeTime(placed, now)' /></p>`
+
+	html, err := processor.MergeHtmlWithOptions(template, model, &MergeOptions{Locale: "fr"})
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>1\u202f234,5|1\u202f234|50\u202f%|1\u202f234,50\u00a0$US|21 septembre 2022|il y a 3 jours</p>", html)
+
+	for _, expr := range []string{`formatNumber()`, `formatNumber(total, "2")`, `formatCurrency(total)`, `formatCurrency(total, 1)`, `formatDate(total)`, `formatRelativeTime(placed, 1.5)`} {
+		_, err = processor.MergeHtml(`<p><get var='`+expr+`' /></p>`, model)
+		assert.Error(t, err, expr)
+	}
+}
@@ -0,0 +1,406 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sangupta/berry"
+)
+
+//
+// Return the length of a string (in runes), slice or map.
+//
+//   len(items)
+//
+func LenFunction(evaluator *Evaluator, args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.New("len() requires exactly one argument")
+	}
+
+	value := args[0]
+	if value == nil {
+		return 0, nil
+	}
+
+	if s, ok := value.(string); ok {
+		return utf8.RuneCountInString(s), nil
+	}
+
+	reflected := reflect.ValueOf(value)
+	switch reflected.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return reflected.Len(), nil
+	}
+
+	return nil, errors.New("len() requires a string, slice or map")
+}
+
+//
+// Convert the single argument to upper case.
+//
+//   upper(name)
+//
+func UpperFunction(evaluator *Evaluator, args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.New("upper() requires exactly one argument")
+	}
+
+	return UpperFilter(args[0])
+}
+
+//
+// Convert the single argument to lower case.
+//
+//   lower(name)
+//
+func LowerFunction(evaluator *Evaluator, args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.New("lower() requires exactly one argument")
+	}
+
+	return LowerFilter(args[0])
+}
+
+//
+// Remove leading and trailing white space from the single argument.
+//
+//   trim(name)
+//
+func TrimFunction(evaluator *Evaluator, args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.New("trim() requires exactly one argument")
+	}
+
+	return TrimFilter(args[0])
+}
+
+//
+// Filter that converts the value to upper case.
+//
+func UpperFilter(value interface{}) (interface{}, error) {
+	return strings.ToUpper(berry.ConvertToString(value)), nil
+}
+
+//
+// Filter that converts the value to lower case.
+//
+func LowerFilter(value interface{}) (interface{}, error) {
+	return strings.ToLower(berry.ConvertToString(value)), nil
+}
+
+//
+// Filter that removes leading and trailing white space.
+//
+func TrimFilter(value interface{}) (interface{}, error) {
+	return strings.TrimSpace(berry.ConvertToString(value)), nil
+}
+
+//
+// Filter that converts the first letter of the value to upper case.
+//
+func CapitalizeFilter(value interface{}) (interface{}, error) {
+	s := berry.ConvertToString(value)
+	if s == "" {
+		return s, nil
+	}
+
+	first, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(first)) + s[size:], nil
+}
+
+//
+// Translate a message of the message bundle into the locale of the
+// merge. The optional second argument is an object with the values of
+// the named placeholders, where `count` selects the plural form. The
+// values are escaped as in the `msg` tag.
+//
+//   t("checkout.title")
+//   t("cart.items", {"count": cart.size, "name": user.name})
+//
+func TranslateFunction(evaluator *Evaluator, args ...interface{}) (interface{}, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, errors.New("t() requires a message key and optional arguments")
+	}
+
+	key, ok := args[0].(string)
+	if !ok {
+		return nil, errors.New("t() requires the message key to be a string")
+	}
+
+	var values map[string]interface{}
+	if len(args) == 2 && args[1] != nil {
+		arguments, ok := args[1].(map[string]interface{})
+		if !ok {
+			return nil, errors.New("t() requires the arguments to be an object")
+		}
+
+		values = make(map[string]interface{}, len(arguments))
+		for name, item := range arguments {
+			values[name] = escapeMessageArgument(item)
+		}
+	}
+
+	return evaluator.processor.Translate(evaluator.Locale(), key, values)
+}
+
+//
+// Format a number for the locale of the merge, with an optional fixed
+// number of fraction digits.
+//
+//   formatNumber(order.weight)
+//   formatNumber(order.weight, 2)
+//
+func FormatNumberFunction(evaluator *Evaluator, args ...interface{}) (interface{}, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, errors.New("formatNumber() requires a number and optional fraction digits")
+	}
+
+	decimals, err := optionalDigits("formatNumber", args)
+	if err != nil {
+		return nil, err
+	}
+
+	return FormatNumber(evaluator.Locale(), args[0], decimals)
+}
+
+//
+// Format a fraction as a percentage for the locale of the merge, with
+// optional fraction digits.
+//
+//   formatPercent(order.discount)
+//
+func FormatPercentFunction(evaluator *Evaluator, args ...interface{}) (interface{}, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, errors.New("formatPercent() requires a number and optional fraction digits")
+	}
+
+	decimals, err := optionalDigits("formatPercent", args)
+	if err != nil {
+		return nil, err
+	}
+
+	return FormatPercent(evaluator.Locale(), args[0], decimals)
+}
+
+//
+// Format an amount of money in the given currency for the locale of
+// the merge.
+//
+//   formatCurrency(cart.total, "EUR")
+//
+func FormatCurrencyFunction(evaluator *Evaluator, args ...interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, errors.New("formatCurrency() requires an amount and a currency code")
+	}
+
+	code, ok := args[1].(string)
+	if !ok {
+		return nil, errors.New("formatCurrency() requires the currency code to be a string")
+	}
+
+	return FormatCurrency(evaluator.Locale(), args[0], code)
+}
+
+//
+// Format a date for the locale and in the time zone of the merge, with
+// an optional pattern or style.
+//
+//   formatDate(order.placed)
+//   formatDate(order.placed, "d MMM y")
+//
+func FormatDateFunction(evaluator *Evaluator, args ...interface{}) (interface{}, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, errors.New("formatDate() requires a date and an optional pattern")
+	}
+
+	date, err := evaluator.toLocalTime(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	pattern := ""
+	if len(args) == 2 {
+		pattern = berry.ConvertToString(args[1])
+	}
+
+	return FormatDate(evaluator.Locale(), date, pattern), nil
+}
+
+//
+// Format a date relative to now, or to the optional second date, for
+// the locale of the merge.
+//
+//   formatRelativeTime(comment.posted)
+//
+func FormatRelativeTimeFunction(evaluator *Evaluator, args ...interface{}) (interface{}, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, errors.New("formatRelativeTime() requires a date and an optional date to compare with")
+	}
+
+	date, err := evaluator.toLocalTime(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if len(args) == 2 {
+		now, err = evaluator.toLocalTime(args[1])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return FormatRelativeTime(evaluator.Locale(), date, now), nil
+}
+
+//
+// Return the optional number of fraction digits given as the second
+// argument, or -1.
+//
+func optionalDigits(name string, args []interface{}) (int, error) {
+	if len(args) < 2 {
+		return -1, nil
+	}
+
+	digits, ok := args[1].(int)
+	if !ok {
+		return 0, errors.New(name + "() requires the fraction digits to be an integer")
+	}
+
+	return digits, nil
+}
+
+//
+// Build a URL from a path and optional parameters. See `BuildURL`.
+//
+//   url("/search", {"q": query})
+//
+func URLFunction(evaluator *Evaluator, args ...interface{}) (interface{}, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, errors.New("url() requires a path and optional parameters")
+	}
+
+	path, ok := args[0].(string)
+	if !ok {
+		return nil, errors.New("url() requires the path to be a string")
+	}
+
+	params, err := optionalParams("url", args)
+	if err != nil {
+		return nil, err
+	}
+
+	return evaluator.processor.BuildURL(path, params)
+}
+
+//
+// Build the URL of a named route of the processor, with optional
+// parameters. See `BuildRoute`.
+//
+//   route("product", {"id": product.id})
+//
+func RouteFunction(evaluator *Evaluator, args ...interface{}) (interface{}, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, errors.New("route() requires a route name and optional parameters")
+	}
+
+	name, ok := args[0].(string)
+	if !ok {
+		return nil, errors.New("route() requires the route name to be a string")
+	}
+
+	params, err := optionalParams("route", args)
+	if err != nil {
+		return nil, err
+	}
+
+	return evaluator.processor.BuildRoute(name, params)
+}
+
+//
+// Return the optional object of parameters given as the second
+// argument.
+//
+func optionalParams(name string, args []interface{}) (map[string]interface{}, error) {
+	if len(args) < 2 || args[1] == nil {
+		return nil, nil
+	}
+
+	params, ok := args[1].(map[string]interface{})
+	if !ok {
+		return nil, errors.New(name + "() requires the parameters to be an object")
+	}
+
+	return params, nil
+}
+
+//
+// Return the fingerprinted URL of an asset. See `AssetURL`.
+//
+//   asset("fonts/inter.woff2")
+//
+func AssetFunction(evaluator *Evaluator, args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.New("asset() requires exactly one argument")
+	}
+
+	name, ok := args[0].(string)
+	if !ok {
+		return nil, errors.New("asset() requires the asset name to be a string")
+	}
+
+	return evaluator.processor.AssetURL(name)
+}
+
+//
+// Return the Subresource Integrity hash of an asset, or an empty
+// string if it is not known.
+//
+//   assetIntegrity("app.js")
+//
+func AssetIntegrityFunction(evaluator *Evaluator, args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.New("assetIntegrity() requires exactly one argument")
+	}
+
+	name, ok := args[0].(string)
+	if !ok {
+		return nil, errors.New("assetIntegrity() requires the asset name to be a string")
+	}
+
+	_, asset, err := evaluator.processor.resolveAsset(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return asset.Integrity, nil
+}
+
+//
+// Encode the single argument as JSON that is safe to embed in a
+// `<script>` element. See `EncodeJSON`.
+//
+//   json(cart)
+//
+func JSONFunction(evaluator *Evaluator, args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.New("json() requires exactly one argument")
+	}
+
+	return EncodeJSON(args[0])
+}
@@ -0,0 +1,87 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStandardFunctions(t *testing.T) {
+	value, err := LenFunction(nil, "héllo")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, value)
+
+	value, _ = LenFunction(nil, []int{1, 2})
+	assert.Equal(t, 2, value)
+
+	value, _ = LenFunction(nil, map[string]int{"a": 1})
+	assert.Equal(t, 1, value)
+
+	value, _ = LenFunction(nil, nil)
+	assert.Equal(t, 0, value)
+
+	_, err = LenFunction(nil, 1)
+	assert.Error(t, err)
+
+	_, err = LenFunction(nil)
+	assert.Error(t, err)
+
+	value, _ = UpperFunction(nil, "abc")
+	assert.Equal(t, "ABC", value)
+
+	value, _ = LowerFunction(nil, "ABC")
+	assert.Equal(t, "abc", value)
+
+	value, _ = TrimFunction(nil, "  abc ")
+	assert.Equal(t, "abc", value)
+
+	_, err = UpperFunction(nil)
+	assert.Error(t, err)
+	_, err = LowerFunction(nil)
+	assert.Error(t, err)
+	_, err = TrimFunction(nil)
+	assert.Error(t, err)
+}
+
+func TestStandardFilters(t *testing.T) {
+	value, _ := CapitalizeFilter("élan")
+	assert.Equal(t, "Élan", value)
+
+	value, _ = CapitalizeFilter("")
+	assert.Equal(t, "", value)
+
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "")
+
+	value, err := processor.ApplyFilters(" hello ", "trim | capitalize")
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello", value)
+
+	_, err = processor.ApplyFilters("x", "unknown")
+	assert.Error(t, err)
+
+	_, err = processor.AddFilter("upper", UpperFilter)
+	assert.Error(t, err)
+	_, err = processor.AddFilter("", UpperFilter)
+	assert.Error(t, err)
+	_, err = processor.AddFilter("x", nil)
+	assert.Error(t, err)
+
+	_, err = processor.AddFunction("len", LenFunction)
+	assert.Error(t, err)
+	_, err = processor.AddFunction("", LenFunction)
+	assert.Error(t, err)
+	_, err = processor.AddFunction("x", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,143 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+//
+// Set a global value that is visible to every merge of this processor,
+// such as the site name or the build version. The model of a merge can
+// override global values with its own values of the same key. Globals
+// can be changed while merges are running; a merge sees the globals as
+// they were when it started.
+//
+func (pageProcessor *HtmlPageProcessor) SetGlobal(key string, value interface{}) {
+	pageProcessor._globalsMutex.Lock()
+	defer pageProcessor._globalsMutex.Unlock()
+
+	globals := pageProcessor.copyGlobals()
+	globals.Put(key, value)
+	globals.Freeze()
+	pageProcessor._globals = globals
+}
+
+//
+// Remove a global value.
+//
+func (pageProcessor *HtmlPageProcessor) RemoveGlobal(key string) {
+	pageProcessor._globalsMutex.Lock()
+	defer pageProcessor._globalsMutex.Unlock()
+
+	globals := pageProcessor.copyGlobals()
+	globals.Remove(key)
+	globals.Freeze()
+	pageProcessor._globals = globals
+}
+
+//
+// Replace all global values with the values of the given model,
+// including those of its parents. A `nil` model removes all globals.
+//
+func (pageProcessor *HtmlPageProcessor) SetGlobals(model *Model) {
+	globals := NewModel()
+	if model != nil {
+		for key, value := range model.values() {
+			globals._map[key] = value
+		}
+	}
+	globals.Freeze()
+
+	pageProcessor._globalsMutex.Lock()
+	defer pageProcessor._globalsMutex.Unlock()
+
+	pageProcessor._globals = globals
+}
+
+//
+// Get a global value.
+//
+func (pageProcessor *HtmlPageProcessor) GetGlobal(key string) (interface{}, bool) {
+	return pageProcessor.Globals().Get(key)
+}
+
+//
+// Return the global values as a frozen model. The returned model
+// does not change when globals are set later.
+//
+func (pageProcessor *HtmlPageProcessor) Globals() *Model {
+	pageProcessor._globalsMutex.RLock()
+	defer pageProcessor._globalsMutex.RUnlock()
+
+	if pageProcessor._globals == nil {
+		globals := NewModel()
+		globals.Freeze()
+		return globals
+	}
+
+	return pageProcessor._globals
+}
+
+//
+// Return a copy of the globals that can be changed. Globals are never
+// changed in place, so that merges can read them without locking.
+//
+func (pageProcessor *HtmlPageProcessor) copyGlobals() *Model {
+	globals := NewModel()
+	if pageProcessor._globals != nil {
+		for key, value := range pageProcessor._globals._map {
+			globals._map[key] = value
+		}
+	}
+
+	return globals
+}
+
+//
+// Return the model that a merge evaluates against: the given model with
+// the globals as the last parent, which computes lazy values once for
+// the merge of the evaluator. The models of the returned chain share
+// their values with the given models, so that values written during the
+// merge are visible in the given model as before, while the given models
+// themselves are not changed.
+//
+func (pageProcessor *HtmlPageProcessor) scopeModel(model *Model, evaluator *Evaluator) *Model {
+	if model == nil {
+		model = NewModel()
+	}
+
+	globals := pageProcessor.Globals()
+	if globals.Size() == 0 {
+		globals = nil
+	}
+
+	return model.withRoot(globals, evaluator)
+}
+
+//
+// Return a copy of the chain of models that shares the values of the
+// models, with the given model as the parent of the last model. Writes
+// to a copy are seen by the model it was copied from.
+//
+func (model *Model) withRoot(root *Model, evaluator *Evaluator) *Model {
+	var parent *Model
+	if model._parent != nil {
+		parent = model._parent.withRoot(root, evaluator)
+	} else {
+		parent = root
+	}
+
+	return &Model{
+		_map:       model._map,
+		_parent:    parent,
+		_frozen:    model._frozen,
+		_origin:    model,
+		_evaluator: evaluator,
+	}
+}
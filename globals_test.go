@@ -0,0 +1,148 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessorGlobals(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "")
+
+	assert.Equal(t, 0, processor.Globals().Size())
+
+	processor.SetGlobal("site", "Snowmark")
+	processor.SetGlobal("version", "1.0")
+
+	value, exists := processor.GetGlobal("site")
+	assert.True(t, exists)
+	assert.Equal(t, "Snowmark", value)
+	assert.True(t, processor.Globals().IsFrozen())
+
+	// globals are visible, and can be overridden per merge
+	html, err := processor.MergeHtml("<p><get var='site' />/<get var='version' /></p>", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>Snowmark/1.0</p>", html)
+
+	model := NewModel()
+	model.Put("version", "2.0")
+	html, _ = processor.MergeHtml("<p><get var='site' />/<get var='version' /></p>", model)
+	assert.Equal(t, "<p>Snowmark/2.0</p>", html)
+
+	// writes during the merge go to the model, not to the globals
+	html, _ = processor.MergeHtml("<p><set var='site' value='Other' /><get var='site' /></p>", model)
+	assert.Equal(t, "<p>Other</p>", html)
+	assert.Equal(t, "Other", model.GetString("site", ""))
+	assert.Equal(t, 2, model.Size())
+
+	value, _ = processor.GetGlobal("site")
+	assert.Equal(t, "Snowmark", value)
+
+	// snapshots do not change
+	snapshot := processor.Globals()
+	processor.RemoveGlobal("version")
+	assert.Equal(t, "1.0", snapshot.GetString("version", ""))
+	_, exists = processor.GetGlobal("version")
+	assert.False(t, exists)
+
+	// replace all globals, including those of parents
+	base := NewModel()
+	base.Put("a", "1")
+	globals := NewModel()
+	globals.Overlay(base)
+	globals.Put("b", "2")
+	processor.SetGlobals(globals)
+
+	html, _ = processor.MergeHtml("<p><get var='a + b' /></p>", nil)
+	assert.Equal(t, "<p>12</p>", html)
+
+	processor.SetGlobals(nil)
+	assert.Equal(t, 0, processor.Globals().Size())
+}
+
+func TestProcessorGlobalsWithOverlay(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "")
+	processor.SetGlobal("site", "Snowmark")
+	processor.SetGlobal("title", "Global")
+
+	base := NewModel()
+	base.Put("title", "Base")
+	base.Freeze()
+
+	page := NewModel()
+	page.Overlay(base)
+
+	html, err := processor.MergeHtml("<p><get var='site' />/<get var='title' /></p>", page)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>Snowmark/Base</p>", html)
+
+	// the models of the merge are left as they are
+	assert.Nil(t, base.Parent())
+	assert.Same(t, base, page.Parent())
+}
+
+func TestProcessorGlobalsWrites(t *testing.T) {
+	plain := NewHtmlPageProcessor()
+	plain.Import(StandardLibrary(), "")
+
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "")
+	processor.SetGlobal("site", "Snowmark")
+
+	base := NewModel()
+	base.Put("title", "Home")
+	page := NewModel()
+	page.Overlay(base)
+
+	// the flattened values of the page are built by the first merge
+	html, _ := plain.MergeHtml("<p><get var='title' /><get var='x' /></p>", page)
+	assert.Equal(t, "<p>Home</p>", html)
+
+	// and are rebuilt after a write made during a merge with globals
+	processor.MergeHtml("<set var='x' value='y' />", page)
+	assert.Equal(t, "y", page.GetString("x", ""))
+
+	html, _ = plain.MergeHtml("<p><get var='title' /><get var='x' /></p>", page)
+	assert.Equal(t, "<p>Homey</p>", html)
+}
+
+func TestProcessorGlobalsConcurrency(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "")
+	processor.SetGlobal("site", "Snowmark")
+
+	template, _ := ParseTemplate("page.html", "<p><get var='site' />:<get var='id' /></p>")
+
+	var wait sync.WaitGroup
+	for index := 0; index < 20; index++ {
+		wait.Add(1)
+		go func(index int) {
+			defer wait.Done()
+
+			model := NewModel()
+			model.Put("id", index)
+			html, err := processor.MergeTemplate(template, model)
+			assert.NoError(t, err)
+			assert.Equal(t, "<p>Snowmark:"+strconv.Itoa(index)+"</p>", html)
+
+			processor.SetGlobal("other", index)
+		}(index)
+	}
+
+	wait.Wait()
+}
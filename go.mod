@@ -3,15 +3,17 @@ module github.com/sangupta/snowmark
 go 1.18
 
 require (
+	github.com/BurntSushi/toml v1.2.0
 	github.com/maja42/goval v1.2.1
 	github.com/sangupta/berry v0.1.0
 	github.com/sangupta/lhtml v0.2.1
 	github.com/stretchr/testify v1.8.0
+	github.com/yuin/goldmark v1.5.6
+	golang.org/x/net v0.0.0-20220708220712-1185a9018129
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	golang.org/x/net v0.0.0-20220708220712-1185a9018129 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
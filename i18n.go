@@ -0,0 +1,718 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sangupta/berry"
+	"gopkg.in/yaml.v3"
+)
+
+//
+// The model key that holds the locale of a merge, unless the locale
+// is given in the merge options.
+//
+const LocaleModelKey = "locale"
+
+//
+// The locale of a message bundle when none is given.
+//
+const DefaultLocale = "en"
+
+//
+// Error returned, wrapped in a `MessageError`, when a message is not
+// found in any locale of the fallback chain.
+//
+var ErrMessageNotFound = errors.New("Message not found")
+
+//
+// Error returned when a message cannot be found.
+//
+type MessageError struct {
+	Key    string
+	Locale string
+}
+
+//
+// Return the error message.
+//
+func (err *MessageError) Error() string {
+	return "Message not found: " + err.Key + " (locale " + err.Locale + ")"
+}
+
+//
+// Match `ErrMessageNotFound`.
+//
+func (err *MessageError) Is(target error) bool {
+	return target == ErrMessageNotFound
+}
+
+//
+// A bundle of translated messages for several locales. A message is
+// looked up by its key, such as `checkout.title`, in the locale of the
+// merge, then in its parent locales and finally in the default locale
+// of the bundle: `pt-BR` falls back to `pt` and then to `en`.
+//
+// A message is either a single text or a set of plural forms keyed by
+// CLDR plural category (`zero`, `one`, `two`, `few`, `many` and `other`),
+// of which `other` is required. Messages contain named placeholders like
+// `{name}`, which are replaced by the arguments of the translation. The
+// `count` argument selects the plural form.
+//
+// A bundle is safe for concurrent use, and messages may be added while
+// merges are running.
+//
+type MessageBundle struct {
+	_defaultLocale string
+	_messages      map[string]map[string]map[string]string
+	_mutex         sync.RWMutex
+}
+
+//
+// Create a new empty bundle, using the given locale when a message is
+// not found in the locale of the merge. An empty locale uses `en`.
+//
+func NewMessageBundle(defaultLocale string) *MessageBundle {
+	defaultLocale = NormalizeLocale(defaultLocale)
+	if defaultLocale == "" {
+		defaultLocale = DefaultLocale
+	}
+
+	return &MessageBundle{
+		_defaultLocale: defaultLocale,
+		_messages:      make(map[string]map[string]map[string]string),
+	}
+}
+
+//
+// Return the default locale of the bundle.
+//
+func (bundle *MessageBundle) DefaultLocale() string {
+	return bundle._defaultLocale
+}
+
+//
+// Return the locales that have messages, sorted.
+//
+func (bundle *MessageBundle) Locales() []string {
+	bundle._mutex.RLock()
+	defer bundle._mutex.RUnlock()
+
+	locales := make([]string, 0, len(bundle._messages))
+	for locale := range bundle._messages {
+		locales = append(locales, locale)
+	}
+
+	sort.Strings(locales)
+	return locales
+}
+
+//
+// Add a single message to the given locale, replacing any existing
+// message with the same key.
+//
+func (bundle *MessageBundle) AddMessage(locale string, key string, text string) error {
+	return bundle.AddPluralMessage(locale, key, map[string]string{PluralOther: text})
+}
+
+//
+// Add a message with plural forms to the given locale, keyed by CLDR
+// plural category. The `other` form is required.
+//
+func (bundle *MessageBundle) AddPluralMessage(locale string, key string, forms map[string]string) error {
+	locale = NormalizeLocale(locale)
+	if locale == "" {
+		return errors.New("Locale cannot be empty")
+	}
+
+	if key == "" {
+		return errors.New("Message key cannot be empty")
+	}
+
+	_, exists := forms[PluralOther]
+	if !exists {
+		return errors.New("Message '" + key + "' requires an 'other' form")
+	}
+
+	copied := make(map[string]string, len(forms))
+	for category, text := range forms {
+		if !isPluralCategory(category) {
+			return errors.New("Message '" + key + "' has unknown plural category: " + category)
+		}
+		copied[category] = text
+	}
+
+	bundle._mutex.Lock()
+	defer bundle._mutex.Unlock()
+
+	messages, exists := bundle._messages[locale]
+	if !exists {
+		messages = make(map[string]map[string]string)
+		bundle._messages[locale] = messages
+	}
+
+	messages[key] = copied
+	return nil
+}
+
+//
+// Check if a message exists in the given locale or any locale it falls
+// back to.
+//
+func (bundle *MessageBundle) HasMessage(locale string, key string) bool {
+	_, _, found := bundle.find(locale, key)
+	return found
+}
+
+//
+// Translate the message with the given key into the given locale. The
+// arguments replace the named placeholders of the message, and the
+// `count` argument, if present, selects the plural form.
+//
+func (bundle *MessageBundle) Translate(locale string, key string, args map[string]interface{}) (string, error) {
+	forms, matched, found := bundle.find(locale, key)
+	if !found {
+		return "", &MessageError{Key: key, Locale: locale}
+	}
+
+	text := forms[PluralOther]
+	count, hasCount := args["count"]
+	if hasCount {
+		form, exists := forms[PluralCategory(matched, count)]
+		if exists {
+			text = form
+		}
+	}
+
+	return formatMessage(text, args), nil
+}
+
+//
+// Find the message in the fallback chain of the locale, returning its
+// plural forms along with the locale it was found in.
+//
+func (bundle *MessageBundle) find(locale string, key string) (map[string]string, string, bool) {
+	bundle._mutex.RLock()
+	defer bundle._mutex.RUnlock()
+
+	for _, candidate := range bundle.localeChain(locale) {
+		forms, exists := bundle._messages[candidate][key]
+		if exists {
+			return forms, candidate, true
+		}
+	}
+
+	return nil, "", false
+}
+
+//
+// Return the locales to look a message up in: the locale itself and
+// its parents, followed by the default locale and its parents.
+//
+func (bundle *MessageBundle) localeChain(locale string) []string {
+	chain := make([]string, 0, 4)
+	for _, start := range []string{NormalizeLocale(locale), bundle._defaultLocale} {
+		for current := start; current != ""; {
+			if !containsString(chain, current) {
+				chain = append(chain, current)
+			}
+
+			index := strings.LastIndex(current, "-")
+			if index < 0 {
+				break
+			}
+			current = current[:index]
+		}
+	}
+
+	return chain
+}
+
+//
+// Read the messages of a locale from a JSON object. Nested objects
+// are flattened into dotted keys, such that `{"checkout": {"title": ""}}`
+// defines `checkout.title`. An object whose keys are all plural
+// categories, including `other`, defines the plural forms of a message.
+//
+func (bundle *MessageBundle) LoadJSON(locale string, reader io.Reader) error {
+	if reader == nil {
+		return errors.New("Reader is required to read messages")
+	}
+
+	values := make(map[string]interface{})
+	err := json.NewDecoder(reader).Decode(&values)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	return bundle.addMessages(locale, "", values)
+}
+
+//
+// Read the messages of a locale from a YAML mapping, in the same
+// layout as `LoadJSON`.
+//
+func (bundle *MessageBundle) LoadYAML(locale string, reader io.Reader) error {
+	if reader == nil {
+		return errors.New("Reader is required to read messages")
+	}
+
+	values := make(map[string]interface{})
+	err := yaml.NewDecoder(reader).Decode(&values)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	return bundle.addMessages(locale, "", values)
+}
+
+//
+// Read the messages of a locale from a gettext PO file. The `msgid`
+// is used as the key of the message. The `msgstr[n]` forms of plural
+// messages are assigned to the plural categories of the locale in the
+// order gettext uses. Untranslated and fuzzy entries are skipped, so
+// that the message falls back to another locale. Message contexts are
+// ignored.
+//
+func (bundle *MessageBundle) LoadPO(locale string, reader io.Reader) error {
+	if reader == nil {
+		return errors.New("Reader is required to read messages")
+	}
+
+	entries, err := parsePO(reader)
+	if err != nil {
+		return err
+	}
+
+	categories := getPluralRule(locale).gettext
+	for _, entry := range entries {
+		if entry.id == "" || entry.fuzzy || len(entry.forms) == 0 {
+			continue
+		}
+
+		forms := make(map[string]string)
+		if !entry.plural {
+			forms[PluralOther] = entry.forms[0]
+		} else {
+			for index, text := range entry.forms {
+				if index < len(categories) && text != "" {
+					forms[categories[index]] = text
+				}
+			}
+
+			_, exists := forms[PluralOther]
+			if !exists {
+				forms[PluralOther] = entry.forms[len(entry.forms)-1]
+			}
+		}
+
+		if forms[PluralOther] == "" {
+			continue
+		}
+
+		err = bundle.AddPluralMessage(locale, entry.id, forms)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//
+// Read all message files in the given directory of the file system,
+// such as `en.json`, `pt-BR.yaml` or `fr.po`. The name of the file,
+// without extension, is the locale of its messages. Files with other
+// extensions are skipped.
+//
+func (bundle *MessageBundle) LoadFS(fsys fs.FS, dir string) error {
+	if fsys == nil {
+		return errors.New("File system is required to read messages")
+	}
+
+	if dir == "" {
+		dir = "."
+	}
+
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		extension := path.Ext(entry.Name())
+		locale := strings.TrimSuffix(entry.Name(), extension)
+
+		var load func(string, io.Reader) error
+		switch strings.ToLower(extension) {
+		case ".json":
+			load = bundle.LoadJSON
+
+		case ".yaml", ".yml":
+			load = bundle.LoadYAML
+
+		case ".po":
+			load = bundle.LoadPO
+
+		default:
+			continue
+		}
+
+		file, err := fsys.Open(path.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+
+		err = load(locale, file)
+		file.Close()
+		if err != nil {
+			return errors.New("Unable to read messages from " + entry.Name() + ": " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+//
+// Add the messages of a decoded JSON or YAML document, flattening
+// nested objects into dotted keys.
+//
+func (bundle *MessageBundle) addMessages(locale string, prefix string, values map[string]interface{}) error {
+	for key, value := range values {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		var err error
+		switch typed := value.(type) {
+		case string:
+			err = bundle.AddMessage(locale, key, typed)
+
+		case map[string]interface{}:
+			forms, ok := pluralForms(typed)
+			if ok {
+				err = bundle.AddPluralMessage(locale, key, forms)
+			} else {
+				err = bundle.addMessages(locale, key, typed)
+			}
+
+		default:
+			err = errors.New("Message '" + key + "' must be a string or an object")
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//
+// Return the plural forms if all keys of the object are plural
+// categories with text values, including `other`.
+//
+func pluralForms(values map[string]interface{}) (map[string]string, bool) {
+	_, exists := values[PluralOther]
+	if !exists {
+		return nil, false
+	}
+
+	forms := make(map[string]string, len(values))
+	for category, value := range values {
+		text, ok := value.(string)
+		if !ok || !isPluralCategory(category) {
+			return nil, false
+		}
+		forms[category] = text
+	}
+
+	return forms, true
+}
+
+func isPluralCategory(category string) bool {
+	switch category {
+	case PluralZero, PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther:
+		return true
+	}
+
+	return false
+}
+
+//
+// Replace the named placeholders of the message, such as `{name}`,
+// with the arguments. Placeholders without an argument are kept.
+//
+func formatMessage(text string, args map[string]interface{}) string {
+	if len(args) == 0 || !strings.Contains(text, "{") {
+		return text
+	}
+
+	builder := strings.Builder{}
+	for {
+		start := strings.IndexByte(text, '{')
+		if start < 0 {
+			break
+		}
+
+		end := strings.IndexByte(text[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+
+		value, exists := args[strings.TrimSpace(text[start+1:end])]
+		if !exists {
+			builder.WriteString(text[:end+1])
+		} else {
+			builder.WriteString(text[:start])
+			builder.WriteString(berry.ConvertToString(value))
+		}
+
+		text = text[end+1:]
+	}
+
+	builder.WriteString(text)
+	return builder.String()
+}
+
+//
+// Normalize a locale to the form `language-Script-REGION`, such as
+// `pt-BR` for `pt_br`, or `zh-Hant-TW` for `zh_hant_tw`.
+//
+func NormalizeLocale(locale string) string {
+	locale = strings.TrimSpace(locale)
+	if locale == "" {
+		return ""
+	}
+
+	parts := strings.Split(strings.ReplaceAll(locale, "_", "-"), "-")
+	for index, part := range parts {
+		switch {
+		case index == 0:
+			parts[index] = strings.ToLower(part)
+
+		case len(part) == 4:
+			parts[index] = strings.ToUpper(part[:1]) + strings.ToLower(part[1:])
+
+		case len(part) == 2:
+			parts[index] = strings.ToUpper(part)
+
+		default:
+			parts[index] = strings.ToLower(part)
+		}
+	}
+
+	return strings.Join(parts, "-")
+}
+
+//
+// Check if the normalized locale has the shape of a BCP 47 language
+// tag, such as `en`, `pt-BR` or `zh-Hant-TW`: a language of two to
+// eight letters, followed by subtags of one to eight letters and
+// digits.
+//
+func isWellFormedLocale(locale string) bool {
+	for index, part := range strings.Split(locale, "-") {
+		if len(part) == 0 || len(part) > 8 || (index == 0 && len(part) < 2) {
+			return false
+		}
+
+		for _, r := range part {
+			isLetter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+			if !isLetter && (index == 0 || r < '0' || r > '9') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+//
+// An entry of a PO file.
+//
+type poEntry struct {
+	id     string
+	plural bool
+	forms  []string
+	fuzzy  bool
+}
+
+//
+// Parse the entries of a PO file. Strings spanning several lines are
+// joined, and C escapes such as `\n` and `\"` are decoded.
+//
+func parsePO(reader io.Reader) ([]*poEntry, error) {
+	entries := make([]*poEntry, 0)
+	entry := &poEntry{}
+	fuzzy := false
+
+	// the string that continuation lines are appended to
+	var target *string
+
+	flush := func() {
+		if entry.id != "" || len(entry.forms) > 0 {
+			entries = append(entries, entry)
+		}
+		entry = &poEntry{}
+		target = nil
+	}
+
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			if len(entry.forms) > 0 {
+				flush()
+			}
+
+			if strings.HasPrefix(line, "#,") && strings.Contains(line, "fuzzy") {
+				fuzzy = true
+			}
+			continue
+		}
+
+		if strings.HasPrefix(line, "\"") {
+			if target == nil {
+				return nil, errors.New("Unexpected string on line " + strconv.Itoa(lineNumber))
+			}
+
+			value, err := strconv.Unquote(line)
+			if err != nil {
+				return nil, errors.New("Invalid string on line " + strconv.Itoa(lineNumber))
+			}
+
+			*target += value
+			continue
+		}
+
+		keyword, rest, _ := strings.Cut(line, " ")
+		value, err := strconv.Unquote(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, errors.New("Invalid string on line " + strconv.Itoa(lineNumber))
+		}
+
+		switch {
+		case keyword == "msgctxt":
+			if entry.id != "" || len(entry.forms) > 0 {
+				flush()
+			}
+			target = nil
+
+		case keyword == "msgid":
+			if entry.id != "" || len(entry.forms) > 0 {
+				flush()
+			}
+
+			entry.id = value
+			entry.fuzzy = fuzzy
+			fuzzy = false
+			target = &entry.id
+
+		case keyword == "msgid_plural":
+			entry.plural = true
+			target = nil
+
+		case keyword == "msgstr":
+			entry.forms = append(entry.forms, value)
+			target = &entry.forms[len(entry.forms)-1]
+
+		case strings.HasPrefix(keyword, "msgstr["):
+			entry.plural = true
+			entry.forms = append(entry.forms, value)
+			target = &entry.forms[len(entry.forms)-1]
+
+		default:
+			return nil, errors.New("Unknown keyword on line " + strconv.Itoa(lineNumber) + ": " + keyword)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	flush()
+	return entries, nil
+}
+
+//
+// Set the message bundle that the `msg` tag and the `t` function
+// translate messages with. A `nil` bundle removes the bundle.
+//
+func (pageProcessor *HtmlPageProcessor) SetMessageBundle(bundle *MessageBundle) {
+	pageProcessor._messages = bundle
+}
+
+//
+// Return the message bundle of this processor, if any.
+//
+func (pageProcessor *HtmlPageProcessor) GetMessageBundle() *MessageBundle {
+	return pageProcessor._messages
+}
+
+//
+// Translate the message with the given key into the given locale,
+// using the message bundle of this processor.
+//
+func (pageProcessor *HtmlPageProcessor) Translate(locale string, key string, args map[string]interface{}) (string, error) {
+	if pageProcessor._messages == nil {
+		return "", errors.New("No message bundle is set on the processor")
+	}
+
+	return pageProcessor._messages.Translate(locale, key, args)
+}
+
+//
+// Return the locale of the current merge: the locale of the merge
+// options, else the `locale` value of the model, else the default
+// locale of the message bundle.
+//
+func (evaluator *Evaluator) Locale() string {
+	if evaluator.options != nil && evaluator.options.Locale != "" {
+		return NormalizeLocale(evaluator.options.Locale)
+	}
+
+	if evaluator.model != nil {
+		locale := evaluator.model.GetString(LocaleModelKey, "")
+		if locale != "" {
+			return NormalizeLocale(locale)
+		}
+	}
+
+	if evaluator.processor != nil && evaluator.processor._messages != nil {
+		return evaluator.processor._messages.DefaultLocale()
+	}
+
+	return DefaultLocale
+}
@@ -0,0 +1,276 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBundle(t *testing.T) *MessageBundle {
+	bundle := NewMessageBundle("en")
+
+	err := bundle.LoadJSON("en", strings.NewReader(`{
+		"checkout": {"title": "Checkout", "total": "Total for {name}"},
+		"cart": {"items": {"one": "{count} item", "other": "{count} items"}}
+	}`))
+	assert.NoError(t, err)
+
+	err = bundle.LoadYAML("pt", strings.NewReader(`
+checkout:
+  title: Finalizar compra
+cart:
+  items:
+    one: "{count} item"
+    other: "{count} itens"
+`))
+	assert.NoError(t, err)
+
+	err = bundle.AddMessage("pt-BR", "checkout.title", "Fechar pedido")
+	assert.NoError(t, err)
+	return bundle
+}
+
+func TestMessageBundle(t *testing.T) {
+	bundle := newTestBundle(t)
+
+	assert.Equal(t, "en", bundle.DefaultLocale())
+	assert.Equal(t, []string{"en", "pt", "pt-BR"}, bundle.Locales())
+
+	text, err := bundle.Translate("en", "checkout.title", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "Checkout", text)
+
+	// fallback chain: pt-BR -> pt -> en
+	assert.Equal(t, []string{"pt-BR", "pt", "en"}, bundle.localeChain("pt_br"))
+
+	text, _ = bundle.Translate("pt-BR", "checkout.title", nil)
+	assert.Equal(t, "Fechar pedido", text)
+
+	text, _ = bundle.Translate("pt-BR", "cart.items", map[string]interface{}{"count": 3})
+	assert.Equal(t, "3 itens", text)
+
+	text, _ = bundle.Translate("pt-BR", "checkout.total", map[string]interface{}{"name": "Ana"})
+	assert.Equal(t, "Total for Ana", text)
+
+	text, _ = bundle.Translate("en", "cart.items", map[string]interface{}{"count": 1})
+	assert.Equal(t, "1 item", text)
+
+	// plural rules of the locale the message was found in
+	text, _ = bundle.Translate("pt", "cart.items", map[string]interface{}{"count": 0})
+	assert.Equal(t, "0 item", text)
+
+	text, _ = bundle.Translate("en", "cart.items", map[string]interface{}{"count": 0})
+	assert.Equal(t, "0 items", text)
+
+	// placeholders without arguments are kept
+	text, _ = bundle.Translate("en", "checkout.total", nil)
+	assert.Equal(t, "Total for {name}", text)
+
+	assert.True(t, bundle.HasMessage("de", "checkout.title"))
+	assert.False(t, bundle.HasMessage("en", "missing"))
+
+	_, err = bundle.Translate("de", "missing", nil)
+	assert.True(t, errors.Is(err, ErrMessageNotFound))
+	assert.Equal(t, "Message not found: missing (locale de)", err.Error())
+
+	// invalid messages
+	assert.Error(t, bundle.AddPluralMessage("en", "x", map[string]string{"one": "x"}))
+	assert.Error(t, bundle.AddPluralMessage("en", "x", map[string]string{"other": "x", "lots": "x"}))
+	assert.Error(t, bundle.AddMessage("", "x", "x"))
+	assert.Error(t, bundle.AddMessage("en", "", "x"))
+	assert.Error(t, bundle.LoadJSON("en", strings.NewReader(`{"x": 1}`)))
+}
+
+func TestMessageBundleLoadPO(t *testing.T) {
+	bundle := NewMessageBundle("")
+	assert.Equal(t, "en", bundle.DefaultLocale())
+
+	err := bundle.LoadPO("ru", strings.NewReader(`
+# Russian messages
+msgid ""
+msgstr ""
+"Language: ru\n"
+"Plural-Forms: nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);\n"
+
+msgid "checkout.title"
+msgstr "Оформление "
+"заказа"
+
+msgid "cart.items"
+msgid_plural "cart.items"
+msgstr[0] "{count} товар"
+msgstr[1] "{count} товара"
+msgstr[2] "{count} товаров"
+
+#, fuzzy
+msgid "checkout.total"
+msgstr "Итого"
+
+msgid "checkout.note"
+msgstr ""
+
+msgctxt "menu"
+msgid "quote"
+msgstr "\"Цитата\""
+`))
+	assert.NoError(t, err)
+
+	text, _ := bundle.Translate("ru", "checkout.title", nil)
+	assert.Equal(t, "Оформление заказа", text)
+
+	for count, expected := range map[int]string{1: "1 товар", 3: "3 товара", 5: "5 товаров", 21: "21 товар"} {
+		text, _ = bundle.Translate("ru", "cart.items", map[string]interface{}{"count": count})
+		assert.Equal(t, expected, text)
+	}
+
+	// decimals use the last form
+	text, _ = bundle.Translate("ru", "cart.items", map[string]interface{}{"count": 1.5})
+	assert.Equal(t, "1.5 товаров", text)
+
+	text, _ = bundle.Translate("ru", "quote", nil)
+	assert.Equal(t, `"Цитата"`, text)
+
+	// fuzzy and untranslated entries are skipped
+	assert.False(t, bundle.HasMessage("ru", "checkout.total"))
+	assert.False(t, bundle.HasMessage("ru", "checkout.note"))
+
+	assert.Error(t, bundle.LoadPO("ru", strings.NewReader(`msgid "x`)))
+	assert.Error(t, bundle.LoadPO("ru", strings.NewReader(`msgfoo "x"`)))
+}
+
+func TestMessageBundleLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"messages/en.json":  {Data: []byte(`{"hello": "Hello"}`)},
+		"messages/fr.yaml":  {Data: []byte(`hello: Bonjour`)},
+		"messages/de.po":    {Data: []byte("msgid \"hello\"\nmsgstr \"Hallo\"\n")},
+		"messages/notes.md": {Data: []byte(`ignored`)},
+	}
+
+	bundle := NewMessageBundle("en")
+	assert.NoError(t, bundle.LoadFS(fsys, "messages"))
+	assert.Equal(t, []string{"de", "en", "fr"}, bundle.Locales())
+
+	text, _ := bundle.Translate("fr-CA", "hello", nil)
+	assert.Equal(t, "Bonjour", text)
+
+	fsys["messages/it.json"] = &fstest.MapFile{Data: []byte(`{`)}
+	err := bundle.LoadFS(fsys, "messages")
+	assert.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "Unable to read messages from it.json"))
+}
+
+func TestNormalizeLocale(t *testing.T) {
+	assert.Equal(t, "pt-BR", NormalizeLocale("pt_br"))
+	assert.Equal(t, "zh-Hant-TW", NormalizeLocale(" ZH-hant-tw "))
+	assert.Equal(t, "en", NormalizeLocale("EN"))
+	assert.Equal(t, "", NormalizeLocale(""))
+}
+
+func TestIsWellFormedLocale(t *testing.T) {
+	assert.True(t, isWellFormedLocale("en"))
+	assert.True(t, isWellFormedLocale("zh-Hant-TW"))
+	assert.True(t, isWellFormedLocale("es-419"))
+	assert.False(t, isWellFormedLocale("e"))
+	assert.False(t, isWellFormedLocale("en-"))
+	assert.False(t, isWellFormedLocale("12-US"))
+	assert.False(t, isWellFormedLocale(`en" onclick="x`))
+	assert.False(t, isWellFormedLocale("en-toolongsubtag"))
+}
+
+func TestMessageTag(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "")
+	processor.Import(I18nLibrary(), "")
+	processor.SetStrictMode(true)
+	processor.SetMessageBundle(newTestBundle(t))
+
+	model := NewModel()
+	model.Put("cart", map[string]interface{}{"size": 2})
+	model.Put("user", map[string]interface{}{"name": "Ana"})
+
+	html, err := processor.MergeHtml(`<h1><msg key="checkout.title" /></h1>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, "<h1>Checkout</h1>", html)
+
+	html, err = processor.MergeHtml(`<p><msg key="cart.items" count="cart.size" />|<msg key="checkout.total" args='{"name": user.name}' /></p>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>2 items|Total for Ana</p>", html)
+
+	// placeholder values are escaped
+	model.Put("attacker", map[string]interface{}{"name": "<img src=x onerror=alert(1)>"})
+	html, err = processor.MergeHtml(`<p><msg key="checkout.total" args='{"name": attacker.name}' /></p>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>Total for &lt;img src=x onerror=alert(1)&gt;</p>", html)
+
+	// locale from the model
+	model.Put("locale", "pt-BR")
+	html, _ = processor.MergeHtml(`<p><msg key="checkout.title" />|<msg key="cart.items" count="cart.size" /></p>`, model)
+	assert.Equal(t, "<p>Fechar pedido|2 itens</p>", html)
+
+	// merge options win over the model
+	html, _ = processor.MergeHtmlWithOptions(`<p><msg key="checkout.title" /></p>`, model, &MergeOptions{Locale: "pt"})
+	assert.Equal(t, "<p>Finalizar compra</p>", html)
+
+	// locale of the tag wins over the merge
+	html, _ = processor.MergeHtml(`<p><msg key="checkout.title" locale="en" />|<msg expr:key='"checkout." + "title"' /></p>`, model)
+	assert.Equal(t, "<p>Checkout|Fechar pedido</p>", html)
+
+	_, err = processor.MergeHtml(`<p><msg key="missing" /></p>`, model)
+	assert.True(t, errors.Is(err, ErrMessageNotFound))
+
+	_, err = processor.MergeHtml(`<p><msg key="checkout.total" args="user.name" /></p>`, model)
+	assert.Error(t, err)
+
+	// without a message bundle
+	processor.SetMessageBundle(nil)
+	_, err = processor.MergeHtml(`<p><msg key="checkout.title" /></p>`, nil)
+	assert.Error(t, err)
+}
+
+func TestTranslateFunction(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "")
+	processor.Import(I18nLibrary(), "")
+	processor.SetStrictMode(true)
+	processor.SetMessageBundle(newTestBundle(t))
+
+	model := NewModel()
+	model.Put("count", 1)
+
+	html, err := processor.MergeHtml(`<a expr:title='t("checkout.title")'><get var='t("cart.items", {"count": count})' /></a>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<a title="Checkout">1 item</a>`, html)
+
+	html, err = processor.MergeHtmlWithOptions(`<a><get var='t("cart.items", {"count": count + 1})' /></a>`, model, &MergeOptions{Locale: "pt-BR"})
+	assert.NoError(t, err)
+	assert.Equal(t, `<a>2 itens</a>`, html)
+
+	// placeholder values are escaped as in the msg tag
+	model.Put("attacker", map[string]interface{}{"name": "<img src=x onerror=alert(1)>"})
+	html, err = processor.MergeHtml(`<p><get var='t("checkout.total", {"name": attacker.name})' /></p>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>Total for &lt;img src=x onerror=alert(1)&gt;</p>", html)
+
+	_, err = processor.MergeHtml(`<a><get var='t()' /></a>`, model)
+	assert.Error(t, err)
+
+	_, err = processor.MergeHtml(`<a><get var='t(1)' /></a>`, model)
+	assert.Error(t, err)
+
+	_, err = processor.MergeHtml(`<a><get var='t("cart.items", 1)' /></a>`, model)
+	assert.Error(t, err)
+}
@@ -0,0 +1,39 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+//
+// Encode the value as JSON that is safe to embed in HTML, such as in
+// a `<script>` element: `<`, `>` and `&` are written as `\u003c`,
+// `\u003e` and `\u0026`, and the line separators U+2028 and U+2029
+// as `\u2028` and `\u2029`, so that the data cannot close the element
+// or break a JavaScript string. Quotes are not escaped, so the result
+// must not be written in an attribute as is.
+//
+func EncodeJSON(value interface{}) (string, error) {
+	buffer := bytes.Buffer{}
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(true)
+
+	err := encoder.Encode(value)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(buffer.String(), "\n"), nil
+}
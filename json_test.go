@@ -0,0 +1,74 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeJSON(t *testing.T) {
+	result, err := EncodeJSON(map[string]interface{}{
+		"name":  "</script><script>alert(1)</script>",
+		"terms": "Tom & Jerry\u2028\u2029",
+		"items": []interface{}{1, 2.5, true, nil},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"items":[1,2.5,true,null],"name":"\u003c/script\u003e\u003cscript\u003ealert(1)\u003c/script\u003e","terms":"Tom \u0026 Jerry\u2028\u2029"}`, result)
+
+	result, err = EncodeJSON(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "null", result)
+
+	_, err = EncodeJSON(func() {})
+	assert.Error(t, err)
+}
+
+func TestJSONTag(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "")
+	processor.Import(JSONLibrary(), "")
+	processor.SetStrictMode(true)
+
+	model := NewModel()
+	model.Put("cart", map[string]interface{}{
+		"items": []interface{}{"<b>Tea</b>"},
+		"total": 12,
+	})
+	model.Put("widget", "cart")
+
+	html, err := processor.MergeHtml(`<div><json var="cart" /></div>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<div>{"items":["\u003cb\u003eTea\u003c/b\u003e"],"total":12}</div>`, html)
+
+	html, err = processor.MergeHtml(`<div><json var="cart.total" id="cart-data" /></div>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<div><script type="application/json" id="cart-data">12</script></div>`, html)
+
+	html, err = processor.MergeHtml(`<div><json var="cart.items" expr:id='widget + "-items"' /></div>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<div><script type="application/json" id="cart-items">["\u003cb\u003eTea\u003c/b\u003e"]</script></div>`, html)
+
+	html, err = processor.MergeHtml(`<div><get var="json(cart.items)" /></div>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<div>["\u003cb\u003eTea\u003c/b\u003e"]</div>`, html)
+
+	// fields named like go keywords
+	model.Put("item", map[string]interface{}{"type": "book"})
+	html, err = processor.MergeHtml(`<div><json var="item.type" /></div>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<div>"book"</div>`, html)
+
+	_, err = processor.MergeHtml(`<div><json /></div>`, model)
+	assert.Error(t, err)
+}
@@ -0,0 +1,190 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+//
+// A value that is computed when it is first read, such as an expensive
+// navigation tree that only some templates use. Within a merge, the
+// value is computed at most once, and the value or error is returned on
+// every later read of the same merge; the next merge computes it again.
+// Outside of a merge, every call to `Value` computes the value. The
+// function must be safe for concurrent use if the model is shared by
+// concurrent merges.
+//
+// A `func() (interface{}, error)` that is put into a model is wrapped
+// in a `Lazy` automatically. Expressions, the `Get` and `Lookup` methods
+// of the model and `GetPath` all read the computed value, and an error
+// fails the tag that reads the value.
+//
+type Lazy struct {
+	compute func() (interface{}, error)
+}
+
+//
+// The value of a lazy value, as computed by a merge.
+//
+type lazyResult struct {
+	value interface{}
+	err   error
+}
+
+//
+// Create a new lazy value computed by the given function.
+//
+func NewLazy(compute func() (interface{}, error)) *Lazy {
+	return &Lazy{
+		compute: compute,
+	}
+}
+
+//
+// Compute and return the value.
+//
+func (lazy *Lazy) Value() (interface{}, error) {
+	if lazy.compute == nil {
+		return nil, errors.New("Lazy value has no function to compute it")
+	}
+
+	return lazy.compute()
+}
+
+//
+// Return the value of the lazy value for the current merge, computing
+// it if this is the first read of the merge.
+//
+func (evaluator *Evaluator) lazyValue(lazy *Lazy) (interface{}, error) {
+	result, exists := evaluator.lazy[lazy]
+	if !exists {
+		result = &lazyResult{}
+		result.value, result.err = lazy.Value()
+
+		if evaluator.lazy == nil {
+			evaluator.lazy = make(map[*Lazy]*lazyResult)
+		}
+		evaluator.lazy[lazy] = result
+	}
+
+	return result.value, result.err
+}
+
+//
+// Write the computed value as JSON.
+//
+func (lazy *Lazy) MarshalJSON() ([]byte, error) {
+	value, err := lazy.Value()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(value)
+}
+
+//
+// Wrap functions that compute a value in a `Lazy`, and return all
+// other values as they are.
+//
+func wrapLazy(value interface{}) interface{} {
+	compute, ok := value.(func() (interface{}, error))
+	if ok && compute != nil {
+		return NewLazy(compute)
+	}
+
+	return value
+}
+
+//
+// Return the computed value if the value is lazy, or the value itself.
+// Models of a merge compute each lazy value once for the merge.
+//
+func (model *Model) resolveLazy(value interface{}) (interface{}, error) {
+	lazy, ok := value.(*Lazy)
+	if !ok || lazy == nil {
+		return value, nil
+	}
+
+	if model._evaluator != nil {
+		return model._evaluator.lazyValue(lazy)
+	}
+
+	return lazy.Value()
+}
+
+//
+// Fail the tag being merged with the error of a lazy value that was
+// read by a method that cannot return it, such as `Get`. Nothing is
+// reported outside of a merge.
+//
+func (model *Model) reportLazyError(err error) {
+	if model._evaluator != nil && model._evaluator.lazyError == nil {
+		model._evaluator.lazyError = err
+	}
+}
+
+//
+// Return the values to evaluate the expression against, with the lazy
+// values it reads computed, and the nonce of the merge. The values of
+// the model are returned as they are if the expression does not read
+// any lazy value or the nonce.
+//
+func (evaluator *Evaluator) resolveVariables(expr string, values map[string]interface{}) (map[string]interface{}, error) {
+	variables, exists := evaluator.variables[expr]
+	if !exists {
+		info, err := parseExpression(expr)
+		if err == nil {
+			variables = info.Variables
+		}
+
+		if evaluator.variables == nil {
+			evaluator.variables = make(map[string][]string)
+		}
+		evaluator.variables[expr] = variables
+	}
+
+	var resolved map[string]interface{}
+	for _, variable := range variables {
+		var value interface{}
+		if variable == NonceModelKey && evaluator.Nonce() != "" {
+			value = evaluator.Nonce()
+		} else {
+			lazy, ok := values[variable].(*Lazy)
+			if !ok {
+				continue
+			}
+
+			var err error
+			value, err = evaluator.lazyValue(lazy)
+			if err != nil {
+				return nil, errors.New("Unable to compute '" + variable + "': " + err.Error())
+			}
+		}
+
+		if resolved == nil {
+			// copy, as the model may be shared
+			resolved = make(map[string]interface{}, len(values))
+			for key, item := range values {
+				resolved[key] = item
+			}
+		}
+		resolved[variable] = value
+	}
+
+	if resolved == nil {
+		return values, nil
+	}
+
+	return resolved, nil
+}
@@ -0,0 +1,173 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sangupta/lhtml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLazy(t *testing.T) {
+	calls := 0
+	lazy := NewLazy(func() (interface{}, error) {
+		calls++
+		return 42, nil
+	})
+
+	value, err := lazy.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, 42, value)
+	assert.Equal(t, 1, calls)
+
+	data, err := json.Marshal(lazy)
+	assert.NoError(t, err)
+	assert.Equal(t, "42", string(data))
+
+	_, err = NewLazy(nil).Value()
+	assert.Error(t, err)
+}
+
+func TestLazyModelValues(t *testing.T) {
+	calls := 0
+	model := NewModel()
+	model.Put("count", func() (interface{}, error) {
+		calls++
+		return 3, nil
+	})
+	model.Put("user", NewLazy(func() (interface{}, error) {
+		return map[string]interface{}{"name": "Jane"}, nil
+	}))
+	model.Put("broken", func() (interface{}, error) {
+		return nil, errors.New("database is down")
+	})
+
+	// nothing is computed until read
+	assert.Equal(t, 0, calls)
+
+	value, exists := model.Get("count")
+	assert.True(t, exists)
+	assert.Equal(t, 3, value)
+	assert.Equal(t, int64(3), model.GetInt64("count", 0))
+
+	// outside of a merge, the value is computed on every read
+	assert.Equal(t, 2, calls)
+
+	assert.Equal(t, "Jane", model.GetPathString("user.name", ""))
+
+	value, exists = model.Get("broken")
+	assert.True(t, exists)
+	assert.Nil(t, value)
+
+	_, err := model.LookupString("broken")
+	assert.Equal(t, "Unable to compute 'broken': database is down", err.Error())
+	assert.False(t, errors.Is(err, ErrWrongType))
+
+	data, err := json.Marshal(NewModelFromMap(map[string]interface{}{"count": func() (interface{}, error) { return 1, nil }}))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"count":1}`, string(data))
+}
+
+func TestLazyExpressions(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "")
+	processor.SetStrictMode(true)
+
+	calls := 0
+	model := NewModel()
+	model.Put("nav", func() (interface{}, error) {
+		calls++
+		return []interface{}{"home", "about"}, nil
+	})
+	model.Put("unused", func() (interface{}, error) {
+		t.Fatal("unused lazy value must not be computed")
+		return nil, nil
+	})
+
+	html, err := processor.MergeHtml("<ul><foreach collection='nav' var='item'><li><get var='item' /></li></foreach><li><get var='len(nav)' /></li></ul>", model)
+	assert.NoError(t, err)
+	assert.Equal(t, "<ul><li>home</li><li>about</li><li>2</li></ul>", html)
+	assert.Equal(t, 1, calls)
+
+	// every merge computes the value again, including merges of clones
+	// and of globals
+	_, err = processor.MergeHtml("<p><get var='len(nav)' /></p>", model)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	_, err = processor.MergeHtml("<p><get var='len(nav)' /></p>", model.Clone())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	processor.SetGlobal("globalNav", model.GetMap()["nav"])
+	html, err = processor.MergeHtml("<p><get var='len(globalNav)' /><get var='len(globalNav)' /></p>", NewModel())
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>22</p>", html)
+	assert.Equal(t, 4, calls)
+
+	// errors fail the render
+	model.Put("broken", func() (interface{}, error) {
+		return nil, errors.New("database is down")
+	})
+
+	_, err = processor.MergeHtml("<p><get var='broken' /></p>", model)
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "Unable to compute 'broken': database is down"))
+}
+
+func TestLazyModelValuesInMerge(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "")
+	processor.SetStrictMode(true)
+	processor.AddCustomTag("user", func(node *lhtml.HtmlNode, model *Model, evaluator *Evaluator) error {
+		name, _ := model.GetPath("user.name")
+		evaluator.WriteString(model.GetString("greeting", "") + " " + name.(string))
+		return nil
+	})
+
+	calls := 0
+	model := NewModel()
+	model.Put("greeting", "Hello")
+	model.Put("user", func() (interface{}, error) {
+		calls++
+		return map[string]interface{}{"name": "Jane"}, nil
+	})
+
+	// the helpers of the model share the value with expressions
+	html, err := processor.MergeHtml("<p><user /><user /><get var='user.name' /></p>", model)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>Hello JaneHello JaneJane</p>", html)
+	assert.Equal(t, 1, calls)
+
+	// errors fail the tag that reads the value
+	model.Put("greeting", func() (interface{}, error) {
+		return nil, errors.New("database is down")
+	})
+
+	_, err = processor.MergeHtml("<p><user /></p>", model)
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "Unable to compute 'greeting': database is down"))
+
+	var warnings []error
+	processor.SetStrictMode(false)
+	html, err = processor.MergeHtmlWithOptions("<p><user /></p>", model, &MergeOptions{
+		OnWarning: func(err error) {
+			warnings = append(warnings, err)
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, warnings, 1)
+}
@@ -0,0 +1,414 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+//
+// A function that can be called from within expressions. The
+// evaluator of the current merge is passed so that functions can
+// access the processor and the render state.
+//
+type ExpressionFunction func(evaluator *Evaluator, args ...interface{}) (interface{}, error)
+
+//
+// A filter transforms a value before it is written to the page.
+// Filters are applied using the `filter` attribute of the `get` tag,
+// such as `<get var="name" filter="trim|upper" />`.
+//
+type FilterFunction func(value interface{}) (interface{}, error)
+
+//
+// A custom tag along with its optional definition.
+//
+type libraryTag struct {
+	processor  CustomTagProcessor
+	definition *TagDefinition
+}
+
+//
+// A tag library bundles custom tags, expression functions and filters
+// so that they can be registered with a processor in one go. Tags are
+// registered under a prefix, such as `c:if`, while functions and filters
+// are registered with their plain name.
+//
+type TagLibrary struct {
+	Prefix     string
+	_tags      map[string]*libraryTag
+	_functions map[string]ExpressionFunction
+	_filters   map[string]FilterFunction
+}
+
+//
+// Create a new tag library with the given default prefix.
+//
+func NewTagLibrary(prefix string) *TagLibrary {
+	return &TagLibrary{
+		Prefix:     prefix,
+		_tags:      make(map[string]*libraryTag),
+		_functions: make(map[string]ExpressionFunction),
+		_filters:   make(map[string]FilterFunction),
+	}
+}
+
+//
+// Add a custom tag to the library. The name must not contain a prefix.
+// If the definition is `nil` and the tag is one of the standard tags,
+// the standard definition is used.
+//
+func (library *TagLibrary) AddTag(name string, tagProcessor CustomTagProcessor, definition *TagDefinition) error {
+	name, err := normalizeName(name)
+	if err != nil {
+		return err
+	}
+
+	if strings.Contains(name, ":") {
+		return errors.New("Tag name cannot contain a prefix")
+	}
+
+	if tagProcessor == nil {
+		return errors.New("Custom tag processor cannot be nil")
+	}
+
+	_, exists := library._tags[name]
+	if exists {
+		return errors.New("Tag already exists")
+	}
+
+	if definition == nil {
+		definition = standardTagDefinitions[funcPointer(tagProcessor)]
+	}
+
+	library._tags[name] = &libraryTag{
+		processor:  tagProcessor,
+		definition: definition,
+	}
+	return nil
+}
+
+//
+// Add an expression function to the library.
+//
+func (library *TagLibrary) AddFunction(name string, function ExpressionFunction) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("Function name cannot be empty")
+	}
+
+	if function == nil {
+		return errors.New("Function cannot be nil")
+	}
+
+	_, exists := library._functions[name]
+	if exists {
+		return errors.New("Function already exists")
+	}
+
+	library._functions[name] = function
+	return nil
+}
+
+//
+// Add a filter to the library.
+//
+func (library *TagLibrary) AddFilter(name string, filter FilterFunction) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("Filter name cannot be empty")
+	}
+
+	if filter == nil {
+		return errors.New("Filter cannot be nil")
+	}
+
+	_, exists := library._filters[name]
+	if exists {
+		return errors.New("Filter already exists")
+	}
+
+	library._filters[name] = filter
+	return nil
+}
+
+//
+// Return the names of all tags in the library, without prefix.
+//
+func (library *TagLibrary) TagNames() []string {
+	names := make([]string, 0, len(library._tags))
+	for name := range library._tags {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+//
+// Register all tags, functions and filters of the library with
+// this processor. Tags are registered as `prefix:name`, or with just
+// their name if the prefix is empty. Nothing is registered if any of
+// the names is already taken.
+//
+func (pageProcessor *HtmlPageProcessor) Import(library *TagLibrary, prefix string) error {
+	if library == nil {
+		return errors.New("Library cannot be nil")
+	}
+
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if strings.Contains(prefix, ":") {
+		return errors.New("Prefix cannot contain ':'")
+	}
+
+	// check for conflicts first, so that we import all or nothing
+	for name := range library._tags {
+		if pageProcessor.HasCustomTag(prefixedName(prefix, name)) {
+			return errors.New("Tag already exists: " + prefixedName(prefix, name))
+		}
+	}
+
+	// functions and filters of the same library may be imported
+	// again under another prefix
+	for name, function := range library._functions {
+		existing, exists := pageProcessor._functions[name]
+		if exists && reflect.ValueOf(existing).Pointer() != reflect.ValueOf(function).Pointer() {
+			return errors.New("Function already exists: " + name)
+		}
+	}
+
+	for name, filter := range library._filters {
+		existing, exists := pageProcessor._filters[name]
+		if exists && reflect.ValueOf(existing).Pointer() != reflect.ValueOf(filter).Pointer() {
+			return errors.New("Filter already exists: " + name)
+		}
+	}
+
+	for name, tag := range library._tags {
+		_, err := pageProcessor.AddCustomTagWithDefinition(prefixedName(prefix, name), tag.processor, tag.definition)
+		if err != nil {
+			return err
+		}
+	}
+
+	for name, function := range library._functions {
+		pageProcessor._functions[name] = function
+	}
+
+	for name, filter := range library._filters {
+		pageProcessor._filters[name] = filter
+	}
+
+	return nil
+}
+
+//
+// Register the library using its default prefix.
+//
+func (pageProcessor *HtmlPageProcessor) ImportWithDefaultPrefix(library *TagLibrary) error {
+	if library == nil {
+		return errors.New("Library cannot be nil")
+	}
+
+	return pageProcessor.Import(library, library.Prefix)
+}
+
+func prefixedName(prefix string, name string) string {
+	if prefix == "" {
+		return name
+	}
+
+	return prefix + ":" + name
+}
+
+//
+// Trim and lower-case the given tag name, returning an error if
+// the name is empty or blank.
+//
+func normalizeName(name string) (string, error) {
+	if name == "" {
+		return "", errors.New("Name cannot be empty")
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("Name cannot be blank")
+	}
+
+	return strings.ToLower(name), nil
+}
+
+//
+// Create the standard tag library that ships with snowmark, with the
+// core tags for variables and control flow. Its default prefix is `s`,
+// and it contains the following tags:
+//
+//  - `get`: GetVariableTag
+//  - `set`: SetVariableTag
+//  - `if`: IfElseTag
+//  - `foreach`: ForEachTag
+//
+// along with the `len`, `upper`, `lower` and `trim` functions, and
+// the `upper`, `lower`, `trim` and `capitalize` filters.
+//
+// The optional features come in libraries of their own, which use the
+// same default prefix: `IncludeLibrary`, `I18nLibrary`, `URLLibrary`,
+// `SanitizeLibrary`, `MarkdownLibrary` and `JSONLibrary`.
+//
+func StandardLibrary() *TagLibrary {
+	library := NewTagLibrary("s")
+
+	library.AddTag("get", GetVariableTag, GetVariableTagDefinition)
+	library.AddTag("set", SetVariableTag, SetVariableTagDefinition)
+	library.AddTag("if", IfElseTag, IfElseTagDefinition)
+	library.AddTag("foreach", ForEachTag, ForEachTagDefinition)
+
+	library.AddFunction("len", LenFunction)
+	library.AddFunction("upper", UpperFunction)
+	library.AddFunction("lower", LowerFunction)
+	library.AddFunction("trim", TrimFunction)
+
+	library.AddFilter("upper", UpperFilter)
+	library.AddFilter("lower", LowerFilter)
+	library.AddFilter("trim", TrimFilter)
+	library.AddFilter("capitalize", CapitalizeFilter)
+
+	return library
+}
+
+//
+// Create the include library, for templates that include other
+// templates loaded by the template loader of the processor. Its default
+// prefix is `s`, and it contains the `include` tag: IncludeTag.
+//
+func IncludeLibrary() *TagLibrary {
+	library := NewTagLibrary("s")
+
+	library.AddTag("include", IncludeTag, IncludeTagDefinition)
+
+	return library
+}
+
+//
+// Create the internationalization library, for translated messages and
+// locale-aware formatting. Its default prefix is `s`, and it contains
+// the following tags:
+//
+//  - `msg`: MessageTag
+//  - `formatNumber`: FormatNumberTag
+//  - `formatCurrency`: FormatCurrencyTag
+//  - `formatDate`: FormatDateTag
+//  - `formatRelativeTime`: FormatRelativeTimeTag
+//  - `bidi`: BidiTag
+//
+// along with the `t`, `formatNumber`, `formatPercent`, `formatCurrency`,
+// `formatDate` and `formatRelativeTime` functions.
+//
+func I18nLibrary() *TagLibrary {
+	library := NewTagLibrary("s")
+
+	library.AddTag("msg", MessageTag, MessageTagDefinition)
+	library.AddTag("formatNumber", FormatNumberTag, FormatNumberTagDefinition)
+	library.AddTag("formatCurrency", FormatCurrencyTag, FormatCurrencyTagDefinition)
+	library.AddTag("formatDate", FormatDateTag, FormatDateTagDefinition)
+	library.AddTag("formatRelativeTime", FormatRelativeTimeTag, FormatRelativeTimeTagDefinition)
+	library.AddTag("bidi", BidiTag, BidiTagDefinition)
+
+	library.AddFunction("t", TranslateFunction)
+	library.AddFunction("formatNumber", FormatNumberFunction)
+	library.AddFunction("formatPercent", FormatPercentFunction)
+	library.AddFunction("formatCurrency", FormatCurrencyFunction)
+	library.AddFunction("formatDate", FormatDateFunction)
+	library.AddFunction("formatRelativeTime", FormatRelativeTimeFunction)
+
+	return library
+}
+
+//
+// Create the URL library, for URLs built from paths and named routes.
+// Its default prefix is `s`, and it contains the `url` tag: URLTag,
+// along with the `url` and `route` functions.
+//
+func URLLibrary() *TagLibrary {
+	library := NewTagLibrary("s")
+
+	library.AddTag("url", URLTag, URLTagDefinition)
+
+	library.AddFunction("url", URLFunction)
+	library.AddFunction("route", RouteFunction)
+
+	return library
+}
+
+//
+// Create the sanitize library, for user-authored HTML. Its default
+// prefix is `s`, and it contains the `sanitize` tag: SanitizeTag.
+//
+func SanitizeLibrary() *TagLibrary {
+	library := NewTagLibrary("s")
+
+	library.AddTag("sanitize", SanitizeTag, SanitizeTagDefinition)
+
+	return library
+}
+
+//
+// Create the Markdown library. Its default prefix is `s`, and it
+// contains the `markdown` tag: MarkdownTag.
+//
+func MarkdownLibrary() *TagLibrary {
+	library := NewTagLibrary("s")
+
+	library.AddTag("markdown", MarkdownTag, MarkdownTagDefinition)
+
+	return library
+}
+
+//
+// Create the JSON library, for model values embedded in pages. Its
+// default prefix is `s`, and it contains the `json` tag: JSONTag, along
+// with the `json` function.
+//
+func JSONLibrary() *TagLibrary {
+	library := NewTagLibrary("s")
+
+	library.AddTag("json", JSONTag, JSONTagDefinition)
+
+	library.AddFunction("json", JSONFunction)
+
+	return library
+}
+
+//
+// Create the asset library, for static files with fingerprinted URLs
+// resolved by the asset resolver of the processor. Its default prefix
+// is `asset`, and it contains the following tags:
+//
+//  - `script`: AssetScriptTag
+//  - `style`: AssetStyleTag
+//
+// along with the `asset` and `assetIntegrity` functions.
+//
+func AssetLibrary() *TagLibrary {
+	library := NewTagLibrary("asset")
+
+	library.AddTag("script", AssetScriptTag, AssetScriptTagDefinition)
+	library.AddTag("style", AssetStyleTag, AssetStyleTagDefinition)
+
+	library.AddFunction("asset", AssetFunction)
+	library.AddFunction("assetIntegrity", AssetIntegrityFunction)
+
+	return library
+}
@@ -0,0 +1,126 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStandardLibrary(t *testing.T) {
+	library := StandardLibrary()
+	assert.Equal(t, "s", library.Prefix)
+	assert.Equal(t, []string{"foreach", "get", "if", "set"}, library.TagNames())
+
+	processor := NewHtmlPageProcessor()
+	assert.NoError(t, processor.Import(library, "c"))
+	assert.True(t, processor.HasCustomTag("c:if"))
+	assert.True(t, processor.HasCustomTag("c:foreach"))
+	assert.False(t, processor.HasCustomTag("if"))
+
+	definition, exists := processor.GetTagDefinition("c:foreach")
+	assert.True(t, exists)
+	assert.Equal(t, ForEachTagDefinition, definition)
+
+	// same library can be imported again under another prefix
+	assert.NoError(t, processor.Import(library, "x"))
+	assert.Error(t, processor.Import(library, "c"))
+	assert.NoError(t, processor.ImportWithDefaultPrefix(library))
+	assert.True(t, processor.HasCustomTag("s:get"))
+
+	assert.Error(t, processor.Import(nil, "c"))
+	assert.Error(t, processor.ImportWithDefaultPrefix(nil))
+	assert.Error(t, processor.Import(library, "a:b"))
+}
+
+func TestStandardLibraryMerge(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "c")
+
+	model := NewModel()
+	model.Put("names", []string{"a", "b"})
+	model.Put("age", 12)
+
+	template := "<ul><c:foreach collection='names' var='name'><li><c:get var='upper(name)' /></li></c:foreach></ul>" +
+		"<c:if condition='age > 10'><c:then>old</c:then><c:else>young</c:else></c:if>" +
+		"<c:get var='len(names)' /><c:get var='\" x \"' filter='trim|upper' />"
+
+	html, err := processor.MergeHtml(template, model)
+	assert.NoError(t, err)
+	assert.Equal(t, "<ul><li>A</li><li>B</li></ul>old2X", html)
+}
+
+func TestFeatureLibraries(t *testing.T) {
+	tests := []struct {
+		library *TagLibrary
+		tags    []string
+	}{
+		{IncludeLibrary(), []string{"include"}},
+		{I18nLibrary(), []string{"bidi", "formatcurrency", "formatdate", "formatnumber", "formatrelativetime", "msg"}},
+		{URLLibrary(), []string{"url"}},
+		{SanitizeLibrary(), []string{"sanitize"}},
+		{MarkdownLibrary(), []string{"markdown"}},
+		{JSONLibrary(), []string{"json"}},
+	}
+
+	// all libraries can be imported along with the standard library
+	processor := NewHtmlPageProcessor()
+	assert.NoError(t, processor.ImportWithDefaultPrefix(StandardLibrary()))
+	for _, test := range tests {
+		assert.Equal(t, "s", test.library.Prefix)
+		assert.Equal(t, test.tags, test.library.TagNames())
+		assert.NoError(t, processor.ImportWithDefaultPrefix(test.library))
+	}
+
+	assert.True(t, processor.HasCustomTag("s:markdown"))
+	definition, exists := processor.GetTagDefinition("s:markdown")
+	assert.True(t, exists)
+	assert.Equal(t, MarkdownTagDefinition, definition)
+
+	html, err := processor.MergeHtml(`<s:json var='"<b>"' />`, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, `"\u003cb\u003e"`, html)
+}
+
+func TestTagLibrary(t *testing.T) {
+	library := NewTagLibrary("my")
+	assert.NoError(t, library.AddTag("get", GetVariableTag, nil))
+	assert.Equal(t, GetVariableTagDefinition, library._tags["get"].definition)
+
+	assert.Error(t, library.AddTag("get", GetVariableTag, nil))
+	assert.Error(t, library.AddTag("", GetVariableTag, nil))
+	assert.Error(t, library.AddTag("  ", GetVariableTag, nil))
+	assert.Error(t, library.AddTag("a:b", GetVariableTag, nil))
+	assert.Error(t, library.AddTag("other", nil, nil))
+
+	assert.NoError(t, library.AddFunction("len", LenFunction))
+	assert.Error(t, library.AddFunction("len", LenFunction))
+	assert.Error(t, library.AddFunction("", LenFunction))
+	assert.Error(t, library.AddFunction("x", nil))
+
+	assert.NoError(t, library.AddFilter("upper", UpperFilter))
+	assert.Error(t, library.AddFilter("upper", UpperFilter))
+	assert.Error(t, library.AddFilter("", UpperFilter))
+	assert.Error(t, library.AddFilter("x", nil))
+
+	// conflicting function
+	processor := NewHtmlPageProcessor()
+	processor.AddFunction("len", UpperFunction)
+	assert.Error(t, processor.Import(library, ""))
+	assert.False(t, processor.HasCustomTag("get"))
+
+	// conflicting filter
+	processor = NewHtmlPageProcessor()
+	processor.AddFilter("upper", LowerFilter)
+	assert.Error(t, processor.Import(library, ""))
+}
@@ -0,0 +1,317 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// Error returned by template loaders when no template exists with
+// the requested name.
+//
+var ErrTemplateNotFound = errors.New("Template not found")
+
+//
+// A template loader returns parsed templates by name. Names are
+// slash-separated paths such as `pages/home.html`.
+//
+type TemplateLoader interface {
+	Load(name string) (*Template, error)
+}
+
+//
+// A loader that can record that one template depends on another, such
+// as through an include. In development mode, a template is parsed again
+// when any of its dependencies change.
+//
+type DependencyTracker interface {
+	AddDependency(name string, dependency string)
+}
+
+//
+// A template loader that reads templates from a file system and
+// caches the parsed templates. It is safe for concurrent use.
+//
+// In production mode, which is the default, templates are parsed once
+// and cached forever. In development mode, the modification time of the
+// file is checked on every load, and a template is parsed again if the
+// file or any of its dependencies has changed since.
+//
+type FileTemplateLoader struct {
+	fsys        fs.FS
+	mutex       sync.RWMutex
+	cache       map[string]*cachedTemplate
+	development bool
+}
+
+//
+// A parsed template along with the modification time of its file
+// and the names of the templates it depends on.
+//
+type cachedTemplate struct {
+	template     *Template
+	modTime      time.Time
+	dependencies map[string]bool
+}
+
+//
+// Create a new loader that reads templates from the given file system.
+//
+func NewFileTemplateLoader(fsys fs.FS) *FileTemplateLoader {
+	return &FileTemplateLoader{
+		fsys:  fsys,
+		cache: make(map[string]*cachedTemplate),
+	}
+}
+
+//
+// Create a new loader that reads templates from the given directory.
+//
+func NewDirectoryTemplateLoader(directory string) *FileTemplateLoader {
+	return NewFileTemplateLoader(os.DirFS(directory))
+}
+
+//
+// Enable or disable the development mode, in which changed templates
+// are parsed again on the next load.
+//
+func (loader *FileTemplateLoader) SetDevelopmentMode(development bool) {
+	loader.mutex.Lock()
+	defer loader.mutex.Unlock()
+
+	loader.development = development
+}
+
+//
+// Return `true` if the loader is in development mode.
+//
+func (loader *FileTemplateLoader) IsDevelopmentMode() bool {
+	loader.mutex.RLock()
+	defer loader.mutex.RUnlock()
+
+	return loader.development
+}
+
+//
+// Load the template with the given name. The template is parsed
+// on first use and then served from the cache. An error wrapping
+// `ErrTemplateNotFound` is returned if the template does not exist.
+//
+func (loader *FileTemplateLoader) Load(name string) (*Template, error) {
+	name, err := cleanTemplateName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	loader.mutex.RLock()
+	cached, exists := loader.cache[name]
+	development := loader.development
+	loader.mutex.RUnlock()
+
+	if exists && !development {
+		return cached.template, nil
+	}
+
+	if exists {
+		loader.mutex.Lock()
+		stale := loader.isStale(name, make(map[string]bool))
+		if stale {
+			delete(loader.cache, name)
+		}
+		loader.mutex.Unlock()
+
+		if !stale {
+			return cached.template, nil
+		}
+	}
+
+	cached, err = loader.parse(name)
+	if err != nil {
+		return nil, err
+	}
+
+	loader.mutex.Lock()
+	loader.cache[name] = cached
+	loader.mutex.Unlock()
+
+	return cached.template, nil
+}
+
+//
+// Record that the template with given name depends on another
+// template, such that it is parsed again in development mode when
+// the other template changes.
+//
+func (loader *FileTemplateLoader) AddDependency(name string, dependency string) {
+	name, err := cleanTemplateName(name)
+	if err != nil {
+		return
+	}
+
+	dependency, err = cleanTemplateName(dependency)
+	if err != nil || name == dependency {
+		return
+	}
+
+	loader.mutex.Lock()
+	defer loader.mutex.Unlock()
+
+	cached, exists := loader.cache[name]
+	if exists {
+		cached.dependencies[dependency] = true
+	}
+}
+
+//
+// Remove all parsed templates from the cache.
+//
+func (loader *FileTemplateLoader) Clear() {
+	loader.mutex.Lock()
+	defer loader.mutex.Unlock()
+
+	loader.cache = make(map[string]*cachedTemplate)
+}
+
+//
+// Check if the cached template, or any of its dependencies, has changed
+// on disk. Must be called with the write lock held, as stale dependencies
+// are removed from the cache.
+//
+func (loader *FileTemplateLoader) isStale(name string, visited map[string]bool) bool {
+	if visited[name] {
+		return false
+	}
+	visited[name] = true
+
+	cached, exists := loader.cache[name]
+	if !exists {
+		// never loaded or already removed, thus dependents must be parsed again
+		return true
+	}
+
+	info, err := fs.Stat(loader.fsys, name)
+	if err != nil || !info.ModTime().Equal(cached.modTime) {
+		delete(loader.cache, name)
+		return true
+	}
+
+	stale := false
+	for dependency := range cached.dependencies {
+		if loader.isStale(dependency, visited) {
+			stale = true
+		}
+	}
+
+	return stale
+}
+
+func (loader *FileTemplateLoader) parse(name string) (*cachedTemplate, error) {
+	info, err := fs.Stat(loader.fsys, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, &TemplateError{
+				Position: Position{File: name},
+				Err:      ErrTemplateNotFound,
+			}
+		}
+
+		return nil, err
+	}
+
+	bytes, err := fs.ReadFile(loader.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := ParseTemplate(name, string(bytes))
+	if err != nil {
+		return nil, err
+	}
+
+	return &cachedTemplate{
+		template:     template,
+		modTime:      info.ModTime(),
+		dependencies: make(map[string]bool),
+	}, nil
+}
+
+//
+// Clean the given template name into a path that is valid for
+// an `fs.FS`.
+//
+func cleanTemplateName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("Template name cannot be empty")
+	}
+
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if !fs.ValidPath(name) || name == "." {
+		return "", errors.New("Invalid template name: " + name)
+	}
+
+	return name, nil
+}
+
+//
+// Set the loader that is used to load templates by name, such as by
+// `MergeNamed` and the `include` tag.
+//
+func (pageProcessor *HtmlPageProcessor) SetTemplateLoader(loader TemplateLoader) {
+	pageProcessor._loader = loader
+}
+
+//
+// Return the loader that is used to load templates by name.
+//
+func (pageProcessor *HtmlPageProcessor) GetTemplateLoader() TemplateLoader {
+	return pageProcessor._loader
+}
+
+//
+// Load the template with given name using the template loader.
+//
+func (pageProcessor *HtmlPageProcessor) LoadTemplate(name string) (*Template, error) {
+	if pageProcessor._loader == nil {
+		return nil, errors.New("No template loader has been set")
+	}
+
+	return pageProcessor._loader.Load(name)
+}
+
+//
+// Load the template with given name using the template loader and
+// merge it with the given model.
+//
+func (pageProcessor *HtmlPageProcessor) MergeNamed(name string, model *Model) (string, error) {
+	return pageProcessor.MergeNamedWithOptions(name, model, nil)
+}
+
+//
+// Load the template with given name using the template loader and
+// merge it with the given model and options.
+//
+func (pageProcessor *HtmlPageProcessor) MergeNamedWithOptions(name string, model *Model, options *MergeOptions) (string, error) {
+	template, err := pageProcessor.LoadTemplate(name)
+	if err != nil {
+		return "", err
+	}
+
+	return pageProcessor.MergeTemplateWithOptions(template, model, options)
+}
@@ -0,0 +1,138 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileTemplateLoader(t *testing.T) {
+	fsys := fstest.MapFS{
+		"pages/home.html": &fstest.MapFile{Data: []byte("<p>home</p>")},
+	}
+
+	loader := NewFileTemplateLoader(fsys)
+
+	template, err := loader.Load("pages/home.html")
+	assert.NoError(t, err)
+	assert.Equal(t, "pages/home.html", template.Name)
+
+	// names are cleaned and templates are cached
+	cached, err := loader.Load("/pages/../pages/home.html")
+	assert.NoError(t, err)
+	assert.Same(t, template, cached)
+
+	loader.Clear()
+	reloaded, err := loader.Load("pages/home.html")
+	assert.NoError(t, err)
+	assert.NotSame(t, template, reloaded)
+
+	_, err = loader.Load("pages/missing.html")
+	assert.True(t, errors.Is(err, ErrTemplateNotFound))
+	assert.Equal(t, "pages/missing.html: Template not found", err.Error())
+
+	_, err = loader.Load(" ")
+	assert.Error(t, err)
+}
+
+func TestIncludeTag(t *testing.T) {
+	fsys := fstest.MapFS{
+		"page.html":          &fstest.MapFile{Data: []byte("<div><s:include template='partials/name.html' /></div>")},
+		"partials/name.html": &fstest.MapFile{Data: []byte("<b><s:get var='name' /></b>")},
+		"dynamic.html":       &fstest.MapFile{Data: []byte(`<s:include expr:template='"partials/" + part + ".html"' />`)},
+		"loop.html":          &fstest.MapFile{Data: []byte("<s:include template='loop.html' />")},
+		"broken.html":        &fstest.MapFile{Data: []byte("<s:include template='missing.html' />")},
+	}
+
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "s")
+	processor.Import(IncludeLibrary(), "s")
+	processor.SetStrictMode(true)
+
+	model := NewModel()
+	model.Put("name", "snowmark")
+	model.Put("part", "name")
+
+	// no loader
+	_, err := processor.MergeNamed("page.html", model)
+	assert.Error(t, err)
+
+	processor.SetTemplateLoader(NewFileTemplateLoader(fsys))
+
+	html, err := processor.MergeNamed("page.html", model)
+	assert.NoError(t, err)
+	assert.Equal(t, "<div><b>snowmark</b></div>", html)
+
+	html, err = processor.MergeNamed("dynamic.html", model)
+	assert.NoError(t, err)
+	assert.Equal(t, "<b>snowmark</b>", html)
+
+	_, err = processor.MergeNamed("loop.html", model)
+	assert.Error(t, err)
+
+	_, err = processor.MergeNamed("broken.html", model)
+	assert.True(t, errors.Is(err, ErrTemplateNotFound))
+}
+
+func TestFileTemplateLoaderDevelopmentMode(t *testing.T) {
+	modTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"page.html":   &fstest.MapFile{Data: []byte("<div><include template='header.html' /></div>"), ModTime: modTime},
+		"header.html": &fstest.MapFile{Data: []byte("<h1>one</h1>"), ModTime: modTime},
+	}
+
+	loader := NewFileTemplateLoader(fsys)
+	assert.False(t, loader.IsDevelopmentMode())
+
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "")
+	processor.Import(IncludeLibrary(), "")
+	processor.SetTemplateLoader(loader)
+
+	page, _ := loader.Load("page.html")
+	html, _ := processor.MergeNamed("page.html", nil)
+	assert.Equal(t, "<div><h1>one</h1></div>", html)
+
+	// production mode caches forever
+	fsys["header.html"] = &fstest.MapFile{Data: []byte("<h1>two</h1>"), ModTime: modTime.Add(time.Second)}
+	html, _ = processor.MergeNamed("page.html", nil)
+	assert.Equal(t, "<div><h1>one</h1></div>", html)
+
+	// development mode picks up the change, and parses the dependent page again
+	loader.SetDevelopmentMode(true)
+	assert.True(t, loader.IsDevelopmentMode())
+
+	reloaded, _ := loader.Load("page.html")
+	assert.NotSame(t, page, reloaded)
+
+	html, _ = processor.MergeNamed("page.html", nil)
+	assert.Equal(t, "<div><h1>two</h1></div>", html)
+
+	// unchanged templates are served from the cache
+	cached, _ := loader.Load("page.html")
+	assert.Same(t, reloaded, cached)
+
+	// changed templates are parsed again
+	fsys["page.html"] = &fstest.MapFile{Data: []byte("<p><include template='header.html' /></p>"), ModTime: modTime.Add(time.Second)}
+	html, _ = processor.MergeNamed("page.html", nil)
+	assert.Equal(t, "<p><h1>two</h1></p>", html)
+
+	// removed templates are reported as missing
+	delete(fsys, "page.html")
+	_, err := loader.Load("page.html")
+	assert.True(t, errors.Is(err, ErrTemplateNotFound))
+}
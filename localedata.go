@@ -0,0 +1,514 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"strings"
+)
+
+//
+// The names of the date styles that can be used instead of a pattern.
+//
+const (
+	DateStyleShort  = "short"
+	DateStyleMedium = "medium"
+	DateStyleLong   = "long"
+	DateStyleFull   = "full"
+)
+
+//
+// The CLDR data needed to format numbers, currencies, dates and
+// relative times in a locale.
+//
+type localeData struct {
+	decimal string
+	group   string
+
+	// the minimum number of digits in front of the first group
+	// separator, such that Spanish writes `1234` but `12.345`
+	minimumGrouping int
+
+	// the size of all groups but the last, such as 2 for `12,34,567`
+	secondaryGrouping int
+
+	// patterns where `{0}` is the number and `¤` the currency symbol
+	percent  string
+	currency string
+
+	// symbols that differ from `currencySymbols`
+	symbols map[string]string
+
+	am          string
+	pm          string
+	months      [12]string
+	shortMonths [12]string
+
+	// starting with Sunday
+	days      [7]string
+	shortDays [7]string
+
+	dateStyles map[string]string
+	relative   *relativeData
+}
+
+//
+// Phrases for relative times. The unit names are given in the order
+// of the plural categories, and are inserted into the `future` and
+// `past` phrases in place of `%s`.
+//
+type relativeData struct {
+	now        string
+	future     string
+	past       string
+	categories []string
+	units      map[string][]string
+}
+
+//
+// Currency symbols used by all locales unless they have their own.
+//
+var currencySymbols = map[string]string{
+	"AUD": "A$",
+	"BRL": "R$",
+	"CAD": "CA$",
+	"CNY": "CN¥",
+	"EUR": "€",
+	"GBP": "£",
+	"HKD": "HK$",
+	"ILS": "₪",
+	"INR": "₹",
+	"JPY": "¥",
+	"KRW": "₩",
+	"MXN": "MX$",
+	"NZD": "NZ$",
+	"USD": "$",
+	"VND": "₫",
+}
+
+//
+// Currencies that are not written with two fraction digits.
+//
+var currencyDigits = map[string]int{
+	"CLP": 0,
+	"ISK": 0,
+	"JPY": 0,
+	"KRW": 0,
+	"VND": 0,
+	"BHD": 3,
+	"KWD": 3,
+	"OMR": 3,
+}
+
+var englishMonths = [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}
+var englishShortMonths = [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+var englishDays = [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+var englishShortDays = [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+
+var cjkMonths = [12]string{"1月", "2月", "3月", "4月", "5月", "6月", "7月", "8月", "9月", "10月", "11月", "12月"}
+
+//
+// The locale data by language or locale. Locales that are not listed
+// use the data of their language, and languages that are not listed
+// use English.
+//
+var localeFormats = map[string]*localeData{
+	"en": {
+		decimal: ".", group: ",", minimumGrouping: 1,
+		percent: "{0}%", currency: "¤{0}",
+		am: "AM", pm: "PM",
+		months: englishMonths, shortMonths: englishShortMonths,
+		days: englishDays, shortDays: englishShortDays,
+		dateStyles: map[string]string{
+			DateStyleShort:  "M/d/yy",
+			DateStyleMedium: "MMM d, y",
+			DateStyleLong:   "MMMM d, y",
+			DateStyleFull:   "EEEE, MMMM d, y",
+		},
+		relative: &relativeData{
+			now: "now", future: "in {0} %s", past: "{0} %s ago",
+			categories: []string{PluralOne, PluralOther},
+			units: map[string][]string{
+				"second": {"second", "seconds"},
+				"minute": {"minute", "minutes"},
+				"hour":   {"hour", "hours"},
+				"day":    {"day", "days"},
+				"week":   {"week", "weeks"},
+				"month":  {"month", "months"},
+				"year":   {"year", "years"},
+			},
+		},
+	},
+
+	"de": {
+		decimal: ",", group: ".", minimumGrouping: 1,
+		percent: "{0}\u00a0%", currency: "{0}\u00a0¤",
+		am: "AM", pm: "PM",
+		months:      [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		shortMonths: [12]string{"Jan.", "Feb.", "März", "Apr.", "Mai", "Juni", "Juli", "Aug.", "Sept.", "Okt.", "Nov.", "Dez."},
+		days:        [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		shortDays:   [7]string{"So.", "Mo.", "Di.", "Mi.", "Do.", "Fr.", "Sa."},
+		dateStyles: map[string]string{
+			DateStyleShort:  "dd.MM.yy",
+			DateStyleMedium: "dd.MM.y",
+			DateStyleLong:   "d. MMMM y",
+			DateStyleFull:   "EEEE, d. MMMM y",
+		},
+		relative: &relativeData{
+			now: "jetzt", future: "in {0} %s", past: "vor {0} %s",
+			categories: []string{PluralOne, PluralOther},
+			units: map[string][]string{
+				"second": {"Sekunde", "Sekunden"},
+				"minute": {"Minute", "Minuten"},
+				"hour":   {"Stunde", "Stunden"},
+				"day":    {"Tag", "Tagen"},
+				"week":   {"Woche", "Wochen"},
+				"month":  {"Monat", "Monaten"},
+				"year":   {"Jahr", "Jahren"},
+			},
+		},
+	},
+
+	"fr": {
+		decimal: ",", group: "\u202f", minimumGrouping: 1,
+		percent: "{0}\u202f%", currency: "{0}\u00a0¤",
+		symbols: map[string]string{"CAD": "$CA", "USD": "$US"},
+		am:      "AM", pm: "PM",
+		months:      [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		shortMonths: [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		days:        [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		shortDays:   [7]string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."},
+		dateStyles: map[string]string{
+			DateStyleShort:  "dd/MM/y",
+			DateStyleMedium: "d MMM y",
+			DateStyleLong:   "d MMMM y",
+			DateStyleFull:   "EEEE d MMMM y",
+		},
+		relative: &relativeData{
+			now: "maintenant", future: "dans {0} %s", past: "il y a {0} %s",
+			categories: []string{PluralOne, PluralOther},
+			units: map[string][]string{
+				"second": {"seconde", "secondes"},
+				"minute": {"minute", "minutes"},
+				"hour":   {"heure", "heures"},
+				"day":    {"jour", "jours"},
+				"week":   {"semaine", "semaines"},
+				"month":  {"mois", "mois"},
+				"year":   {"an", "ans"},
+			},
+		},
+	},
+
+	"es": {
+		decimal: ",", group: ".", minimumGrouping: 2,
+		percent: "{0}\u00a0%", currency: "{0}\u00a0¤",
+		symbols: map[string]string{"USD": "US$"},
+		am:      "a.\u00a0m.", pm: "p.\u00a0m.",
+		months:      [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		shortMonths: [12]string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sept", "oct", "nov", "dic"},
+		days:        [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+		shortDays:   [7]string{"dom", "lun", "mar", "mié", "jue", "vie", "sáb"},
+		dateStyles: map[string]string{
+			DateStyleShort:  "d/M/yy",
+			DateStyleMedium: "d MMM y",
+			DateStyleLong:   "d 'de' MMMM 'de' y",
+			DateStyleFull:   "EEEE, d 'de' MMMM 'de' y",
+		},
+		relative: &relativeData{
+			now: "ahora", future: "dentro de {0} %s", past: "hace {0} %s",
+			categories: []string{PluralOne, PluralOther},
+			units: map[string][]string{
+				"second": {"segundo", "segundos"},
+				"minute": {"minuto", "minutos"},
+				"hour":   {"hora", "horas"},
+				"day":    {"día", "días"},
+				"week":   {"semana", "semanas"},
+				"month":  {"mes", "meses"},
+				"year":   {"año", "años"},
+			},
+		},
+	},
+
+	"it": {
+		decimal: ",", group: ".", minimumGrouping: 1,
+		percent: "{0}%", currency: "{0}\u00a0¤",
+		symbols: map[string]string{"USD": "USD"},
+		am:      "AM", pm: "PM",
+		months:      [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+		shortMonths: [12]string{"gen", "feb", "mar", "apr", "mag", "giu", "lug", "ago", "set", "ott", "nov", "dic"},
+		days:        [7]string{"domenica", "lunedì", "martedì", "mercoledì", "giovedì", "venerdì", "sabato"},
+		shortDays:   [7]string{"dom", "lun", "mar", "mer", "gio", "ven", "sab"},
+		dateStyles: map[string]string{
+			DateStyleShort:  "dd/MM/yy",
+			DateStyleMedium: "d MMM y",
+			DateStyleLong:   "d MMMM y",
+			DateStyleFull:   "EEEE d MMMM y",
+		},
+		relative: &relativeData{
+			now: "ora", future: "tra {0} %s", past: "{0} %s fa",
+			categories: []string{PluralOne, PluralOther},
+			units: map[string][]string{
+				"second": {"secondo", "secondi"},
+				"minute": {"minuto", "minuti"},
+				"hour":   {"ora", "ore"},
+				"day":    {"giorno", "giorni"},
+				"week":   {"settimana", "settimane"},
+				"month":  {"mese", "mesi"},
+				"year":   {"anno", "anni"},
+			},
+		},
+	},
+
+	"pt": {
+		decimal: ",", group: ".", minimumGrouping: 1,
+		percent: "{0}%", currency: "¤\u00a0{0}",
+		symbols: map[string]string{"USD": "US$"},
+		am:      "AM", pm: "PM",
+		months:      [12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		shortMonths: [12]string{"jan.", "fev.", "mar.", "abr.", "mai.", "jun.", "jul.", "ago.", "set.", "out.", "nov.", "dez."},
+		days:        [7]string{"domingo", "segunda-feira", "terça-feira", "quarta-feira", "quinta-feira", "sexta-feira", "sábado"},
+		shortDays:   [7]string{"dom.", "seg.", "ter.", "qua.", "qui.", "sex.", "sáb."},
+		dateStyles: map[string]string{
+			DateStyleShort:  "dd/MM/y",
+			DateStyleMedium: "d 'de' MMM 'de' y",
+			DateStyleLong:   "d 'de' MMMM 'de' y",
+			DateStyleFull:   "EEEE, d 'de' MMMM 'de' y",
+		},
+		relative: &relativeData{
+			now: "agora", future: "em {0} %s", past: "há {0} %s",
+			categories: []string{PluralOne, PluralOther},
+			units: map[string][]string{
+				"second": {"segundo", "segundos"},
+				"minute": {"minuto", "minutos"},
+				"hour":   {"hora", "horas"},
+				"day":    {"dia", "dias"},
+				"week":   {"semana", "semanas"},
+				"month":  {"mês", "meses"},
+				"year":   {"ano", "anos"},
+			},
+		},
+	},
+
+	"nl": {
+		decimal: ",", group: ".", minimumGrouping: 1,
+		percent: "{0}%", currency: "¤\u00a0{0}",
+		symbols: map[string]string{"USD": "US$"},
+		am:      "a.m.", pm: "p.m.",
+		months:      [12]string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+		shortMonths: [12]string{"jan", "feb", "mrt", "apr", "mei", "jun", "jul", "aug", "sep", "okt", "nov", "dec"},
+		days:        [7]string{"zondag", "maandag", "dinsdag", "woensdag", "donderdag", "vrijdag", "zaterdag"},
+		shortDays:   [7]string{"zo", "ma", "di", "wo", "do", "vr", "za"},
+		dateStyles: map[string]string{
+			DateStyleShort:  "dd-MM-y",
+			DateStyleMedium: "d MMM y",
+			DateStyleLong:   "d MMMM y",
+			DateStyleFull:   "EEEE d MMMM y",
+		},
+		relative: &relativeData{
+			now: "nu", future: "over {0} %s", past: "{0} %s geleden",
+			categories: []string{PluralOne, PluralOther},
+			units: map[string][]string{
+				"second": {"seconde", "seconden"},
+				"minute": {"minuut", "minuten"},
+				"hour":   {"uur", "uur"},
+				"day":    {"dag", "dagen"},
+				"week":   {"week", "weken"},
+				"month":  {"maand", "maanden"},
+				"year":   {"jaar", "jaar"},
+			},
+		},
+	},
+
+	"ru": {
+		decimal: ",", group: "\u00a0", minimumGrouping: 1,
+		percent: "{0}\u00a0%", currency: "{0}\u00a0¤",
+		symbols: map[string]string{"RUB": "₽", "USD": "$"},
+		am:      "AM", pm: "PM",
+		months:      [12]string{"января", "февраля", "марта", "апреля", "мая", "июня", "июля", "августа", "сентября", "октября", "ноября", "декабря"},
+		shortMonths: [12]string{"янв.", "февр.", "мар.", "апр.", "мая", "июн.", "июл.", "авг.", "сент.", "окт.", "нояб.", "дек."},
+		days:        [7]string{"воскресенье", "понедельник", "вторник", "среда", "четверг", "пятница", "суббота"},
+		shortDays:   [7]string{"вс", "пн", "вт", "ср", "чт", "пт", "сб"},
+		dateStyles: map[string]string{
+			DateStyleShort:  "dd.MM.y",
+			DateStyleMedium: "d MMM y 'г'.",
+			DateStyleLong:   "d MMMM y 'г'.",
+			DateStyleFull:   "EEEE, d MMMM y 'г'.",
+		},
+		relative: &relativeData{
+			now: "сейчас", future: "через {0} %s", past: "{0} %s назад",
+			categories: []string{PluralOne, PluralFew, PluralMany, PluralOther},
+			units: map[string][]string{
+				"second": {"секунду", "секунды", "секунд", "секунды"},
+				"minute": {"минуту", "минуты", "минут", "минуты"},
+				"hour":   {"час", "часа", "часов", "часа"},
+				"day":    {"день", "дня", "дней", "дня"},
+				"week":   {"неделю", "недели", "недель", "недели"},
+				"month":  {"месяц", "месяца", "месяцев", "месяца"},
+				"year":   {"год", "года", "лет", "года"},
+			},
+		},
+	},
+
+	"pl": {
+		decimal: ",", group: "\u00a0", minimumGrouping: 2,
+		percent: "{0}%", currency: "{0}\u00a0¤",
+		symbols: map[string]string{"PLN": "zł", "USD": "USD"},
+		am:      "AM", pm: "PM",
+		months:      [12]string{"stycznia", "lutego", "marca", "kwietnia", "maja", "czerwca", "lipca", "sierpnia", "września", "października", "listopada", "grudnia"},
+		shortMonths: [12]string{"sty", "lut", "mar", "kwi", "maj", "cze", "lip", "sie", "wrz", "paź", "lis", "gru"},
+		days:        [7]string{"niedziela", "poniedziałek", "wtorek", "środa", "czwartek", "piątek", "sobota"},
+		shortDays:   [7]string{"niedz.", "pon.", "wt.", "śr.", "czw.", "pt.", "sob."},
+		dateStyles: map[string]string{
+			DateStyleShort:  "d.MM.y",
+			DateStyleMedium: "d MMM y",
+			DateStyleLong:   "d MMMM y",
+			DateStyleFull:   "EEEE, d MMMM y",
+		},
+		relative: &relativeData{
+			now: "teraz", future: "za {0} %s", past: "{0} %s temu",
+			categories: []string{PluralOne, PluralFew, PluralMany, PluralOther},
+			units: map[string][]string{
+				"second": {"sekundę", "sekundy", "sekund", "sekundy"},
+				"minute": {"minutę", "minuty", "minut", "minuty"},
+				"hour":   {"godzinę", "godziny", "godzin", "godziny"},
+				"day":    {"dzień", "dni", "dni", "dnia"},
+				"week":   {"tydzień", "tygodnie", "tygodni", "tygodnia"},
+				"month":  {"miesiąc", "miesiące", "miesięcy", "miesiąca"},
+				"year":   {"rok", "lata", "lat", "roku"},
+			},
+		},
+	},
+
+	"ja": {
+		decimal: ".", group: ",", minimumGrouping: 1,
+		percent: "{0}%", currency: "¤{0}",
+		symbols: map[string]string{"JPY": "￥", "CNY": "元"},
+		am:      "午前", pm: "午後",
+		months: cjkMonths, shortMonths: cjkMonths,
+		days:      [7]string{"日曜日", "月曜日", "火曜日", "水曜日", "木曜日", "金曜日", "土曜日"},
+		shortDays: [7]string{"日", "月", "火", "水", "木", "金", "土"},
+		dateStyles: map[string]string{
+			DateStyleShort:  "y/MM/dd",
+			DateStyleMedium: "y/MM/dd",
+			DateStyleLong:   "y年M月d日",
+			DateStyleFull:   "y年M月d日EEEE",
+		},
+		relative: &relativeData{
+			now: "今", future: "%s後", past: "%s前",
+			categories: []string{PluralOther},
+			units: map[string][]string{
+				"second": {"{0} 秒"},
+				"minute": {"{0} 分"},
+				"hour":   {"{0} 時間"},
+				"day":    {"{0} 日"},
+				"week":   {"{0} 週間"},
+				"month":  {"{0} か月"},
+				"year":   {"{0} 年"},
+			},
+		},
+	},
+
+	"zh": {
+		decimal: ".", group: ",", minimumGrouping: 1,
+		percent: "{0}%", currency: "¤{0}",
+		symbols: map[string]string{"CNY": "¥", "JPY": "JP¥", "USD": "US$"},
+		am:      "上午", pm: "下午",
+		months: cjkMonths, shortMonths: cjkMonths,
+		days:      [7]string{"星期日", "星期一", "星期二", "星期三", "星期四", "星期五", "星期六"},
+		shortDays: [7]string{"周日", "周一", "周二", "周三", "周四", "周五", "周六"},
+		dateStyles: map[string]string{
+			DateStyleShort:  "y/M/d",
+			DateStyleMedium: "y年M月d日",
+			DateStyleLong:   "y年M月d日",
+			DateStyleFull:   "y年M月d日EEEE",
+		},
+		relative: &relativeData{
+			now: "现在", future: "%s后", past: "%s前",
+			categories: []string{PluralOther},
+			units: map[string][]string{
+				"second": {"{0}秒钟"},
+				"minute": {"{0}分钟"},
+				"hour":   {"{0}小时"},
+				"day":    {"{0}天"},
+				"week":   {"{0}周"},
+				"month":  {"{0}个月"},
+				"year":   {"{0}年"},
+			},
+		},
+	},
+}
+
+func init() {
+	// regional variants that differ from their language
+	localeFormats["en-GB"] = withLocaleData("en", func(data *localeData) {
+		data.dateStyles = map[string]string{
+			DateStyleShort:  "dd/MM/y",
+			DateStyleMedium: "d MMM y",
+			DateStyleLong:   "d MMMM y",
+			DateStyleFull:   "EEEE d MMMM y",
+		}
+		data.symbols = map[string]string{"USD": "US$"}
+	})
+
+	localeFormats["en-IN"] = withLocaleData("en-GB", func(data *localeData) {
+		data.secondaryGrouping = 2
+		data.symbols = map[string]string{"USD": "$"}
+	})
+
+	localeFormats["de-CH"] = withLocaleData("de", func(data *localeData) {
+		data.decimal = "."
+		data.group = "’"
+		data.percent = "{0}%"
+		data.currency = "¤\u00a0{0}"
+	})
+
+	localeFormats["pt-PT"] = withLocaleData("pt", func(data *localeData) {
+		data.group = "\u00a0"
+		data.minimumGrouping = 2
+		data.currency = "{0}\u00a0¤"
+		data.relative = &relativeData{
+			now: "agora", future: "dentro de {0} %s", past: "há {0} %s",
+			categories: data.relative.categories,
+			units:      data.relative.units,
+		}
+	})
+}
+
+//
+// Copy the data of a locale and change it for a regional variant.
+//
+func withLocaleData(base string, change func(data *localeData)) *localeData {
+	data := *localeFormats[base]
+	change(&data)
+	return &data
+}
+
+//
+// Return the data of the locale, falling back to its language and
+// then to English.
+//
+func getLocaleData(locale string) *localeData {
+	locale = NormalizeLocale(locale)
+	for locale != "" {
+		data, exists := localeFormats[locale]
+		if exists {
+			return data
+		}
+
+		index := strings.LastIndex(locale, "-")
+		if index < 0 {
+			break
+		}
+		locale = locale[:index]
+	}
+
+	return localeFormats["en"]
+}
@@ -0,0 +1,618 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+//
+// Error wrapped by the `Lookup` functions when the key does not exist.
+//
+var ErrValueNotFound = errors.New("Value not found")
+
+//
+// Error wrapped by the `Lookup` functions when the value cannot be
+// converted to the requested type.
+//
+var ErrWrongType = errors.New("Value has wrong type")
+
+//
+// Layouts tried in order when parsing a `string` as time, after any
+// layouts passed to `GetTime` and `LookupTime`.
+//
+var DefaultTimeLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+//
+// An error returned by the `Lookup` functions. Use `errors.Is` with
+// `ErrValueNotFound` or `ErrWrongType` to find out what went wrong.
+// For lazy values that fail, `Err` is the error of the computation.
+//
+type ValueError struct {
+	Key      string
+	Expected string
+	Value    interface{}
+	Err      error
+}
+
+//
+// Return the error message along with the key.
+//
+func (err *ValueError) Error() string {
+	if errors.Is(err.Err, ErrValueNotFound) {
+		return "Value not found: " + err.Key
+	}
+
+	if errors.Is(err.Err, ErrWrongType) {
+		return fmt.Sprintf("Value of '%s' is %T, expected %s", err.Key, err.Value, err.Expected)
+	}
+
+	return "Unable to compute '" + err.Key + "': " + err.Err.Error()
+}
+
+//
+// Return the wrapped error.
+//
+func (err *ValueError) Unwrap() error {
+	return err.Err
+}
+
+func notFoundError(key string) error {
+	return &ValueError{Key: key, Err: ErrValueNotFound}
+}
+
+func wrongTypeError(key string, expected string, value interface{}) error {
+	return &ValueError{Key: key, Expected: expected, Value: value, Err: ErrWrongType}
+}
+
+//
+// Get the `string` value for a key. Numbers and booleans are formatted
+// as strings; other types are reported as `ErrWrongType`.
+//
+func (model *Model) LookupString(key string) (string, error) {
+	value, exists, err := model.lookup(key)
+	if err != nil {
+		return "", &ValueError{Key: key, Err: err}
+	}
+
+	if !exists {
+		return "", notFoundError(key)
+	}
+
+	converted, ok := convertToString(value)
+	if !ok {
+		return "", wrongTypeError(key, "string", value)
+	}
+
+	return converted, nil
+}
+
+//
+// Convert strings, numbers, booleans and values that implement
+// `fmt.Stringer` to a `string`.
+//
+func convertToString(value interface{}) (string, bool) {
+	switch typed := value.(type) {
+	case string:
+		return typed, true
+
+	case []byte:
+		return string(typed), true
+
+	case fmt.Stringer:
+		return typed.String(), true
+	}
+
+	reflected := reflect.ValueOf(value)
+	switch reflected.Kind() {
+	case reflect.String:
+		return reflected.String(), true
+
+	case reflect.Bool:
+		return strconv.FormatBool(reflected.Bool()), true
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(reflected.Int(), 10), true
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(reflected.Uint(), 10), true
+
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(reflected.Float(), 'f', -1, 64), true
+	}
+
+	return "", false
+}
+
+//
+// Get the `bool` value for a key. The strings accepted by
+// `strconv.ParseBool` are converted.
+//
+func (model *Model) LookupBool(key string) (bool, error) {
+	value, exists, err := model.lookup(key)
+	if err != nil {
+		return false, &ValueError{Key: key, Err: err}
+	}
+
+	if !exists {
+		return false, notFoundError(key)
+	}
+
+	switch typed := value.(type) {
+	case bool:
+		return typed, nil
+
+	case string:
+		b, err := strconv.ParseBool(typed)
+		if err == nil {
+			return b, nil
+		}
+	}
+
+	return false, wrongTypeError(key, "bool", value)
+}
+
+//
+// Get the `int64` value for a key. Integers of all sizes, floats
+// without a fraction and numeric strings are converted.
+//
+func (model *Model) LookupInt64(key string) (int64, error) {
+	value, exists, err := model.lookup(key)
+	if err != nil {
+		return 0, &ValueError{Key: key, Err: err}
+	}
+
+	if !exists {
+		return 0, notFoundError(key)
+	}
+
+	reflected := reflect.ValueOf(value)
+	switch reflected.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflected.Int(), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if reflected.Uint() <= uint64(1<<63-1) {
+			return int64(reflected.Uint()), nil
+		}
+
+	case reflect.Float32, reflect.Float64:
+		float := reflected.Float()
+		if float == float64(int64(float)) {
+			return int64(float), nil
+		}
+
+	case reflect.String:
+		integer, err := strconv.ParseInt(reflected.String(), 10, 64)
+		if err == nil {
+			return integer, nil
+		}
+	}
+
+	return 0, wrongTypeError(key, "int64", value)
+}
+
+//
+// Get the `uint64` value for a key. Non-negative integers of all
+// sizes, floats without a fraction and numeric strings are converted.
+//
+func (model *Model) LookupUInt64(key string) (uint64, error) {
+	value, exists, err := model.lookup(key)
+	if err != nil {
+		return 0, &ValueError{Key: key, Err: err}
+	}
+
+	if !exists {
+		return 0, notFoundError(key)
+	}
+
+	reflected := reflect.ValueOf(value)
+	switch reflected.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if reflected.Int() >= 0 {
+			return uint64(reflected.Int()), nil
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return reflected.Uint(), nil
+
+	case reflect.Float32, reflect.Float64:
+		float := reflected.Float()
+		if float >= 0 && float == float64(uint64(float)) {
+			return uint64(float), nil
+		}
+
+	case reflect.String:
+		integer, err := strconv.ParseUint(reflected.String(), 10, 64)
+		if err == nil {
+			return integer, nil
+		}
+	}
+
+	return 0, wrongTypeError(key, "uint64", value)
+}
+
+//
+// Get the `float64` value for a key. All numbers and numeric
+// strings are converted.
+//
+func (model *Model) LookupFloat64(key string) (float64, error) {
+	value, exists, err := model.lookup(key)
+	if err != nil {
+		return 0, &ValueError{Key: key, Err: err}
+	}
+
+	if !exists {
+		return 0, notFoundError(key)
+	}
+
+	reflected := reflect.ValueOf(value)
+	switch reflected.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(reflected.Int()), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(reflected.Uint()), nil
+
+	case reflect.Float32, reflect.Float64:
+		return reflected.Float(), nil
+
+	case reflect.String:
+		float, err := strconv.ParseFloat(reflected.String(), 64)
+		if err == nil {
+			return float, nil
+		}
+	}
+
+	return 0, wrongTypeError(key, "float64", value)
+}
+
+//
+// Get a `time.Time` value for a key, or the default value if the key
+// does not exist or cannot be converted. See `LookupTime`.
+//
+func (model *Model) GetTime(key string, defaultValue time.Time, layouts ...string) time.Time {
+	value, err := model.LookupTime(key, layouts...)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+//
+// Get the `time.Time` value for a key. Strings are parsed using the
+// given layouts, followed by `DefaultTimeLayouts`; integers are taken
+// as seconds since the Unix epoch.
+//
+func (model *Model) LookupTime(key string, layouts ...string) (time.Time, error) {
+	value, exists, err := model.lookup(key)
+	if err != nil {
+		return time.Time{}, &ValueError{Key: key, Err: err}
+	}
+
+	if !exists {
+		return time.Time{}, notFoundError(key)
+	}
+
+	converted, ok := convertToTime(value, layouts...)
+	if ok {
+		return converted, nil
+	}
+
+	return time.Time{}, wrongTypeError(key, "time", value)
+}
+
+//
+// Convert the value to a time. Strings are parsed with the given
+// layouts followed by `DefaultTimeLayouts`, and integers are taken as
+// Unix seconds.
+//
+func convertToTime(value interface{}, layouts ...string) (time.Time, bool) {
+	switch typed := value.(type) {
+	case time.Time:
+		return typed, true
+
+	case *time.Time:
+		if typed != nil {
+			return *typed, true
+		}
+
+	case int:
+		return time.Unix(int64(typed), 0), true
+
+	case int64:
+		return time.Unix(typed, 0), true
+
+	case string:
+		for _, list := range [][]string{layouts, DefaultTimeLayouts} {
+			for _, layout := range list {
+				parsed, err := time.Parse(layout, typed)
+				if err == nil {
+					return parsed, true
+				}
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+//
+// Get a `time.Duration` value for a key, or the default value if the
+// key does not exist or cannot be converted. See `LookupDuration`.
+//
+func (model *Model) GetDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := model.LookupDuration(key)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+//
+// Get the `time.Duration` value for a key. Strings such as `1h30m`
+// are parsed using `time.ParseDuration`.
+//
+func (model *Model) LookupDuration(key string) (time.Duration, error) {
+	value, exists, err := model.lookup(key)
+	if err != nil {
+		return 0, &ValueError{Key: key, Err: err}
+	}
+
+	if !exists {
+		return 0, notFoundError(key)
+	}
+
+	switch typed := value.(type) {
+	case time.Duration:
+		return typed, nil
+
+	case string:
+		duration, err := time.ParseDuration(typed)
+		if err == nil {
+			return duration, nil
+		}
+	}
+
+	return 0, wrongTypeError(key, "duration", value)
+}
+
+//
+// Get a `[]string` value for a key, or the default value if the key
+// does not exist or cannot be converted. See `LookupStringSlice`.
+//
+func (model *Model) GetStringSlice(key string, defaultValue []string) []string {
+	value, err := model.LookupStringSlice(key)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+//
+// Get the `[]string` value for a key. Slices and arrays are converted
+// if all their items are strings, numbers or booleans.
+//
+func (model *Model) LookupStringSlice(key string) ([]string, error) {
+	value, exists, err := model.lookup(key)
+	if err != nil {
+		return nil, &ValueError{Key: key, Err: err}
+	}
+
+	if !exists {
+		return nil, notFoundError(key)
+	}
+
+	if typed, ok := value.([]string); ok {
+		return typed, nil
+	}
+
+	reflected := reflect.ValueOf(value)
+	if reflected.Kind() != reflect.Slice && reflected.Kind() != reflect.Array {
+		return nil, wrongTypeError(key, "[]string", value)
+	}
+
+	strings := make([]string, reflected.Len())
+	for index := range strings {
+		item, ok := convertToString(reflected.Index(index).Interface())
+		if !ok {
+			return nil, wrongTypeError(key, "[]string", value)
+		}
+
+		strings[index] = item
+	}
+
+	return strings, nil
+}
+
+//
+// Get a `map[string]interface{}` value for a key, or the default value
+// if the key does not exist or is not a map. See `LookupStringMap`.
+//
+func (model *Model) GetStringMap(key string, defaultValue map[string]interface{}) map[string]interface{} {
+	value, err := model.LookupStringMap(key)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+//
+// Get the `map[string]interface{}` value for a key. Maps with string
+// keys are copied into a new map, and models return all their values.
+//
+func (model *Model) LookupStringMap(key string) (map[string]interface{}, error) {
+	value, exists, err := model.lookup(key)
+	if err != nil {
+		return nil, &ValueError{Key: key, Err: err}
+	}
+
+	if !exists {
+		return nil, notFoundError(key)
+	}
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		return typed, nil
+
+	case *Model:
+		if typed != nil {
+			return typed.ToMap(), nil
+		}
+	}
+
+	reflected := reflect.ValueOf(value)
+	if reflected.Kind() == reflect.Map && reflected.Type().Key().Kind() == reflect.String {
+		values := make(map[string]interface{}, reflected.Len())
+		iterator := reflected.MapRange()
+		for iterator.Next() {
+			values[iterator.Key().String()] = iterator.Value().Interface()
+		}
+
+		return values, nil
+	}
+
+	return nil, wrongTypeError(key, "map[string]interface{}", value)
+}
+
+//
+// Get the nested model for a key, or `nil` if the key does not exist
+// or cannot be converted. See `LookupModel`.
+//
+func (model *Model) GetModel(key string) *Model {
+	value, err := model.LookupModel(key)
+	if err != nil {
+		return nil
+	}
+
+	return value
+}
+
+//
+// Get the nested model for a key. Nested models are returned as they
+// are, and a `map[string]interface{}` is wrapped in a model that shares
+// the map, such that changes to the model are seen by this model.
+// Structs are converted using `NewModelFromStruct`.
+//
+func (model *Model) LookupModel(key string) (*Model, error) {
+	value, exists, err := model.lookup(key)
+	if err != nil {
+		return nil, &ValueError{Key: key, Err: err}
+	}
+
+	if !exists {
+		return nil, notFoundError(key)
+	}
+
+	switch typed := value.(type) {
+	case *Model:
+		if typed != nil {
+			return typed, nil
+		}
+
+	case map[string]interface{}:
+		if typed != nil {
+			return &Model{_map: typed}, nil
+		}
+	}
+
+	nested, err := NewModelFromStruct(value)
+	if err != nil {
+		return nil, wrongTypeError(key, "model", value)
+	}
+
+	return nested, nil
+}
+
+//
+// Get the value for a key as type `T`, or the default value if the key
+// does not exist or cannot be converted. See `LookupAs`.
+//
+//  count := snowmark.GetAs(model, "count", 0)
+//  user := snowmark.GetAs[*User](model, "user", nil)
+//
+func GetAs[T any](model *Model, key string, defaultValue T) T {
+	value, err := LookupAs[T](model, key)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+//
+// Get the value for a key as type `T`. Values that are of type `T`
+// are returned as they are, and numbers are converted to other number
+// types if `T` is a number type.
+//
+func LookupAs[T any](model *Model, key string) (T, error) {
+	var zero T
+
+	value, exists, err := model.lookup(key)
+	if err != nil {
+		return zero, &ValueError{Key: key, Err: err}
+	}
+
+	if !exists {
+		return zero, notFoundError(key)
+	}
+
+	typed, ok := value.(T)
+	if ok {
+		return typed, nil
+	}
+
+	target := reflect.TypeOf(&zero).Elem()
+	reflected := reflect.ValueOf(value)
+	if reflected.IsValid() && isNumberKind(reflected.Kind()) && isNumberKind(target.Kind()) {
+		converted := reflected.Convert(target)
+
+		// only allow conversions that do not lose information
+		if converted.Convert(reflected.Type()).Interface() == reflected.Interface() {
+			return converted.Interface().(T), nil
+		}
+	}
+
+	if value == nil {
+		switch target.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+			return zero, nil
+		}
+	}
+
+	return zero, wrongTypeError(key, target.String(), value)
+}
+
+func isNumberKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,192 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelLookupScalars(t *testing.T) {
+	model := NewModel()
+	model.Put("name", "Jane")
+	model.Put("count", 3)
+	model.Put("ratio", 2.5)
+	model.Put("flag", "true")
+	model.Put("negative", -1)
+	model.Put("list", []int{1})
+
+	value, err := model.LookupString("count")
+	assert.NoError(t, err)
+	assert.Equal(t, "3", value)
+
+	_, err = model.LookupString("list")
+	assert.True(t, errors.Is(err, ErrWrongType))
+	assert.Equal(t, "Value of 'list' is []int, expected string", err.Error())
+
+	_, err = model.LookupString("missing")
+	assert.True(t, errors.Is(err, ErrValueNotFound))
+	assert.False(t, errors.Is(err, ErrWrongType))
+	assert.Equal(t, "Value not found: missing", err.Error())
+
+	flag, err := model.LookupBool("flag")
+	assert.NoError(t, err)
+	assert.True(t, flag)
+	_, err = model.LookupBool("name")
+	assert.True(t, errors.Is(err, ErrWrongType))
+
+	integer, err := model.LookupInt64("count")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), integer)
+	_, err = model.LookupInt64("ratio")
+	assert.True(t, errors.Is(err, ErrWrongType))
+
+	unsigned, err := model.LookupUInt64("count")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), unsigned)
+	_, err = model.LookupUInt64("negative")
+	assert.True(t, errors.Is(err, ErrWrongType))
+
+	float, err := model.LookupFloat64("count")
+	assert.NoError(t, err)
+	assert.Equal(t, 3.0, float)
+	_, err = model.LookupFloat64("name")
+	assert.True(t, errors.Is(err, ErrWrongType))
+}
+
+func TestModelGetTime(t *testing.T) {
+	when := time.Date(2022, 7, 1, 10, 30, 0, 0, time.UTC)
+
+	model := NewModel()
+	model.Put("time", when)
+	model.Put("rfc", "2022-07-01T10:30:00Z")
+	model.Put("date", "2022-07-01")
+	model.Put("custom", "01/07/2022")
+	model.Put("unix", int(when.Unix()))
+	model.Put("bad", "yesterday")
+
+	assert.Equal(t, when, model.GetTime("time", time.Time{}))
+	assert.True(t, when.Equal(model.GetTime("rfc", time.Time{})))
+	assert.Equal(t, time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC), model.GetTime("date", time.Time{}))
+	assert.Equal(t, time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC), model.GetTime("custom", time.Time{}, "02/01/2006"))
+	assert.True(t, when.Equal(model.GetTime("unix", time.Time{})))
+	assert.Equal(t, time.Time{}, model.GetTime("bad", time.Time{}))
+
+	_, err := model.LookupTime("bad")
+	assert.True(t, errors.Is(err, ErrWrongType))
+	_, err = model.LookupTime("missing")
+	assert.True(t, errors.Is(err, ErrValueNotFound))
+}
+
+func TestModelGetDuration(t *testing.T) {
+	model := NewModel()
+	model.Put("timeout", "1m30s")
+	model.Put("ttl", 5*time.Second)
+	model.Put("bad", 10)
+
+	assert.Equal(t, 90*time.Second, model.GetDuration("timeout", 0))
+	assert.Equal(t, 5*time.Second, model.GetDuration("ttl", 0))
+	assert.Equal(t, time.Second, model.GetDuration("bad", time.Second))
+
+	_, err := model.LookupDuration("bad")
+	assert.True(t, errors.Is(err, ErrWrongType))
+}
+
+func TestModelGetStringSlice(t *testing.T) {
+	model := NewModel()
+	model.Put("tags", []string{"a", "b"})
+	model.Put("mixed", []interface{}{"a", 1, true})
+	model.Put("nested", []interface{}{[]int{1}})
+	model.Put("name", "a")
+
+	assert.Equal(t, []string{"a", "b"}, model.GetStringSlice("tags", nil))
+	assert.Equal(t, []string{"a", "1", "true"}, model.GetStringSlice("mixed", nil))
+	assert.Nil(t, model.GetStringSlice("nested", nil))
+
+	_, err := model.LookupStringSlice("name")
+	assert.True(t, errors.Is(err, ErrWrongType))
+}
+
+func TestModelGetStringMap(t *testing.T) {
+	nested := NewModel()
+	nested.Put("a", 1)
+
+	model := NewModel()
+	model.Put("map", map[string]interface{}{"a": 1})
+	model.Put("typed", map[string]int{"a": 1})
+	model.Put("model", nested)
+	model.Put("keys", map[int]string{1: "a"})
+
+	expected := map[string]interface{}{"a": 1}
+	assert.Equal(t, expected, model.GetStringMap("map", nil))
+	assert.Equal(t, expected, model.GetStringMap("typed", nil))
+	assert.Equal(t, expected, model.GetStringMap("model", nil))
+	assert.Nil(t, model.GetStringMap("keys", nil))
+
+	_, err := model.LookupStringMap("keys")
+	assert.True(t, errors.Is(err, ErrWrongType))
+}
+
+func TestModelGetModel(t *testing.T) {
+	nested := NewModel()
+	values := map[string]interface{}{"a": 1}
+
+	model := NewModel()
+	model.Put("model", nested)
+	model.Put("map", values)
+	model.Put("struct", struct{ Name string }{Name: "Jane"})
+	model.Put("number", 1)
+
+	assert.Same(t, nested, model.GetModel("model"))
+	assert.Equal(t, "Jane", model.GetModel("struct").GetString("Name", ""))
+	assert.Nil(t, model.GetModel("number"))
+
+	// the map is shared
+	model.GetModel("map").Put("b", 2)
+	assert.Equal(t, 2, values["b"])
+
+	_, err := model.LookupModel("number")
+	assert.True(t, errors.Is(err, ErrWrongType))
+	_, err = model.LookupModel("missing")
+	assert.True(t, errors.Is(err, ErrValueNotFound))
+}
+
+func TestGetAs(t *testing.T) {
+	type user struct{ Name string }
+
+	model := NewModel()
+	model.Put("count", 3)
+	model.Put("ratio", 2.5)
+	model.Put("user", &user{Name: "Jane"})
+	model.Put("none", nil)
+
+	assert.Equal(t, 3, GetAs(model, "count", 0))
+	assert.Equal(t, int64(3), GetAs[int64](model, "count", 0))
+	assert.Equal(t, 3.0, GetAs[float64](model, "count", 0))
+	assert.Equal(t, 7, GetAs(model, "ratio", 7))
+	assert.Equal(t, "Jane", GetAs[*user](model, "user", nil).Name)
+	assert.Equal(t, "x", GetAs(model, "count", "x"))
+
+	value, err := LookupAs[*user](model, "none")
+	assert.NoError(t, err)
+	assert.Nil(t, value)
+
+	_, err = LookupAs[int](model, "ratio")
+	assert.True(t, errors.Is(err, ErrWrongType))
+	assert.Equal(t, "Value of 'ratio' is float64, expected int", err.Error())
+
+	_, err = LookupAs[int](model, "missing")
+	assert.True(t, errors.Is(err, ErrValueNotFound))
+}
@@ -0,0 +1,302 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/sangupta/lhtml"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+//
+// Options for converting Markdown to HTML.
+//
+type MarkdownOptions struct {
+	// Add an `id` to every heading, generated from its text, such as
+	// `getting-started` for `## Getting started`.
+	HeadingIDs bool
+
+	// Keep the HTML embedded in the Markdown. By default it is replaced
+	// by a comment.
+	AllowHTML bool
+
+	// Sanitize the resulting HTML with this policy, which then must
+	// allow the elements that Markdown creates, such as the `markdown`
+	// policy does. Embedded HTML is kept, and sanitized along with the
+	// rest.
+	Sanitize *SanitizePolicy
+
+	// Called for every link and image, to change its destination or
+	// title, or to add attributes such as `target` or `loading`.
+	LinkHook func(link *MarkdownLink)
+}
+
+//
+// A link or image in Markdown, as passed to the link hook.
+//
+type MarkdownLink struct {
+	// Whether this is an image.
+	Image bool
+
+	// The URL of the link or image.
+	Destination string
+
+	// The title of the link or image, if any.
+	Title string
+
+	// Attributes to add to the element.
+	Attributes map[string]string
+}
+
+//
+// Create the default options for Markdown, which add `id` attributes
+// to headings and omit embedded HTML.
+//
+func NewMarkdownOptions() *MarkdownOptions {
+	return &MarkdownOptions{
+		HeadingIDs: true,
+	}
+}
+
+//
+// Create the `markdown` sanitize policy, which keeps the elements that
+// Markdown creates, with links to web and email addresses that get
+// `rel="nofollow"`.
+//
+func NewMarkdownSanitizePolicy() *SanitizePolicy {
+	return &SanitizePolicy{
+		AllowedElements: []string{
+			"a", "b", "blockquote", "br", "code", "del", "em", "h1", "h2", "h3",
+			"h4", "h5", "h6", "hr", "i", "img", "li", "ol", "p", "pre", "q", "s",
+			"strong", "sub", "sup", "u", "ul",
+		},
+		AllowedAttributes: map[string][]string{
+			"a":    {"href", "title"},
+			"code": {"class"},
+			"h1":   {"id"},
+			"h2":   {"id"},
+			"h3":   {"id"},
+			"h4":   {"id"},
+			"h5":   {"id"},
+			"h6":   {"id"},
+			"img":  {"src", "alt", "title"},
+			"ol":   {"start"},
+		},
+		AllowedURLSchemes: []string{"http", "https", "mailto"},
+		RequireNoFollow:   true,
+	}
+}
+
+//
+// Convert CommonMark to HTML. `nil` options use the defaults.
+//
+func RenderMarkdown(source string, options *MarkdownOptions) (string, error) {
+	if options == nil {
+		options = NewMarkdownOptions()
+	}
+
+	parserOptions := make([]parser.Option, 0, 2)
+	if options.HeadingIDs {
+		parserOptions = append(parserOptions, parser.WithAutoHeadingID())
+	}
+
+	if options.LinkHook != nil {
+		parserOptions = append(parserOptions, parser.WithASTTransformers(
+			util.Prioritized(&markdownLinkTransformer{hook: options.LinkHook}, 100),
+		))
+	}
+
+	rendererOptions := make([]goldmark.Option, 0, 2)
+	rendererOptions = append(rendererOptions, goldmark.WithParserOptions(parserOptions...))
+	if options.AllowHTML || options.Sanitize != nil {
+		rendererOptions = append(rendererOptions, goldmark.WithRendererOptions(html.WithUnsafe()))
+	}
+
+	buffer := bytes.Buffer{}
+	err := goldmark.New(rendererOptions...).Convert([]byte(source), &buffer)
+	if err != nil {
+		return "", err
+	}
+
+	if options.Sanitize != nil {
+		return options.Sanitize.Sanitize(buffer.String())
+	}
+
+	return buffer.String(), nil
+}
+
+//
+// Passes all links and images of the document through the link hook.
+//
+type markdownLinkTransformer struct {
+	hook func(link *MarkdownLink)
+}
+
+func (transformer *markdownLinkTransformer) Transform(document *ast.Document, reader text.Reader, context parser.Context) {
+	ast.Walk(document, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		switch typed := node.(type) {
+		case *ast.Link:
+			typed.Destination, typed.Title = transformer.apply(node, false, typed.Destination, typed.Title)
+
+		case *ast.Image:
+			typed.Destination, typed.Title = transformer.apply(node, true, typed.Destination, typed.Title)
+		}
+
+		return ast.WalkContinue, nil
+	})
+}
+
+func (transformer *markdownLinkTransformer) apply(node ast.Node, image bool, destination []byte, title []byte) ([]byte, []byte) {
+	link := &MarkdownLink{
+		Image:       image,
+		Destination: string(destination),
+		Title:       string(title),
+		Attributes:  make(map[string]string),
+	}
+
+	transformer.hook(link)
+
+	names := make([]string, 0, len(link.Attributes))
+	for name := range link.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		node.SetAttributeString(name, []byte(link.Attributes[name]))
+	}
+
+	if link.Title == "" {
+		return []byte(link.Destination), nil
+	}
+
+	return []byte(link.Destination), []byte(link.Title)
+}
+
+//
+// Return the Markdown in the body of the node. The body is taken from
+// the source of the template, so that the white space, autolinks such
+// as `<https://example.com>` and HTML that Markdown allows are kept as
+// written, and only the custom tags are replaced by their output.
+// Without a template, the output of the child nodes is used.
+//
+func (evaluator *Evaluator) captureMarkdown(node *lhtml.HtmlNode, model *Model) (string, error) {
+	template := evaluator.template
+	bodyStart, bodyEnd, _, found := template.elementSpan(node)
+	if !found {
+		return evaluator.CaptureNodes(node.Children(), model)
+	}
+
+	// the custom tags of the body, which the parser may have nested
+	// inside the elements it made from autolinks
+	tags := make([]*lhtml.HtmlNode, 0)
+	var findTags func(nodes []*lhtml.HtmlNode)
+	findTags = func(nodes []*lhtml.HtmlNode) {
+		for _, child := range nodes {
+			if child.NodeType != lhtml.ElementNode {
+				continue
+			}
+
+			_, isTag := evaluator.processor.GetCustomTag(child.NodeName())
+			if isTag {
+				tags = append(tags, child)
+				continue
+			}
+
+			findTags(child.Children())
+		}
+	}
+	findTags(node.Children())
+
+	builder := strings.Builder{}
+	offset := bodyStart
+	for _, tag := range tags {
+		start := template.nodes[tag].offset
+		_, _, end, found := template.elementSpan(tag)
+		if !found || start < offset || end > bodyEnd {
+			continue
+		}
+
+		output, err := evaluator.CaptureNodes([]*lhtml.HtmlNode{tag}, model)
+		if err != nil {
+			return "", err
+		}
+
+		builder.WriteString(template.Source[offset:start])
+		builder.WriteString(output)
+		offset = end
+	}
+
+	builder.WriteString(template.Source[offset:bodyEnd])
+	return builder.String(), nil
+}
+
+//
+// Remove the indentation that all lines of the text share, so that
+// Markdown can be indented along with the template around it.
+//
+func dedent(source string) string {
+	lines := strings.Split(source, "\n")
+
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		width := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || width < indent {
+			indent = width
+		}
+	}
+
+	if indent <= 0 {
+		return strings.TrimSpace(source)
+	}
+
+	for index, line := range lines {
+		if len(line) >= indent {
+			lines[index] = line[indent:]
+		} else {
+			lines[index] = strings.TrimLeft(line, " \t")
+		}
+	}
+
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+//
+// Set the options that the `markdown` tag converts Markdown with. A
+// `nil` options uses the defaults.
+//
+func (pageProcessor *HtmlPageProcessor) SetMarkdownOptions(options *MarkdownOptions) {
+	pageProcessor._markdown = options
+}
+
+//
+// Return the Markdown options of this processor, if any.
+//
+func (pageProcessor *HtmlPageProcessor) GetMarkdownOptions() *MarkdownOptions {
+	return pageProcessor._markdown
+}
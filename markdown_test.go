@@ -0,0 +1,119 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	html, err := RenderMarkdown("# Getting started\n\nSome *text* and **more**.", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "<h1 id=\"getting-started\">Getting started</h1>\n<p>Some <em>text</em> and <strong>more</strong>.</p>\n", html)
+
+	html, _ = RenderMarkdown("# Title", &MarkdownOptions{})
+	assert.Equal(t, "<h1>Title</h1>\n", html)
+
+	// embedded HTML
+	source := "Hi <span onclick=\"x()\">there</span>\n\n<script>alert(1)</script>\n"
+
+	html, _ = RenderMarkdown(source, nil)
+	assert.NotContains(t, html, "<span")
+	assert.NotContains(t, html, "<script")
+
+	html, _ = RenderMarkdown(source, &MarkdownOptions{AllowHTML: true})
+	assert.Contains(t, html, `<span onclick="x()">there</span>`)
+	assert.Contains(t, html, "<script>")
+
+	html, err = RenderMarkdown(source+"\n[x](javascript:alert(1)) *a* _b_", &MarkdownOptions{Sanitize: NewMarkdownSanitizePolicy()})
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>Hi there</p>\n\n<p><a rel=\"nofollow\">x</a> <em>a</em> <em>b</em></p>", html)
+}
+
+func TestMarkdownLinkHook(t *testing.T) {
+	options := NewMarkdownOptions()
+	options.LinkHook = func(link *MarkdownLink) {
+		if link.Image {
+			link.Destination = "https://cdn.example.com/" + link.Destination
+			link.Attributes["loading"] = "lazy"
+			return
+		}
+
+		if strings.HasPrefix(link.Destination, "http") {
+			link.Attributes["target"] = "_blank"
+			link.Attributes["rel"] = "noopener"
+		}
+	}
+
+	html, err := RenderMarkdown(`[Site](https://example.com "Home") [About](/about) ![Logo](logo.png)`, options)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p><a href=\"https://example.com\" title=\"Home\" rel=\"noopener\" target=\"_blank\">Site</a> <a href=\"/about\">About</a> <img src=\"https://cdn.example.com/logo.png\" alt=\"Logo\" loading=\"lazy\"></p>\n", html)
+}
+
+func TestMarkdownTag(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "")
+	processor.Import(MarkdownLibrary(), "")
+	processor.SetStrictMode(true)
+
+	model := NewModel()
+	model.Put("post", map[string]interface{}{"body": "## Hello\n\nA <b>bold</b> [link](https://example.com)"})
+	model.Put("name", "Ann")
+
+	html, err := processor.MergeHtml(`<article><markdown var="post.body" /></article>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, "<article><h2 id=\"hello\">Hello</h2>\n<p>A <!-- raw HTML omitted -->bold<!-- raw HTML omitted --> <a href=\"https://example.com\">link</a></p>\n</article>", html)
+
+	html, err = processor.MergeHtml(`<article><markdown var="post.body" policy="markdown" /></article>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, "<article><h2 id=\"hello\">Hello</h2>\n<p>A <b>bold</b> <a href=\"https://example.com\" rel=\"nofollow\">link</a></p></article>", html)
+
+	// body with tags, indented with the template
+	html, err = processor.MergeHtml("<article><markdown>\n    # Hi <get var=\"name\" />\n\n    * one\n    * two\n  </markdown></article>", model)
+	assert.NoError(t, err)
+	assert.Equal(t, "<article><h1 id=\"hi-ann\">Hi Ann</h1>\n<ul>\n<li>one</li>\n<li>two</li>\n</ul>\n</article>", html)
+
+	// white space between tags and autolinks in the body
+	model.Put("a", "first")
+	model.Put("b", "second")
+	html, err = processor.MergeHtml("<article><markdown>\n# Title\n\n<get var=\"a\"/>\n\n<get var=\"b\"/>\n</markdown></article>", model)
+	assert.NoError(t, err)
+	assert.Equal(t, "<article><h1 id=\"title\">Title</h1>\n<p>first</p>\n<p>second</p>\n</article>", html)
+
+	html, err = processor.MergeHtml("<article><markdown>see <https://example.com> and <get var=\"name\" /> &amp; co</markdown></article>", model)
+	assert.NoError(t, err)
+	assert.Equal(t, "<article><p>see <a href=\"https://example.com\">https://example.com</a> and Ann &amp; co</p>\n</article>", html)
+
+	// nested tags and tags with a body
+	html, err = processor.MergeHtml("<div><markdown>\n  <if condition=\"true\"><then>*<get var=\"name\" />*</then></if>\n</markdown><markdown></markdown></div>", model)
+	assert.NoError(t, err)
+	assert.Equal(t, "<div><p><em>Ann</em></p>\n</div>", html)
+
+	processor.SetMarkdownOptions(&MarkdownOptions{AllowHTML: true})
+	assert.True(t, processor.GetMarkdownOptions().AllowHTML)
+
+	html, err = processor.MergeHtml(`<article><markdown var="post.body" /></article>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, "<article><h2>Hello</h2>\n<p>A <b>bold</b> <a href=\"https://example.com\">link</a></p>\n</article>", html)
+
+	_, err = processor.MergeHtml(`<article><markdown var="post.body" policy="missing" /></article>`, model)
+	assert.Error(t, err)
+}
+
+func TestDedent(t *testing.T) {
+	assert.Equal(t, "# A\n\n  b", dedent("\n    # A\n\n      b\n  "))
+	assert.Equal(t, "a\nb", dedent("a\nb"))
+	assert.Equal(t, "", dedent("  \n "))
+}
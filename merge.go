@@ -12,27 +12,87 @@
 package snowmark
 
 import (
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/sangupta/lhtml"
 )
 
+//
+// Options of a single merge.
+//
+type MergeOptions struct {
+	// The locale to render in, such as `pt-BR`. If empty, the `locale`
+	// value of the model is used.
+	Locale string
+
+	// The time zone that dates are formatted in. If `nil`, the
+	// `timeZone` value of the model is used.
+	TimeZone *time.Location
+
+	// The Content Security Policy nonce of the response, which is added
+	// to inline `<script>` and `<style>` elements and is available in
+	// the model as `cspNonce`.
+	Nonce string
+
+	// Called with every error that is skipped, along with its location
+	// in the template, when the processor is not in strict mode.
+	OnWarning func(err error)
+}
+
 //
 // Merge given HTML string with the given model.
 //
 func (pageProcessor *HtmlPageProcessor) MergeHtml(html string, model *Model) (string, error) {
-	elements, err := lhtml.ParseHtmlString(html)
+	template, err := ParseTemplate("", html)
+	if err != nil {
+		return "", err
+	}
+
+	return pageProcessor.MergeTemplate(template, model)
+}
+
+//
+// Merge given HTML string with the given model and options.
+//
+func (pageProcessor *HtmlPageProcessor) MergeHtmlWithOptions(html string, model *Model, options *MergeOptions) (string, error) {
+	template, err := ParseTemplate("", html)
 	if err != nil {
 		return "", err
 	}
 
-	return pageProcessor.Merge(elements, model)
+	return pageProcessor.MergeTemplateWithOptions(template, model, options)
 }
 
 //
 // Merge given parsed HTML document with the given model.
 //
 func (pageProcessor *HtmlPageProcessor) Merge(elements *lhtml.HtmlElements, model *Model) (string, error) {
+	return pageProcessor.merge(elements, nil, model, nil)
+}
+
+//
+// Merge given parsed template with the given model. Errors are
+// reported along with their location in the template.
+//
+func (pageProcessor *HtmlPageProcessor) MergeTemplate(template *Template, model *Model) (string, error) {
+	return pageProcessor.MergeTemplateWithOptions(template, model, nil)
+}
+
+//
+// Merge given parsed template with the given model and options. A
+// `nil` options uses the defaults.
+//
+func (pageProcessor *HtmlPageProcessor) MergeTemplateWithOptions(template *Template, model *Model, options *MergeOptions) (string, error) {
+	if template == nil {
+		return "", errors.New("Template is required to merge")
+	}
+
+	return pageProcessor.merge(template.elements, template, model, options)
+}
+
+func (pageProcessor *HtmlPageProcessor) merge(elements *lhtml.HtmlElements, template *Template, model *Model, options *MergeOptions) (string, error) {
 	if elements.IsEmpty() {
 		return "", nil
 	}
@@ -44,7 +104,30 @@ func (pageProcessor *HtmlPageProcessor) Merge(elements *lhtml.HtmlElements, mode
 		processor: pageProcessor,
 	}
 
-	evaluator.EvaluateNodes(elements.Nodes(), model)
+	evaluator.options = options
+	model = pageProcessor.scopeModel(model, evaluator)
+	evaluator.model = model
+	evaluator.started = time.Now()
+
+	var err error
+	if template != nil {
+		err = evaluator.EvaluateTemplate(template, model, EventTemplate)
+	} else {
+		event := evaluator.BeginEvent(EventTemplate, "", nil)
+		err = evaluator.EvaluateNodes(elements.Nodes(), model)
+		evaluator.EndEvent(event, err)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if pageProcessor._sandbox != nil {
+		err = pageProcessor._sandbox.checkOutput(evaluator.builder.String())
+		if err != nil {
+			return "", err
+		}
+	}
 
 	return evaluator.builder.String(), nil
 }
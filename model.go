@@ -11,14 +11,40 @@
 
 package snowmark
 
-import "github.com/sangupta/berry"
+import (
+	"errors"
+	"reflect"
+
+	"github.com/sangupta/berry"
+)
+
+//
+// Error returned when writing to a model that has been frozen.
+//
+var ErrModelFrozen = errors.New("Model is frozen and cannot be modified")
 
 //
 // Represents a model of values
 // that are used to merge with a page or a fragment.
 //
 type Model struct {
-	_map map[string]interface{}
+	_map     map[string]interface{}
+	_parent  *Model
+	_frozen  bool
+	_version uint64
+	_view    *modelView
+	_origin  *Model
+
+	_evaluator *Evaluator
+}
+
+//
+// A flattened copy of the values of a model and its parents,
+// along with the versions of the models it was built from.
+//
+type modelView struct {
+	values   map[string]interface{}
+	versions []uint64
 }
 
 //
@@ -31,10 +57,10 @@ func NewModel() *Model {
 }
 
 //
-// Return the size of the model.
+// Return the size of the model, including the keys of its parents.
 //
 func (model *Model) Size() int {
-	return len(model._map)
+	return len(model.values())
 }
 
 //
@@ -46,33 +72,94 @@ func (model *Model) IsEmpty() bool {
 }
 
 //
-// Clear model and remove all keys
+// Clear model and remove all keys. The keys of the parents
+// are not removed. Does nothing if the model is frozen.
 //
 func (model *Model) Clear() {
+	if model._frozen {
+		return
+	}
+
 	model._map = make(map[string]interface{})
+	model.changed()
 }
 
 //
-// Return the map associated with this model.
+// Return the map associated with this model. This only contains
+// the values of this model, not those of its parents.
 //
 func (model *Model) GetMap() map[string]interface{} {
 	return model._map
 }
 
 //
-// Get the value from the model.
+// Return a new map with all values of this model and its parents.
+//
+func (model *Model) ToMap() map[string]interface{} {
+	values := make(map[string]interface{})
+	for key, value := range model.values() {
+		values[key] = value
+	}
+
+	return values
+}
+
+//
+// Get the value from the model, or from its parents if the model
+// does not contain the key. Lazy values are computed, and `nil` is
+// returned if that fails. Within a merge, the error fails the tag that
+// reads the value; otherwise use the `Lookup` methods to get the error.
 //
 func (model *Model) Get(key string) (interface{}, bool) {
-	value, exists := model._map[key]
+	value, exists, err := model.lookup(key)
+	if err != nil {
+		model.reportLazyError(&ValueError{Key: key, Err: err})
+	}
+
 	return value, exists
 }
 
+//
+// Get the value from the model or its parents, computing lazy values.
+//
+func (model *Model) lookup(key string) (interface{}, bool, error) {
+	value, exists := model.find(key)
+	if !exists {
+		return nil, false, nil
+	}
+
+	value, err := model.resolveLazy(value)
+	return value, true, err
+}
+
+//
+// Get the value from the model or its parents as it is stored, without
+// computing lazy values.
+//
+func (model *Model) find(key string) (interface{}, bool) {
+	if key == NonceModelKey {
+		nonce, exists := model.mergeNonce()
+		if exists {
+			return nonce, true
+		}
+	}
+
+	for current := model; current != nil; current = current._parent {
+		value, exists := current._map[key]
+		if exists {
+			return value, true
+		}
+	}
+
+	return nil, false
+}
+
 //
 // Get a `string` value for a key from the model, or the default value if
 // the key does not exist, or is not a `string`.
 //
 func (model *Model) GetString(key string, defaultValue string) string {
-	value, exists := model._map[key]
+	value, exists := model.Get(key)
 	if !exists {
 		return defaultValue
 	}
@@ -85,7 +172,7 @@ func (model *Model) GetString(key string, defaultValue string) string {
 // the key does not exist, or is not a `bool`.
 //
 func (model *Model) GetBool(key string, defaultValue bool) bool {
-	value, exists := model._map[key]
+	value, exists := model.Get(key)
 	if !exists {
 		return defaultValue
 	}
@@ -99,7 +186,7 @@ func (model *Model) GetBool(key string, defaultValue bool) bool {
 // the key does not exist, or is not a `uint64`.
 //
 func (model *Model) GetUInt64(key string, defaultValue uint64) uint64 {
-	value, exists := model._map[key]
+	value, exists := model.Get(key)
 	if !exists {
 		return defaultValue
 	}
@@ -113,7 +200,7 @@ func (model *Model) GetUInt64(key string, defaultValue uint64) uint64 {
 // the key does not exist, or is not a `int64`.
 //
 func (model *Model) GetInt64(key string, defaultValue int64) int64 {
-	value, exists := model._map[key]
+	value, exists := model.Get(key)
 	if !exists {
 		return defaultValue
 	}
@@ -127,7 +214,7 @@ func (model *Model) GetInt64(key string, defaultValue int64) int64 {
 // the key does not exist, or is not a `float64`.
 //
 func (model *Model) GetFloat64(key string, defaultValue float64) float64 {
-	value, exists := model._map[key]
+	value, exists := model.Get(key)
 	if !exists {
 		return defaultValue
 	}
@@ -137,10 +224,17 @@ func (model *Model) GetFloat64(key string, defaultValue float64) float64 {
 }
 
 //
-// Put the value in model against given key.
+// Put the value in model against given key. A function of type
+// `func() (interface{}, error)` is stored as a `Lazy` value. Does
+// nothing if the model is frozen.
 //
 func (model *Model) Put(key string, value interface{}) {
-	model._map[key] = value
+	if model._frozen {
+		return
+	}
+
+	model._map[key] = wrapLazy(value)
+	model.changed()
 }
 
 //
@@ -148,12 +242,12 @@ func (model *Model) Put(key string, value interface{}) {
 // `true` if value was added to model, `false` otherwise.
 //
 func (model *Model) PutIfNotExists(key string, value interface{}) bool {
-	_, exists := model._map[key]
-	if exists {
+	_, exists := model.Get(key)
+	if exists || model._frozen {
 		return false
 	}
 
-	model._map[key] = value
+	model.Put(key, value)
 	return true
 }
 
@@ -163,18 +257,324 @@ func (model *Model) PutIfNotExists(key string, value interface{}) bool {
 // replaced.
 //
 func (model *Model) Replace(key string, value interface{}) bool {
-	_, exists := model._map[key]
-	if !exists {
+	_, exists := model.Get(key)
+	if !exists || model._frozen {
 		return false
 	}
 
-	model._map[key] = value
+	model.Put(key, value)
 	return true
 }
 
 //
-// Remove the key from the model, if it exists.
+// Remove the key from the model, if it exists. The value of a
+// parent becomes visible again, if the parent has the same key.
+// Does nothing if the model is frozen.
 //
 func (model *Model) Remove(key string) {
+	if model._frozen {
+		return
+	}
+
 	delete(model._map, key)
+	model.changed()
+}
+
+//
+// Record that the values of the model changed. For a copy of a model
+// that shares its values, the model it was copied from changes too.
+//
+func (model *Model) changed() {
+	model._version++
+	if model._origin != nil {
+		model._origin._map = model._map
+		model._origin.changed()
+	}
+}
+
+//
+// Return the version of the model, which changes whenever the model,
+// or the model it was copied from, changes.
+//
+func (model *Model) version() uint64 {
+	if model._origin == nil {
+		return model._version
+	}
+
+	return model._version + model._origin.version()
+}
+
+//
+// Return the values of the model and its parents in a single map,
+// which is how expressions see the model. For models without parents
+// this is the map of the model itself; otherwise a flattened copy is
+// built and reused until any of the models change.
+//
+func (model *Model) values() map[string]interface{} {
+	if model._parent == nil {
+		return model._map
+	}
+
+	view := model._view
+	if view != nil {
+		index := 0
+		current := model
+		for ; current != nil && index < len(view.versions); current = current._parent {
+			if current.version() != view.versions[index] {
+				break
+			}
+			index++
+		}
+
+		if current == nil && index == len(view.versions) {
+			return view.values
+		}
+	}
+
+	chain := make([]*Model, 0)
+	versions := make([]uint64, 0)
+	for current := model; current != nil; current = current._parent {
+		chain = append(chain, current)
+		versions = append(versions, current.version())
+	}
+
+	values := make(map[string]interface{})
+	for index := len(chain) - 1; index >= 0; index-- {
+		for key, value := range chain[index]._map {
+			values[key] = value
+		}
+	}
+
+	model._view = &modelView{
+		values:   values,
+		versions: versions,
+	}
+	return values
+}
+
+//
+// Enum to define how `Merge` treats keys that exist in both models.
+//
+type MergeStrategy uint32
+
+// Enumeration
+const (
+	// values of the other model replace existing values
+	MergeOverwrite MergeStrategy = iota
+
+	// existing values are kept
+	MergeKeep
+
+	// nested maps and models are merged recursively, and all
+	// other values of the other model replace existing values
+	MergeDeep
+)
+
+//
+// Return a deep copy of the model. Nested maps, slices and models are
+// copied, while structs are copied by value and pointers are shared.
+// The copy has the same parent, and is not frozen.
+//
+func (model *Model) Clone() *Model {
+	clone := NewModel()
+	clone._parent = model._parent
+	for key, value := range model._map {
+		clone._map[key] = deepCopy(value)
+	}
+
+	return clone
+}
+
+//
+// Merge all values of the other model, including those of its parents,
+// into this model using the given strategy. Values are deep copied, so
+// that changes to the other model do not affect this model afterwards.
+//
+func (model *Model) Merge(other *Model, strategy MergeStrategy) error {
+	if other == nil {
+		return errors.New("Model to merge cannot be nil")
+	}
+
+	if model._frozen {
+		return ErrModelFrozen
+	}
+
+	if strategy > MergeDeep {
+		return errors.New("Unknown merge strategy")
+	}
+
+	for key, value := range other.values() {
+		existing, exists := model._map[key]
+		switch {
+		case !exists || strategy == MergeOverwrite:
+			model._map[key] = deepCopy(value)
+
+		case strategy == MergeDeep:
+			model._map[key] = deepMerge(existing, value)
+		}
+	}
+
+	model.changed()
+	return nil
+}
+
+//
+// Layer this model on top of the given parent. Keys missing in this
+// model are read from the parent, without copying its values, while
+// all writes go to this model. Passing `nil` removes the parent.
+//
+//  base := snowmark.NewModel()
+//  base.Put("site", siteConfig)
+//  base.Freeze()
+//
+//  // per request, the shared base stays untouched
+//  page := snowmark.NewModel()
+//  page.Overlay(base)
+//  page.Put("title", "Home")
+//
+func (model *Model) Overlay(parent *Model) error {
+	for current := parent; current != nil; current = current._parent {
+		if current == model {
+			return errors.New("Model cannot overlay itself")
+		}
+	}
+
+	model._parent = parent
+	model._view = nil
+	model._version++
+	return nil
+}
+
+//
+// Return the parent of this model, if any.
+//
+func (model *Model) Parent() *Model {
+	return model._parent
+}
+
+//
+// Make the model read-only. Writes to a frozen model are ignored,
+// `PutPath` and `Merge` return `ErrModelFrozen`, and tags that write
+// to the model, such as `set` and `foreach`, fail with the same error.
+// A frozen model can be safely shared between concurrent merges; use
+// `Overlay` to add values on top of it. Freezing cannot be undone,
+// but `Clone` returns a copy that is not frozen.
+//
+func (model *Model) Freeze() {
+	model._frozen = true
+}
+
+//
+// Return `true` if the model is frozen.
+//
+func (model *Model) IsFrozen() bool {
+	return model._frozen
+}
+
+//
+// Return a deep copy of the value.
+//
+func deepCopy(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(typed))
+		for key, item := range typed {
+			copied[key] = deepCopy(item)
+		}
+		return copied
+
+	case []interface{}:
+		copied := make([]interface{}, len(typed))
+		for index, item := range typed {
+			copied[index] = deepCopy(item)
+		}
+		return copied
+
+	case *Model:
+		if typed == nil {
+			return typed
+		}
+		return typed.Clone()
+	}
+
+	reflected := reflect.ValueOf(value)
+	switch reflected.Kind() {
+	case reflect.Map:
+		if reflected.IsNil() {
+			return value
+		}
+
+		copied := reflect.MakeMapWithSize(reflected.Type(), reflected.Len())
+		iterator := reflected.MapRange()
+		for iterator.Next() {
+			copied.SetMapIndex(iterator.Key(), deepCopyValue(iterator.Value(), reflected.Type().Elem()))
+		}
+		return copied.Interface()
+
+	case reflect.Slice:
+		if reflected.IsNil() {
+			return value
+		}
+
+		copied := reflect.MakeSlice(reflected.Type(), reflected.Len(), reflected.Len())
+		for index := 0; index < reflected.Len(); index++ {
+			copied.Index(index).Set(deepCopyValue(reflected.Index(index), reflected.Type().Elem()))
+		}
+		return copied.Interface()
+	}
+
+	return value
+}
+
+//
+// Deep copy a reflected value, keeping it assignable to the given type.
+//
+func deepCopyValue(value reflect.Value, target reflect.Type) reflect.Value {
+	if !value.IsValid() || (value.Kind() == reflect.Interface && value.IsNil()) {
+		return reflect.Zero(target)
+	}
+
+	copied := reflect.ValueOf(deepCopy(value.Interface()))
+	if !copied.IsValid() {
+		return reflect.Zero(target)
+	}
+
+	return copied
+}
+
+//
+// Merge the value into the existing value: maps and models are merged
+// recursively, all other values replace the existing value.
+//
+func deepMerge(existing interface{}, value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		current, ok := existing.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		merged := deepCopy(current).(map[string]interface{})
+		for key, item := range typed {
+			previous, exists := merged[key]
+			if exists {
+				merged[key] = deepMerge(previous, item)
+			} else {
+				merged[key] = deepCopy(item)
+			}
+		}
+		return merged
+
+	case *Model:
+		current, ok := existing.(*Model)
+		if !ok || current == nil || typed == nil {
+			break
+		}
+
+		merged := current.Clone()
+		merged.Merge(typed, MergeDeep)
+		return merged
+	}
+
+	return deepCopy(value)
 }
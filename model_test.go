@@ -12,6 +12,7 @@
 package snowmark
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -66,3 +67,152 @@ func TestModel(t *testing.T) {
 	assert.Equal(t, float64(2), model.GetFloat64("hello", 0))
 	assert.Equal(t, float64(0), model.GetFloat64("hello-no-exists", 0))
 }
+
+func TestModelClone(t *testing.T) {
+	model := NewModel()
+	model.Put("user", map[string]interface{}{"name": "Jane", "tags": []interface{}{"a"}})
+	model.Put("scores", map[string]int{"x": 1})
+	model.Put("nested", NewModel())
+	model.Freeze()
+
+	clone := model.Clone()
+	assert.False(t, clone.IsFrozen())
+	assert.Equal(t, model.GetMap()["user"], clone.GetMap()["user"])
+
+	assert.NoError(t, clone.PutPath("user.name", "John"))
+	assert.NoError(t, clone.PutPath("user.tags[0]", "b"))
+	assert.NoError(t, clone.PutPath("scores.x", 2))
+	assert.NoError(t, clone.PutPath("nested.key", "value"))
+
+	assert.Equal(t, "Jane", model.GetPathString("user.name", ""))
+	assert.Equal(t, "a", model.GetPathString("user.tags[0]", ""))
+	assert.Equal(t, int64(1), model.GetPathInt64("scores.x", 0))
+	assert.False(t, model.HasPath("nested.key"))
+}
+
+func TestModelMerge(t *testing.T) {
+	newBase := func() *Model {
+		model := NewModel()
+		model.Put("title", "Base")
+		model.Put("site", map[string]interface{}{"name": "Site", "theme": map[string]interface{}{"color": "red", "font": "serif"}})
+		return model
+	}
+
+	other := NewModel()
+	other.Put("title", "Page")
+	other.Put("author", "Jane")
+	other.Put("site", map[string]interface{}{"theme": map[string]interface{}{"color": "blue"}})
+
+	model := newBase()
+	assert.NoError(t, model.Merge(other, MergeOverwrite))
+	assert.Equal(t, "Page", model.GetString("title", ""))
+	assert.Equal(t, "Jane", model.GetString("author", ""))
+	assert.False(t, model.HasPath("site.name"))
+
+	model = newBase()
+	assert.NoError(t, model.Merge(other, MergeKeep))
+	assert.Equal(t, "Base", model.GetString("title", ""))
+	assert.Equal(t, "Jane", model.GetString("author", ""))
+	assert.Equal(t, "red", model.GetPathString("site.theme.color", ""))
+
+	model = newBase()
+	assert.NoError(t, model.Merge(other, MergeDeep))
+	assert.Equal(t, "Page", model.GetString("title", ""))
+	assert.Equal(t, "Site", model.GetPathString("site.name", ""))
+	assert.Equal(t, "blue", model.GetPathString("site.theme.color", ""))
+	assert.Equal(t, "serif", model.GetPathString("site.theme.font", ""))
+
+	// merged values are copies
+	other.PutPath("site.theme.color", "green")
+	assert.Equal(t, "blue", model.GetPathString("site.theme.color", ""))
+
+	assert.Error(t, model.Merge(nil, MergeDeep))
+	assert.Error(t, model.Merge(other, MergeStrategy(9)))
+
+	model.Freeze()
+	assert.Equal(t, ErrModelFrozen, model.Merge(other, MergeOverwrite))
+}
+
+func TestModelOverlay(t *testing.T) {
+	base := NewModel()
+	base.Put("site", map[string]interface{}{"name": "Site"})
+	base.Put("title", "Base")
+	base.Freeze()
+
+	page := NewModel()
+	assert.NoError(t, page.Overlay(base))
+	assert.Same(t, base, page.Parent())
+
+	assert.Equal(t, "Base", page.GetString("title", ""))
+	assert.Equal(t, "Site", page.GetPathString("site.name", ""))
+	assert.Equal(t, 2, page.Size())
+	assert.Equal(t, 0, len(page.GetMap()))
+
+	page.Put("title", "Page")
+	assert.Equal(t, "Page", page.GetString("title", ""))
+	assert.Equal(t, "Base", base.GetString("title", ""))
+	assert.False(t, page.PutIfNotExists("site", nil))
+	assert.True(t, page.Replace("site", map[string]interface{}{"name": "Other"}))
+	page.Remove("site")
+	assert.Equal(t, "Site", page.GetPathString("site.name", ""))
+
+	// nested values of the parent are copied before changing them
+	assert.NoError(t, page.PutPath("site.name", "Changed"))
+	assert.Equal(t, "Changed", page.GetPathString("site.name", ""))
+	assert.Equal(t, "Site", base.GetPathString("site.name", ""))
+
+	assert.Equal(t, map[string]interface{}{"title": "Page", "site": map[string]interface{}{"name": "Changed"}}, page.ToMap())
+
+	// expressions see the parent values, and changes to any of the models
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "")
+
+	top := NewModel()
+	top.Overlay(page)
+	html, err := processor.MergeHtml("<p><get var='title + \"/\" + site.name' /></p>", top)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>Page/Changed</p>", html)
+
+	page.Put("title", "Again")
+	html, _ = processor.MergeHtml("<p><get var='title' /></p>", top)
+	assert.Equal(t, "<p>Again</p>", html)
+
+	assert.Error(t, base.Overlay(top))
+	assert.Error(t, page.Overlay(page))
+	assert.NoError(t, page.Overlay(nil))
+	assert.Nil(t, page.Parent())
+}
+
+func TestModelFreeze(t *testing.T) {
+	model := NewModel()
+	model.Put("items", []int{1, 2})
+	model.Freeze()
+	assert.True(t, model.IsFrozen())
+
+	model.Put("hello", "world")
+	assert.False(t, model.PutIfNotExists("hello", "world"))
+	assert.False(t, model.Replace("items", nil))
+	model.Remove("items")
+	model.Clear()
+	assert.Equal(t, 1, model.Size())
+	assert.Equal(t, ErrModelFrozen, model.PutPath("a.b", 1))
+	assert.False(t, model.RemovePath("items[0]"))
+
+	// tags that write to the model fail
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "")
+	processor.SetStrictMode(true)
+
+	_, err := processor.MergeHtml("<set var='a' value='b' />", model)
+	assert.True(t, errors.Is(err, ErrModelFrozen))
+
+	_, err = processor.MergeHtml("<foreach collection='items' var='i'><get var='i' /></foreach>", model)
+	assert.True(t, errors.Is(err, ErrModelFrozen))
+
+	// but work on an overlay
+	page := NewModel()
+	page.Overlay(model)
+	html, err := processor.MergeHtml("<p><foreach collection='items' var='i'><get var='i' /></foreach></p>", page)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>12</p>", html)
+}
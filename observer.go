@@ -0,0 +1,180 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"errors"
+	"time"
+
+	"github.com/sangupta/lhtml"
+)
+
+//
+// Enum to define the kind of a render event.
+//
+type EventKind uint32
+
+// Enumeration
+const (
+	EventTemplate EventKind = iota
+	EventTag
+	EventExpression
+	EventInclude
+	EventLoopIteration
+)
+
+//
+// Return the event kind as a lower-case string.
+//
+func (kind EventKind) String() string {
+	switch kind {
+	case EventTemplate:
+		return "template"
+
+	case EventTag:
+		return "tag"
+
+	case EventExpression:
+		return "expression"
+
+	case EventInclude:
+		return "include"
+
+	case EventLoopIteration:
+		return "loop"
+	}
+
+	return "unknown"
+}
+
+//
+// An event raised while merging a template. The same instance is
+// passed to `BeginEvent` and `EndEvent`; `Duration` and `Err` are
+// only available when the event ends. `Name` is the name of the
+// template, the tag, the expression, the included template or the
+// loop variable, depending on the kind of event.
+//
+type RenderEvent struct {
+	Kind     EventKind
+	Name     string
+	Template string
+	Position Position
+	Parent   *RenderEvent
+	Start    time.Time
+	Duration time.Duration
+	Err      error
+	children time.Duration
+}
+
+//
+// Return the time spent in this event excluding the time spent
+// in its child events.
+//
+func (event *RenderEvent) SelfDuration() time.Duration {
+	return event.Duration - event.children
+}
+
+//
+// An observer receives an event when the processing of a template,
+// custom tag, expression, include or loop iteration begins and ends.
+// Observers are called synchronously on the goroutine that merges the
+// template, and must be safe for concurrent use if the processor is
+// used concurrently.
+//
+type RenderObserver interface {
+	BeginEvent(event *RenderEvent)
+	EndEvent(event *RenderEvent)
+}
+
+//
+// Add an observer that receives render events from every merge.
+//
+func (pageProcessor *HtmlPageProcessor) AddObserver(observer RenderObserver) error {
+	if observer == nil {
+		return errors.New("Observer cannot be nil")
+	}
+
+	pageProcessor._observers = append(pageProcessor._observers, observer)
+	return nil
+}
+
+//
+// Remove a previously added observer. Returns `true` if the
+// observer was found.
+//
+func (pageProcessor *HtmlPageProcessor) RemoveObserver(observer RenderObserver) bool {
+	for index, existing := range pageProcessor._observers {
+		if existing == observer {
+			pageProcessor._observers = append(pageProcessor._observers[:index:index], pageProcessor._observers[index+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+//
+// Begin a render event for the given node and notify all observers.
+// Custom tags can use this to report their own events. Returns `nil`
+// if there are no observers, in which case `EndEvent` does nothing.
+//
+func (evaluator *Evaluator) BeginEvent(kind EventKind, name string, node *lhtml.HtmlNode) *RenderEvent {
+	if evaluator.processor == nil || len(evaluator.processor._observers) == 0 {
+		return nil
+	}
+
+	event := &RenderEvent{
+		Kind:     kind,
+		Name:     name,
+		Template: evaluator.templateName(),
+		Position: evaluator.template.PositionOf(node),
+		Parent:   evaluator.event,
+	}
+
+	evaluator.event = event
+	for _, observer := range evaluator.processor._observers {
+		observer.BeginEvent(event)
+	}
+
+	// start the clock after the observers are done
+	event.Start = time.Now()
+	return event
+}
+
+//
+// End the given render event with the given error, and notify
+// all observers.
+//
+func (evaluator *Evaluator) EndEvent(event *RenderEvent, err error) {
+	if event == nil {
+		return
+	}
+
+	event.Duration = time.Since(event.Start)
+	event.Err = err
+	if event.Parent != nil {
+		event.Parent.children += event.Duration
+	}
+
+	evaluator.event = event.Parent
+	for _, observer := range evaluator.processor._observers {
+		observer.EndEvent(event)
+	}
+}
+
+func (evaluator *Evaluator) templateName() string {
+	if evaluator.template == nil {
+		return ""
+	}
+
+	return evaluator.template.Name
+}
@@ -0,0 +1,102 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingObserver struct {
+	events []string
+	ended  []*RenderEvent
+}
+
+func (observer *recordingObserver) BeginEvent(event *RenderEvent) {
+	observer.events = append(observer.events, "begin "+event.Kind.String()+" "+event.Name)
+}
+
+func (observer *recordingObserver) EndEvent(event *RenderEvent) {
+	observer.events = append(observer.events, "end "+event.Kind.String()+" "+event.Name)
+	observer.ended = append(observer.ended, event)
+}
+
+func TestRenderObserver(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "c")
+
+	observer := &recordingObserver{}
+	assert.NoError(t, processor.AddObserver(observer))
+	assert.Error(t, processor.AddObserver(nil))
+
+	model := NewModel()
+	model.Put("items", []int{1, 2})
+
+	template, _ := ParseTemplate("list.html", "<ul>\n<c:foreach collection='items' var='i'><li><c:get var='i' /></li></c:foreach></ul>")
+	html, err := processor.MergeTemplate(template, model)
+	assert.NoError(t, err)
+	assert.Equal(t, "<ul><li>1</li><li>2</li></ul>", html)
+
+	assert.Equal(t, []string{
+		"begin template list.html",
+		"begin tag c:foreach",
+		"begin expression items",
+		"end expression items",
+		"begin loop i",
+		"begin tag c:get",
+		"begin expression i",
+		"end expression i",
+		"end tag c:get",
+		"end loop i",
+		"begin loop i",
+		"begin tag c:get",
+		"begin expression i",
+		"end expression i",
+		"end tag c:get",
+		"end loop i",
+		"end tag c:foreach",
+		"end template list.html",
+	}, observer.events)
+
+	// check nesting and positions
+	foreach := observer.ended[len(observer.ended)-2]
+	assert.Equal(t, Position{File: "list.html", Line: 2, Column: 1}, foreach.Position)
+	assert.Equal(t, "list.html", foreach.Template)
+	assert.Equal(t, observer.ended[len(observer.ended)-1], foreach.Parent)
+	assert.True(t, foreach.SelfDuration() <= foreach.Duration)
+
+	// errors are reported on the event, even in lenient mode
+	observer.events = nil
+	observer.ended = nil
+	_, err = processor.MergeHtml("<c:get var='missing' />", model)
+	assert.NoError(t, err)
+	assert.Error(t, observer.ended[0].Err)
+	assert.Error(t, observer.ended[1].Err)
+	assert.Equal(t, Position{Line: 1, Column: 13}, observer.ended[1].Err.(*TemplateError).Position)
+
+	assert.True(t, processor.RemoveObserver(observer))
+	assert.False(t, processor.RemoveObserver(observer))
+
+	observer.events = nil
+	processor.MergeHtml("<c:get var='1' />", model)
+	assert.Nil(t, observer.events)
+}
+
+func TestEventKindString(t *testing.T) {
+	assert.Equal(t, "template", EventTemplate.String())
+	assert.Equal(t, "tag", EventTag.String())
+	assert.Equal(t, "expression", EventExpression.String())
+	assert.Equal(t, "include", EventInclude.String())
+	assert.Equal(t, "loop", EventLoopIteration.String())
+	assert.Equal(t, "unknown", EventKind(100).String())
+}
@@ -0,0 +1,641 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/sangupta/berry"
+)
+
+//
+// A single step of a path: either the key of a map or field of
+// a struct, or the index into a slice.
+//
+type pathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+func (segment pathSegment) String() string {
+	if segment.isIndex {
+		return "[" + strconv.Itoa(segment.index) + "]"
+	}
+
+	return segment.key
+}
+
+//
+// Get the value at the given path, such as `user.address.city` or
+// `items[2].name`. Paths traverse nested maps, slices, arrays, structs
+// and models. Map keys that are not valid names can be written as
+// `headers["user-agent"]`. Returns `false` if any part of the path does
+// not exist, or if the path is invalid.
+//
+func (model *Model) GetPath(path string) (interface{}, bool) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return nil, false
+	}
+
+	current, exists := model.Get(segments[0].key)
+	if !exists {
+		return nil, false
+	}
+
+	for _, segment := range segments[1:] {
+		child, exists := getChild(current, segment)
+		if !exists {
+			return nil, false
+		}
+
+		child, err = model.resolveLazy(child)
+		if err != nil {
+			model.reportLazyError(&ValueError{Key: path, Err: err})
+			return nil, false
+		}
+
+		current = child
+	}
+
+	return current, true
+}
+
+//
+// Check if a value exists at the given path.
+//
+func (model *Model) HasPath(path string) bool {
+	_, exists := model.GetPath(path)
+	return exists
+}
+
+//
+// Put the value at the given path. Maps are created for all parts of
+// the path that do not exist yet. Slices can be appended to by using
+// the index just past their end, such as `items[3]` for a slice of
+// three items. An error is returned if the path is invalid, or cannot
+// be written to, such as an index out of range or an unexported field.
+//
+func (model *Model) PutPath(path string, value interface{}) error {
+	segments, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+
+	if model._frozen {
+		return ErrModelFrozen
+	}
+
+	// copy nested values of parents before changing them
+	key := segments[0].key
+	if _, exists := model._map[key]; !exists && len(segments) > 1 {
+		inherited, exists := model.Get(key)
+		if exists {
+			model._map[key] = deepCopy(inherited)
+		}
+	}
+
+	_, err = putChild(model._map, segments, value)
+	model.changed()
+	return err
+}
+
+//
+// Remove the value at the given path: the key of a map, or the item
+// of a slice. Returns `true` if a value was removed.
+//
+func (model *Model) RemovePath(path string) bool {
+	segments, err := parsePath(path)
+	if err != nil || model._frozen {
+		return false
+	}
+
+	_, removed := removeChild(model._map, segments)
+	model.changed()
+	return removed
+}
+
+//
+// Get a `string` value at the given path, or the default value if the
+// path does not exist.
+//
+func (model *Model) GetPathString(path string, defaultValue string) string {
+	value, exists := model.GetPath(path)
+	if !exists {
+		return defaultValue
+	}
+
+	return berry.ConvertToString(value)
+}
+
+//
+// Get a `bool` value at the given path, or the default value if the
+// path does not exist.
+//
+func (model *Model) GetPathBool(path string, defaultValue bool) bool {
+	value, exists := model.GetPath(path)
+	if !exists {
+		return defaultValue
+	}
+
+	b, _ := berry.ConvertToBool(value)
+	return b
+}
+
+//
+// Get a `uint64` value at the given path, or the default value if the
+// path does not exist, or is not a number.
+//
+func (model *Model) GetPathUInt64(path string, defaultValue uint64) uint64 {
+	value, exists := model.GetPath(path)
+	if !exists {
+		return defaultValue
+	}
+
+	b, _ := berry.ConvertToUint64(value, defaultValue)
+	return b
+}
+
+//
+// Get a `int64` value at the given path, or the default value if the
+// path does not exist, or is not a number.
+//
+func (model *Model) GetPathInt64(path string, defaultValue int64) int64 {
+	value, exists := model.GetPath(path)
+	if !exists {
+		return defaultValue
+	}
+
+	b, _ := berry.ConvertToInt64(value, defaultValue)
+	return b
+}
+
+//
+// Get a `float64` value at the given path, or the default value if the
+// path does not exist, or is not a number.
+//
+func (model *Model) GetPathFloat64(path string, defaultValue float64) float64 {
+	value, exists := model.GetPath(path)
+	if !exists {
+		return defaultValue
+	}
+
+	b, _ := berry.ConvertToFloat64(value, defaultValue)
+	return b
+}
+
+//
+// Parse a path such as `items[2].name` or `headers["user-agent"]`
+// into its segments. The path must start with a key.
+//
+func parsePath(path string) ([]pathSegment, error) {
+	invalid := func(message string) error {
+		return errors.New("Invalid path '" + path + "': " + message)
+	}
+
+	segments := make([]pathSegment, 0)
+	position := 0
+	expectKey := true
+
+	for position < len(path) {
+		switch {
+		case path[position] == '[':
+			if len(segments) == 0 {
+				return nil, invalid("must start with a key")
+			}
+
+			end := strings.IndexByte(path[position:], ']')
+			if end < 0 {
+				return nil, invalid("missing ]")
+			}
+
+			inner := strings.TrimSpace(path[position+1 : position+end])
+			position += end + 1
+
+			if len(inner) >= 2 && (inner[0] == '"' || inner[0] == '\'') && inner[len(inner)-1] == inner[0] {
+				segments = append(segments, pathSegment{key: inner[1 : len(inner)-1]})
+				continue
+			}
+
+			index, err := strconv.Atoi(inner)
+			if err != nil || index < 0 {
+				return nil, invalid("index must be a non-negative number")
+			}
+
+			segments = append(segments, pathSegment{index: index, isIndex: true})
+			expectKey = false
+
+		case path[position] == '.':
+			if expectKey {
+				return nil, invalid("empty key")
+			}
+
+			position++
+			expectKey = true
+			if position == len(path) {
+				return nil, invalid("empty key")
+			}
+
+		default:
+			if !expectKey {
+				return nil, invalid("missing .")
+			}
+
+			end := strings.IndexAny(path[position:], ".[")
+			if end < 0 {
+				end = len(path) - position
+			}
+
+			segments = append(segments, pathSegment{key: path[position : position+end]})
+			position += end
+			expectKey = false
+		}
+	}
+
+	if len(segments) == 0 {
+		return nil, invalid("empty key")
+	}
+
+	return segments, nil
+}
+
+//
+// Return the child of the container for the given segment.
+//
+func getChild(container interface{}, segment pathSegment) (interface{}, bool) {
+	switch typed := container.(type) {
+	case map[string]interface{}:
+		if segment.isIndex {
+			return nil, false
+		}
+
+		value, exists := typed[segment.key]
+		return value, exists
+
+	case []interface{}:
+		if !segment.isIndex || segment.index >= len(typed) {
+			return nil, false
+		}
+
+		return typed[segment.index], true
+
+	case *Model:
+		if typed == nil {
+			return nil, false
+		}
+
+		if segment.isIndex {
+			return nil, false
+		}
+
+		return typed.find(segment.key)
+	}
+
+	value := reflect.ValueOf(container)
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil, false
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		if !segment.isIndex || segment.index >= value.Len() {
+			return nil, false
+		}
+
+		return value.Index(segment.index).Interface(), true
+
+	case reflect.Map:
+		key, ok := mapKey(value, segment)
+		if !ok {
+			return nil, false
+		}
+
+		child := value.MapIndex(key)
+		if !child.IsValid() {
+			return nil, false
+		}
+
+		return child.Interface(), true
+
+	case reflect.Struct:
+		if segment.isIndex {
+			return nil, false
+		}
+
+		field, ok := structField(value, segment.key)
+		if !ok {
+			return nil, false
+		}
+
+		return field.Interface(), true
+	}
+
+	return nil, false
+}
+
+//
+// Put the value at the path inside the container, and return the
+// container, which is a new value if the container was `nil`, a slice
+// that was appended to, or a struct that was copied.
+//
+func putChild(container interface{}, segments []pathSegment, value interface{}) (interface{}, error) {
+	segment := segments[0]
+	if len(segments) > 1 {
+		child, exists := getChild(container, segment)
+		if !exists {
+			child = nil
+		}
+
+		updated, err := putChild(child, segments[1:], value)
+		if err != nil {
+			return nil, err
+		}
+
+		value = updated
+	}
+
+	return setChild(container, segment, value)
+}
+
+//
+// Set the child of the container for the given segment, and return
+// the container.
+//
+func setChild(container interface{}, segment pathSegment, value interface{}) (interface{}, error) {
+	switch typed := container.(type) {
+	case nil:
+		if segment.isIndex {
+			return nil, errors.New("Cannot create a list for " + segment.String())
+		}
+
+		return map[string]interface{}{segment.key: value}, nil
+
+	case map[string]interface{}:
+		if segment.isIndex {
+			break
+		}
+
+		typed[segment.key] = value
+		return typed, nil
+
+	case []interface{}:
+		if !segment.isIndex {
+			break
+		}
+
+		if segment.index < len(typed) {
+			typed[segment.index] = value
+			return typed, nil
+		}
+
+		if segment.index == len(typed) {
+			return append(typed, value), nil
+		}
+
+		return nil, errors.New("Index out of range: " + segment.String())
+
+	case *Model:
+		if typed == nil {
+			break
+		}
+
+		_, err := setChild(typed._map, segment, value)
+		return typed, err
+	}
+
+	reflected := reflect.ValueOf(container)
+	target := reflected
+	for target.Kind() == reflect.Ptr || target.Kind() == reflect.Interface {
+		if target.IsNil() {
+			return nil, cannotSetError(container, segment)
+		}
+		target = target.Elem()
+	}
+
+	switch target.Kind() {
+	case reflect.Map:
+		key, ok := mapKey(target, segment)
+		if !ok {
+			break
+		}
+
+		item, ok := assignableValue(value, target.Type().Elem())
+		if !ok {
+			break
+		}
+
+		target.SetMapIndex(key, item)
+		return container, nil
+
+	case reflect.Slice, reflect.Array:
+		if !segment.isIndex {
+			break
+		}
+
+		item, ok := assignableValue(value, target.Type().Elem())
+		if !ok {
+			break
+		}
+
+		if segment.index < target.Len() && target.Index(segment.index).CanSet() {
+			target.Index(segment.index).Set(item)
+			return container, nil
+		}
+
+		if target.Kind() == reflect.Slice && segment.index == target.Len() && reflected.Kind() == reflect.Slice {
+			return reflect.Append(target, item).Interface(), nil
+		}
+
+		return nil, errors.New("Index out of range: " + segment.String())
+
+	case reflect.Struct:
+		if segment.isIndex {
+			break
+		}
+
+		if !target.CanAddr() {
+			// struct values are copied and set back into their container
+			copied := reflect.New(target.Type()).Elem()
+			copied.Set(target)
+			target = copied
+			container = nil
+		}
+
+		field, ok := structField(target, segment.key)
+		if !ok || !field.CanSet() {
+			break
+		}
+
+		item, ok := assignableValue(value, field.Type())
+		if !ok {
+			break
+		}
+
+		field.Set(item)
+		if container == nil {
+			return target.Interface(), nil
+		}
+
+		return container, nil
+	}
+
+	return nil, cannotSetError(container, segment)
+}
+
+//
+// Remove the value at the path inside the container, and return
+// the container, which is a new value if an item was removed from
+// a slice.
+//
+func removeChild(container interface{}, segments []pathSegment) (interface{}, bool) {
+	segment := segments[0]
+	if len(segments) > 1 {
+		child, exists := getChild(container, segment)
+		if !exists {
+			return container, false
+		}
+
+		updated, removed := removeChild(child, segments[1:])
+		if !removed {
+			return container, false
+		}
+
+		updatedContainer, err := setChild(container, segment, updated)
+		if err != nil {
+			return container, false
+		}
+
+		return updatedContainer, true
+	}
+
+	switch typed := container.(type) {
+	case map[string]interface{}:
+		_, exists := typed[segment.key]
+		if segment.isIndex || !exists {
+			return container, false
+		}
+
+		delete(typed, segment.key)
+		return typed, true
+
+	case []interface{}:
+		if !segment.isIndex || segment.index >= len(typed) {
+			return container, false
+		}
+
+		return append(typed[:segment.index:segment.index], typed[segment.index+1:]...), true
+
+	case *Model:
+		if typed == nil {
+			return container, false
+		}
+
+		_, removed := removeChild(typed._map, segments)
+		return typed, removed
+	}
+
+	value := reflect.ValueOf(container)
+	switch value.Kind() {
+	case reflect.Map:
+		key, ok := mapKey(value, segment)
+		if !ok || !value.MapIndex(key).IsValid() {
+			return container, false
+		}
+
+		value.SetMapIndex(key, reflect.Value{})
+		return container, true
+
+	case reflect.Slice:
+		if !segment.isIndex || segment.index >= value.Len() {
+			return container, false
+		}
+
+		remaining := reflect.MakeSlice(value.Type(), 0, value.Len()-1)
+		remaining = reflect.AppendSlice(remaining, value.Slice(0, segment.index))
+		remaining = reflect.AppendSlice(remaining, value.Slice(segment.index+1, value.Len()))
+		return remaining.Interface(), true
+	}
+
+	return container, false
+}
+
+//
+// Return the key for the map with string keys, if the segment is a key.
+//
+func mapKey(value reflect.Value, segment pathSegment) (reflect.Value, bool) {
+	keyType := value.Type().Key()
+	if segment.isIndex || keyType.Kind() != reflect.String {
+		return reflect.Value{}, false
+	}
+
+	return reflect.ValueOf(segment.key).Convert(keyType), true
+}
+
+//
+// Find the exported field of the struct by its name, or by its
+// `json` tag. A field promoted through a `nil` embedded pointer is not
+// found.
+//
+func structField(value reflect.Value, name string) (reflect.Value, bool) {
+	structType := value.Type()
+	field, exists := structType.FieldByName(name)
+	if exists && field.IsExported() {
+		fieldValue, err := value.FieldByIndexErr(field.Index)
+		if err != nil {
+			return reflect.Value{}, false
+		}
+
+		return fieldValue, true
+	}
+
+	for index := 0; index < structType.NumField(); index++ {
+		field := structType.Field(index)
+		if field.IsExported() && strings.Split(field.Tag.Get("json"), ",")[0] == name {
+			return value.Field(index), true
+		}
+	}
+
+	return reflect.Value{}, false
+}
+
+//
+// Return the value in a form that can be assigned to the given type.
+//
+func assignableValue(value interface{}, target reflect.Type) (reflect.Value, bool) {
+	if value == nil {
+		switch target.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+			return reflect.Zero(target), true
+		}
+
+		return reflect.Value{}, false
+	}
+
+	reflected := reflect.ValueOf(value)
+	if reflected.Type().AssignableTo(target) {
+		return reflected, true
+	}
+
+	return reflect.Value{}, false
+}
+
+func cannotSetError(container interface{}, segment pathSegment) error {
+	return errors.New(fmt.Sprintf("Cannot set %s on value of type %T", segment.String(), container))
+}
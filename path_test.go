@@ -0,0 +1,175 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type pathAddress struct {
+	City string `json:"city"`
+	Zip  string
+}
+
+type pathUser struct {
+	Name    string
+	Address *pathAddress `json:"address"`
+	Tags    []string
+	secret  string
+}
+
+type pathProfile struct {
+	Bio string
+}
+
+type pathAuthor struct {
+	*pathProfile
+	Title string
+}
+
+func newPathModel() *Model {
+	model := NewModel()
+	model.Put("user", &pathUser{
+		Name:    "Jane",
+		Address: &pathAddress{City: "Delhi", Zip: "110001"},
+		Tags:    []string{"admin", "editor"},
+		secret:  "hidden",
+	})
+	model.Put("items", []interface{}{
+		map[string]interface{}{"name": "one", "price": 10},
+		map[string]interface{}{"name": "two", "price": 20.5},
+	})
+	model.Put("headers", map[string]string{"user-agent": "tester"})
+	model.Put("nested", NewModel())
+	return model
+}
+
+func TestParsePath(t *testing.T) {
+	segments, err := parsePath(`items[2].name["first"]`)
+	assert.NoError(t, err)
+	assert.Equal(t, []pathSegment{{key: "items"}, {index: 2, isIndex: true}, {key: "name"}, {key: "first"}}, segments)
+
+	for _, path := range []string{"", ".a", "a.", "a..b", "[0]", "a[", "a[-1]", "a[x]", "a[0]b"} {
+		_, err := parsePath(path)
+		assert.Error(t, err, path)
+	}
+}
+
+func TestModelGetPath(t *testing.T) {
+	model := newPathModel()
+
+	value, exists := model.GetPath("user.address.city")
+	assert.True(t, exists)
+	assert.Equal(t, "Delhi", value)
+
+	assert.Equal(t, "110001", model.GetPathString("user.address.Zip", ""))
+	assert.Equal(t, "editor", model.GetPathString("user.Tags[1]", ""))
+	assert.Equal(t, "two", model.GetPathString("items[1].name", ""))
+	assert.Equal(t, "tester", model.GetPathString(`headers["user-agent"]`, ""))
+	assert.Equal(t, int64(10), model.GetPathInt64("items[0].price", 0))
+	assert.Equal(t, uint64(10), model.GetPathUInt64("items[0].price", 0))
+	assert.Equal(t, 20.5, model.GetPathFloat64("items[1].price", 0))
+	assert.Equal(t, false, model.GetPathBool("items[9].flag", false))
+
+	assert.True(t, model.HasPath("user.Name"))
+	assert.False(t, model.HasPath("user.secret"))
+	assert.False(t, model.HasPath("items[2]"))
+	assert.False(t, model.HasPath("items.name"))
+	assert.False(t, model.HasPath("user.address.city.more"))
+	assert.False(t, model.HasPath("a..b"))
+	assert.Equal(t, "default", model.GetPathString("missing", "default"))
+}
+
+func TestModelPutPath(t *testing.T) {
+	model := newPathModel()
+
+	// intermediate maps are created
+	assert.NoError(t, model.PutPath("site.meta.title", "Hello"))
+	assert.Equal(t, "Hello", model.GetPathString("site.meta.title", ""))
+
+	assert.NoError(t, model.PutPath("site.meta.author", "Jane"))
+	meta, _ := model.GetPath("site.meta")
+	assert.Equal(t, map[string]interface{}{"title": "Hello", "author": "Jane"}, meta)
+
+	// slices, structs and typed maps
+	assert.NoError(t, model.PutPath("items[0].name", "first"))
+	assert.Equal(t, "first", model.GetPathString("items[0].name", ""))
+
+	assert.NoError(t, model.PutPath("items[2]", "third"))
+	assert.Equal(t, "third", model.GetPathString("items[2]", ""))
+	assert.Error(t, model.PutPath("items[9]", "tenth"))
+
+	assert.NoError(t, model.PutPath("user.address.city", "Mumbai"))
+	assert.Equal(t, "Mumbai", model.GetPathString("user.address.city", ""))
+
+	assert.NoError(t, model.PutPath("user.Tags[0]", "owner"))
+	assert.Equal(t, "owner", model.GetPathString("user.Tags[0]", ""))
+
+	assert.NoError(t, model.PutPath(`headers["accept"]`, "text/html"))
+	assert.Equal(t, "text/html", model.GetPathString("headers.accept", ""))
+
+	assert.NoError(t, model.PutPath("nested.key", "value"))
+	assert.Equal(t, "value", model.GetPathString("nested.key", ""))
+
+	// wrong types and unexported fields
+	assert.Error(t, model.PutPath("user.Name.first", "x"))
+	assert.Error(t, model.PutPath("user.secret", "x"))
+	assert.Error(t, model.PutPath("user.Address", 42))
+	assert.Error(t, model.PutPath("list[0]", "x"))
+	assert.Error(t, model.PutPath("a..b", "x"))
+
+	// struct values inside maps are copied and set back
+	model.Put("address", pathAddress{City: "Pune"})
+	assert.NoError(t, model.PutPath("address.city", "Goa"))
+	assert.Equal(t, "Goa", model.GetPathString("address.city", ""))
+}
+
+func TestModelPathNilEmbedded(t *testing.T) {
+	model := NewModel()
+	model.Put("author", pathAuthor{Title: "Dr"})
+
+	// fields promoted through a nil embedded pointer are not found
+	assert.Equal(t, "Dr", model.GetPathString("author.Title", ""))
+	assert.False(t, model.HasPath("author.Bio"))
+	assert.Error(t, model.PutPath("author.Bio", "x"))
+
+	model.Put("author", pathAuthor{pathProfile: &pathProfile{Bio: "Writer"}})
+	assert.Equal(t, "Writer", model.GetPathString("author.Bio", ""))
+	assert.NoError(t, model.PutPath("author.Bio", "Poet"))
+	assert.Equal(t, "Poet", model.GetPathString("author.Bio", ""))
+}
+
+func TestModelRemovePath(t *testing.T) {
+	model := newPathModel()
+
+	assert.True(t, model.RemovePath("items[0]"))
+	assert.Equal(t, "two", model.GetPathString("items[0].name", ""))
+	assert.False(t, model.HasPath("items[1]"))
+
+	assert.True(t, model.RemovePath("items[0].price"))
+	assert.False(t, model.HasPath("items[0].price"))
+
+	assert.True(t, model.RemovePath("user.Tags[0]"))
+	assert.Equal(t, "editor", model.GetPathString("user.Tags[0]", ""))
+
+	assert.True(t, model.RemovePath(`headers["user-agent"]`))
+	assert.False(t, model.HasPath(`headers["user-agent"]`))
+
+	assert.True(t, model.RemovePath("user"))
+	assert.False(t, model.HasPath("user"))
+
+	assert.False(t, model.RemovePath("missing.key"))
+	assert.False(t, model.RemovePath("items[5]"))
+	assert.False(t, model.RemovePath("a..b"))
+}
@@ -0,0 +1,391 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/sangupta/berry"
+)
+
+//
+// The CLDR plural categories.
+//
+const (
+	PluralZero  = "zero"
+	PluralOne   = "one"
+	PluralTwo   = "two"
+	PluralFew   = "few"
+	PluralMany  = "many"
+	PluralOther = "other"
+)
+
+//
+// The operands of a number that CLDR plural rules are written in:
+// the absolute value `n`, its integer part `i` and the number of
+// visible fraction digits `v`.
+//
+type pluralOperands struct {
+	n float64
+	i int64
+	v int
+}
+
+//
+// The plural rule of a language: the categories it uses, the
+// categories that the `msgstr[n]` entries of gettext PO files map to,
+// and the function that selects the category of a number.
+//
+type pluralRule struct {
+	categories []string
+	gettext    []string
+	category   func(operands *pluralOperands) string
+}
+
+var (
+	pluralRuleOther = &pluralRule{
+		categories: []string{PluralOther},
+		gettext:    []string{PluralOther},
+		category: func(operands *pluralOperands) string {
+			return PluralOther
+		},
+	}
+
+	// one: i = 1 and v = 0
+	pluralRuleOneInteger = &pluralRule{
+		categories: []string{PluralOne, PluralOther},
+		gettext:    []string{PluralOne, PluralOther},
+		category: func(operands *pluralOperands) string {
+			if operands.i == 1 && operands.v == 0 {
+				return PluralOne
+			}
+			return PluralOther
+		},
+	}
+
+	// one: n = 1
+	pluralRuleOne = &pluralRule{
+		categories: []string{PluralOne, PluralOther},
+		gettext:    []string{PluralOne, PluralOther},
+		category: func(operands *pluralOperands) string {
+			if operands.n == 1 {
+				return PluralOne
+			}
+			return PluralOther
+		},
+	}
+
+	// one: i = 0 or n = 1
+	pluralRuleZeroOrOne = &pluralRule{
+		categories: []string{PluralOne, PluralOther},
+		gettext:    []string{PluralOne, PluralOther},
+		category: func(operands *pluralOperands) string {
+			if operands.i == 0 || operands.n == 1 {
+				return PluralOne
+			}
+			return PluralOther
+		},
+	}
+
+	// one: i = 0,1
+	// many: i != 0 and i % 1000000 = 0 and v = 0
+	pluralRuleFrench = romancePluralRule(func(operands *pluralOperands) bool {
+		return operands.i == 0 || operands.i == 1
+	})
+
+	// one: n = 1
+	// many: i != 0 and i % 1000000 = 0 and v = 0
+	pluralRuleSpanish = romancePluralRule(func(operands *pluralOperands) bool {
+		return operands.n == 1
+	})
+
+	// one: i = 1 and v = 0
+	// many: i != 0 and i % 1000000 = 0 and v = 0
+	pluralRuleItalian = romancePluralRule(func(operands *pluralOperands) bool {
+		return operands.i == 1 && operands.v == 0
+	})
+
+	// one: v = 0 and i % 10 = 1 and i % 100 != 11
+	// few: v = 0 and i % 10 = 2..4 and i % 100 != 12..14
+	// many: v = 0 and (i % 10 = 0 or i % 10 = 5..9 or i % 100 = 11..14)
+	pluralRuleRussian = &pluralRule{
+		categories: []string{PluralOne, PluralFew, PluralMany, PluralOther},
+		gettext:    []string{PluralOne, PluralFew, PluralMany},
+		category: func(operands *pluralOperands) string {
+			if operands.v != 0 {
+				return PluralOther
+			}
+
+			mod10, mod100 := operands.i%10, operands.i%100
+			switch {
+			case mod10 == 1 && mod100 != 11:
+				return PluralOne
+
+			case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+				return PluralFew
+			}
+
+			return PluralMany
+		},
+	}
+
+	// one: i = 1 and v = 0
+	// few: v = 0 and i % 10 = 2..4 and i % 100 != 12..14
+	// many: all other integers
+	pluralRulePolish = &pluralRule{
+		categories: []string{PluralOne, PluralFew, PluralMany, PluralOther},
+		gettext:    []string{PluralOne, PluralFew, PluralMany},
+		category: func(operands *pluralOperands) string {
+			if operands.v != 0 {
+				return PluralOther
+			}
+
+			mod10, mod100 := operands.i%10, operands.i%100
+			switch {
+			case operands.i == 1:
+				return PluralOne
+
+			case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+				return PluralFew
+			}
+
+			return PluralMany
+		},
+	}
+
+	// one: i = 1 and v = 0
+	// few: i = 2..4 and v = 0
+	// many: v != 0
+	pluralRuleCzech = &pluralRule{
+		categories: []string{PluralOne, PluralFew, PluralMany, PluralOther},
+		gettext:    []string{PluralOne, PluralFew, PluralOther},
+		category: func(operands *pluralOperands) string {
+			switch {
+			case operands.v != 0:
+				return PluralMany
+
+			case operands.i == 1:
+				return PluralOne
+
+			case operands.i >= 2 && operands.i <= 4:
+				return PluralFew
+			}
+
+			return PluralOther
+		},
+	}
+
+	// one: i = 1 and v = 0 or i = 0 and v != 0
+	// two: i = 2 and v = 0
+	pluralRuleHebrew = &pluralRule{
+		categories: []string{PluralOne, PluralTwo, PluralOther},
+		gettext:    []string{PluralOne, PluralTwo, PluralOther},
+		category: func(operands *pluralOperands) string {
+			switch {
+			case operands.i == 1 && operands.v == 0, operands.i == 0 && operands.v != 0:
+				return PluralOne
+
+			case operands.i == 2 && operands.v == 0:
+				return PluralTwo
+			}
+
+			return PluralOther
+		},
+	}
+
+	// zero: n = 0
+	// one: n = 1
+	// two: n = 2
+	// few: n % 100 = 3..10
+	// many: n % 100 = 11..99
+	pluralRuleArabic = &pluralRule{
+		categories: []string{PluralZero, PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther},
+		gettext:    []string{PluralZero, PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther},
+		category: func(operands *pluralOperands) string {
+			switch operands.n {
+			case 0:
+				return PluralZero
+
+			case 1:
+				return PluralOne
+
+			case 2:
+				return PluralTwo
+			}
+
+			if operands.n != math.Trunc(operands.n) {
+				return PluralOther
+			}
+
+			mod100 := math.Mod(operands.n, 100)
+			switch {
+			case mod100 >= 3 && mod100 <= 10:
+				return PluralFew
+
+			case mod100 >= 11:
+				return PluralMany
+			}
+
+			return PluralOther
+		},
+	}
+)
+
+//
+// The plural rules by language. Languages that are not listed use
+// the rule of English.
+//
+var pluralRules = map[string]*pluralRule{
+	"ar": pluralRuleArabic,
+	"be": pluralRuleRussian,
+	"bg": pluralRuleOne,
+	"bn": pluralRuleZeroOrOne,
+	"ca": pluralRuleItalian,
+	"cs": pluralRuleCzech,
+	"da": pluralRuleOneInteger,
+	"de": pluralRuleOneInteger,
+	"el": pluralRuleOne,
+	"en": pluralRuleOneInteger,
+	"es": pluralRuleSpanish,
+	"et": pluralRuleOneInteger,
+	"fa": pluralRuleZeroOrOne,
+	"fi": pluralRuleOneInteger,
+	"fr": pluralRuleFrench,
+	"gu": pluralRuleZeroOrOne,
+	"he": pluralRuleHebrew,
+	"hi": pluralRuleZeroOrOne,
+	"hu": pluralRuleOne,
+	"id": pluralRuleOther,
+	"it": pluralRuleItalian,
+	"ja": pluralRuleOther,
+	"kn": pluralRuleZeroOrOne,
+	"ko": pluralRuleOther,
+	"ms": pluralRuleOther,
+	"nb": pluralRuleOne,
+	"nl": pluralRuleOneInteger,
+	"no": pluralRuleOne,
+	"pl": pluralRulePolish,
+	"pt": pluralRuleFrench,
+	"ru": pluralRuleRussian,
+	"sk": pluralRuleCzech,
+	"sv": pluralRuleOneInteger,
+	"th": pluralRuleOther,
+	"tr": pluralRuleOne,
+	"uk": pluralRuleRussian,
+	"vi": pluralRuleOther,
+	"zh": pluralRuleOther,
+}
+
+//
+// Return the CLDR plural category of the number for the given locale,
+// such as `one` for `1` and `other` for `5` in English, or `few` for
+// `3` in Russian. The number may be an integer, a float or a string
+// such as `1.50`, whose visible fraction digits are taken into account.
+// Values that are not numbers are in the `other` category.
+//
+func PluralCategory(locale string, count interface{}) string {
+	operands, ok := newPluralOperands(count)
+	if !ok {
+		return PluralOther
+	}
+
+	return getPluralRule(locale).category(operands)
+}
+
+//
+// Return the plural categories that the given locale uses, ending
+// with `other`.
+//
+func PluralCategories(locale string) []string {
+	categories := getPluralRule(locale).categories
+	return append([]string(nil), categories...)
+}
+
+func getPluralRule(locale string) *pluralRule {
+	locale = NormalizeLocale(locale)
+
+	// European Portuguese uses the rule of Italian
+	if strings.HasPrefix(locale, "pt-PT") {
+		return pluralRuleItalian
+	}
+
+	language, _, _ := strings.Cut(locale, "-")
+	rule, exists := pluralRules[language]
+	if !exists {
+		return pluralRuleOneInteger
+	}
+
+	return rule
+}
+
+//
+// Return a plural rule for languages, like French, Spanish and Italian,
+// that use `many` for whole millions, such as `1000000`.
+//
+func romancePluralRule(one func(operands *pluralOperands) bool) *pluralRule {
+	return &pluralRule{
+		categories: []string{PluralOne, PluralMany, PluralOther},
+		gettext:    []string{PluralOne, PluralOther},
+		category: func(operands *pluralOperands) string {
+			if one(operands) {
+				return PluralOne
+			}
+
+			if operands.v == 0 && operands.i != 0 && operands.i%1000000 == 0 {
+				return PluralMany
+			}
+
+			return PluralOther
+		},
+	}
+}
+
+//
+// Compute the plural operands of a number, using its decimal string
+// representation so that visible fraction digits are kept.
+//
+func newPluralOperands(count interface{}) (*pluralOperands, bool) {
+	var s string
+	switch typed := count.(type) {
+	case float32:
+		s = strconv.FormatFloat(float64(typed), 'f', -1, 32)
+
+	case float64:
+		s = strconv.FormatFloat(typed, 'f', -1, 64)
+
+	case string:
+		s = strings.TrimSpace(typed)
+
+	default:
+		s = berry.ConvertToString(count)
+	}
+
+	s = strings.TrimPrefix(s, "-")
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(n, 0) || math.IsNaN(n) {
+		return nil, false
+	}
+
+	operands := &pluralOperands{
+		n: n,
+		i: int64(math.Trunc(n)),
+	}
+
+	_, fraction, found := strings.Cut(s, ".")
+	if found && fraction != "" {
+		operands.v = len(fraction)
+	}
+
+	return operands, true
+}
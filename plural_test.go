@@ -0,0 +1,82 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPluralCategory(t *testing.T) {
+	tests := []struct {
+		locale   string
+		count    interface{}
+		expected string
+	}{
+		{"en", 1, PluralOne},
+		{"en", 0, PluralOther},
+		{"en", 2, PluralOther},
+		{"en", 1.5, PluralOther},
+		{"en", "1.0", PluralOther},
+		{"en-US", -1, PluralOne},
+
+		{"fr", 0, PluralOne},
+		{"fr", 1.5, PluralOne},
+		{"fr", 2, PluralOther},
+		{"fr", 1000000, PluralMany},
+
+		{"pt-BR", 0, PluralOne},
+		{"pt-PT", 0, PluralOther},
+		{"pt-PT", 1, PluralOne},
+
+		{"ru", 1, PluralOne},
+		{"ru", 21, PluralOne},
+		{"ru", 11, PluralMany},
+		{"ru", 3, PluralFew},
+		{"ru", 24, PluralFew},
+		{"ru", 14, PluralMany},
+		{"ru", 5, PluralMany},
+		{"ru", 1.5, PluralOther},
+
+		{"pl", 1, PluralOne},
+		{"pl", 21, PluralMany},
+		{"pl", 22, PluralFew},
+		{"pl", 12, PluralMany},
+
+		{"cs", 3, PluralFew},
+		{"cs", 5, PluralOther},
+		{"cs", 1.5, PluralMany},
+
+		{"ar", 0, PluralZero},
+		{"ar", 2, PluralTwo},
+		{"ar", 105, PluralFew},
+		{"ar", 11, PluralMany},
+		{"ar", 100, PluralOther},
+
+		{"he", 2, PluralTwo},
+		{"ja", 1, PluralOther},
+		{"xx", 1, PluralOne},
+		{"en", "abc", PluralOther},
+		{"en", nil, PluralOther},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, PluralCategory(test.locale, test.count), "%s %v", test.locale, test.count)
+	}
+}
+
+func TestPluralCategories(t *testing.T) {
+	assert.Equal(t, []string{"one", "other"}, PluralCategories("en"))
+	assert.Equal(t, []string{"one", "few", "many", "other"}, PluralCategories("ru_RU"))
+	assert.Equal(t, []string{"other"}, PluralCategories("zh-Hant-TW"))
+}
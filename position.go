@@ -0,0 +1,160 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+//
+// Represents a location inside the template source. Both the
+// `Line` and the `Column` are 1-based. A zero `Line` means that
+// the position is not known. `File` is the name of the template,
+// and may be empty for templates parsed from a string.
+//
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+//
+// Check if this position points to a known location.
+//
+func (position Position) IsValid() bool {
+	return position.Line > 0
+}
+
+//
+// Return the position as `file:line:column`, or `line:column` if
+// the file is not known.
+//
+func (position Position) String() string {
+	prefix := ""
+	if position.File != "" {
+		prefix = position.File + ":"
+	}
+
+	if !position.IsValid() {
+		if prefix != "" {
+			return position.File
+		}
+		return "-"
+	}
+
+	return prefix + strconv.Itoa(position.Line) + ":" + strconv.Itoa(position.Column)
+}
+
+//
+// Converts byte offsets inside a source string to line
+// and column positions.
+//
+type lineIndex struct {
+	source string
+	starts []int
+}
+
+//
+// Build the line index for the given source.
+//
+func newLineIndex(source string) *lineIndex {
+	starts := []int{0}
+	for index := 0; index < len(source); index++ {
+		if source[index] == '\n' {
+			starts = append(starts, index+1)
+		}
+	}
+
+	return &lineIndex{
+		source: source,
+		starts: starts,
+	}
+}
+
+//
+// Return the position for the given byte offset. Columns are
+// counted in runes so that multi-byte characters do not shift
+// the reported location.
+//
+func (index *lineIndex) position(offset int) Position {
+	if offset < 0 {
+		return Position{}
+	}
+
+	if offset > len(index.source) {
+		offset = len(index.source)
+	}
+
+	line := sort.Search(len(index.starts), func(i int) bool {
+		return index.starts[i] > offset
+	}) - 1
+
+	start := index.starts[line]
+	return Position{
+		Line:   line + 1,
+		Column: utf8.RuneCountInString(index.source[start:offset]) + 1,
+	}
+}
+
+//
+// Return the text of the given 1-based line, without the line
+// terminator.
+//
+func (index *lineIndex) lineText(line int) string {
+	if line < 1 || line > len(index.starts) {
+		return ""
+	}
+
+	start := index.starts[line-1]
+	end := len(index.source)
+	if line < len(index.starts) {
+		end = index.starts[line] - 1
+	}
+
+	return strings.TrimRight(index.source[start:end], "\r")
+}
+
+//
+// Return the line of the position followed by a line with a caret
+// under the column. Tabs are preserved in front of the caret so that
+// it lines up with the text above.
+//
+func (index *lineIndex) snippet(position Position) string {
+	text := index.lineText(position.Line)
+	if text == "" {
+		return ""
+	}
+
+	builder := strings.Builder{}
+	builder.WriteString(text)
+	builder.WriteString("\n")
+
+	column := 1
+	for _, r := range text {
+		if column >= position.Column {
+			break
+		}
+
+		if r == '\t' {
+			builder.WriteRune('\t')
+		} else {
+			builder.WriteRune(' ')
+		}
+		column++
+	}
+
+	builder.WriteString("^")
+	return builder.String()
+}
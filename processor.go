@@ -14,6 +14,7 @@ package snowmark
 import (
 	"errors"
 	"strings"
+	"sync"
 
 	"github.com/sangupta/lhtml"
 )
@@ -26,7 +27,25 @@ type CustomTagProcessor func(node *lhtml.HtmlNode, model *Model, evaluator *Eval
 // HTML templates.
 //
 type HtmlPageProcessor struct {
-	_tags map[string]CustomTagProcessor
+	_tags        map[string]CustomTagProcessor
+	_definitions map[string]*TagDefinition
+	_functions   map[string]ExpressionFunction
+	_filters     map[string]FilterFunction
+	_strict      bool
+	_observers   []RenderObserver
+	_loader      TemplateLoader
+	_messages    *MessageBundle
+	_routes      map[string]*route
+	_basePath    string
+	_assets      *AssetResolver
+	_sanitizers  map[string]*SanitizePolicy
+	_markdown    *MarkdownOptions
+
+	_rejectInlineHandlers bool
+	_sandbox              *sandbox
+
+	_globals      *Model
+	_globalsMutex sync.RWMutex
 }
 
 //
@@ -34,7 +53,12 @@ type HtmlPageProcessor struct {
 //
 func NewHtmlPageProcessor() *HtmlPageProcessor {
 	return &HtmlPageProcessor{
-		_tags: make(map[string]CustomTagProcessor),
+		_tags:        make(map[string]CustomTagProcessor),
+		_definitions: make(map[string]*TagDefinition),
+		_functions:   make(map[string]ExpressionFunction),
+		_filters:     make(map[string]FilterFunction),
+		_routes:      make(map[string]*route),
+		_sanitizers:  make(map[string]*SanitizePolicy),
 	}
 }
 
@@ -43,7 +67,31 @@ func NewHtmlPageProcessor() *HtmlPageProcessor {
 // `func` to work upon. The tag name is case insensitive. If a tag with
 // the same name already exists, an error is returned.
 //
+// The standard tags that ship with snowmark, such as `GetVariableTag`,
+// are registered along with their definition, whatever their name. Every
+// usage of such a tag is then checked before the tag is called, so that
+// a usage that does not match the definition, such as a `get` tag
+// without a `var` attribute, fails instead of being rendered. Use
+// `AddCustomTagWithDefinition` with a `nil` definition to register a
+// standard tag without any checks.
+//
 func (pageProcessor *HtmlPageProcessor) AddCustomTag(name string, tagProcessor CustomTagProcessor) (bool, error) {
+	var definition *TagDefinition
+	if tagProcessor != nil {
+		definition = standardTagDefinitions[funcPointer(tagProcessor)]
+	}
+
+	return pageProcessor.AddCustomTagWithDefinition(name, tagProcessor, definition)
+}
+
+//
+// Add a custom tag to the processor along with the definition that
+// describes its attributes and children. The definition is used to
+// check every usage of the tag before the processor is called, to
+// populate default attribute values, and by `Validate`. A `nil`
+// definition registers the tag without any checks.
+//
+func (pageProcessor *HtmlPageProcessor) AddCustomTagWithDefinition(name string, tagProcessor CustomTagProcessor, definition *TagDefinition) (bool, error) {
 	if name == "" {
 		return false, errors.New("Name cannot be empty")
 	}
@@ -65,6 +113,10 @@ func (pageProcessor *HtmlPageProcessor) AddCustomTag(name string, tagProcessor C
 	}
 
 	pageProcessor._tags[name] = tagProcessor
+	if definition != nil {
+		pageProcessor._definitions[name] = definition
+	}
+
 	return true, nil
 }
 
@@ -85,6 +137,7 @@ func (pageProcessor *HtmlPageProcessor) RemoveCustomTag(name string) (bool, erro
 
 	name = strings.ToLower(name)
 	delete(pageProcessor._tags, name)
+	delete(pageProcessor._definitions, name)
 	return true, nil
 }
 
@@ -124,3 +177,116 @@ func (pageProcessor *HtmlPageProcessor) GetCustomTag(name string) (CustomTagProc
 	tag, exists := pageProcessor._tags[name]
 	return tag, exists
 }
+
+//
+// Return the definition of the custom tag registered with the given
+// name. If no definition was registered, a `nil` is returned.
+//
+func (pageProcessor *HtmlPageProcessor) GetTagDefinition(name string) (*TagDefinition, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return nil, false
+	}
+
+	definition, exists := pageProcessor._definitions[name]
+	return definition, exists
+}
+
+//
+// Add a function that can be called from within expressions. If a
+// function with the same name already exists, an error is returned.
+//
+func (pageProcessor *HtmlPageProcessor) AddFunction(name string, function ExpressionFunction) (bool, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return false, errors.New("Function name cannot be empty")
+	}
+
+	if function == nil {
+		return false, errors.New("Function cannot be nil")
+	}
+
+	_, exists := pageProcessor._functions[name]
+	if exists {
+		return false, errors.New("Function already exists")
+	}
+
+	pageProcessor._functions[name] = function
+	return true, nil
+}
+
+//
+// Add a filter that can be applied to values written by the `get`
+// tag. If a filter with the same name already exists, an error
+// is returned.
+//
+func (pageProcessor *HtmlPageProcessor) AddFilter(name string, filter FilterFunction) (bool, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return false, errors.New("Filter name cannot be empty")
+	}
+
+	if filter == nil {
+		return false, errors.New("Filter cannot be nil")
+	}
+
+	_, exists := pageProcessor._filters[name]
+	if exists {
+		return false, errors.New("Filter already exists")
+	}
+
+	pageProcessor._filters[name] = filter
+	return true, nil
+}
+
+//
+// Return the filter registered with the given name.
+//
+func (pageProcessor *HtmlPageProcessor) GetFilter(name string) (FilterFunction, bool) {
+	filter, exists := pageProcessor._filters[strings.TrimSpace(name)]
+	return filter, exists
+}
+
+//
+// Apply the filters given as a `|` separated list of names to the
+// value, from left to right.
+//
+func (pageProcessor *HtmlPageProcessor) ApplyFilters(value interface{}, filters string) (interface{}, error) {
+	for _, name := range strings.Split(filters, "|") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		filter, exists := pageProcessor.GetFilter(name)
+		if !exists {
+			return nil, errors.New("No such filter: " + name)
+		}
+
+		var err error
+		value, err = filter(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return value, nil
+}
+
+//
+// Enable or disable strict mode. In strict mode the first error
+// raised by any node aborts the merge and is returned along with its
+// location in the template. Otherwise, which is the default, nodes
+// that fail are skipped and the merge continues, reporting the errors
+// to the `OnWarning` function of the merge options.
+//
+func (pageProcessor *HtmlPageProcessor) SetStrictMode(strict bool) {
+	pageProcessor._strict = strict
+}
+
+//
+// Check if the processor is in strict mode.
+//
+func (pageProcessor *HtmlPageProcessor) IsStrictMode() bool {
+	return pageProcessor._strict
+}
@@ -19,11 +19,7 @@ import (
 
 func TestProcessorEmptyTemplate(t *testing.T) {
 	processor := NewHtmlPageProcessor()
-
-	processor.AddCustomTag("if", IfElseTag)
-	processor.AddCustomTag("get", GetVariableTag)
-	processor.AddCustomTag("set", SetVariableTag)
-	processor.AddCustomTag("for", ForEachTag)
+	processor.Import(StandardLibrary(), "")
 
 	template := ""
 	model := NewModel()
@@ -45,10 +41,7 @@ func TestProcessorNoTag(t *testing.T) {
 
 func TestProcessorGetTagNoValue(t *testing.T) {
 	processor := NewHtmlPageProcessor()
-	processor.AddCustomTag("if", IfElseTag)
-	processor.AddCustomTag("get", GetVariableTag)
-	processor.AddCustomTag("set", SetVariableTag)
-	processor.AddCustomTag("for", ForEachTag)
+	processor.Import(StandardLibrary(), "")
 
 	template := "<html><get var='hello' /></html>"
 	model := NewModel()
@@ -60,10 +53,7 @@ func TestProcessorGetTagNoValue(t *testing.T) {
 
 func TestProcessorGetTagWithValue(t *testing.T) {
 	processor := NewHtmlPageProcessor()
-	processor.AddCustomTag("if", IfElseTag)
-	processor.AddCustomTag("get", GetVariableTag)
-	processor.AddCustomTag("set", SetVariableTag)
-	processor.AddCustomTag("for", ForEachTag)
+	processor.Import(StandardLibrary(), "")
 
 	template := "<html><get var='hello' /></html>"
 	model := NewModel()
@@ -73,3 +63,16 @@ func TestProcessorGetTagWithValue(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "<html>world</html>", html)
 }
+
+func TestProcessorGetTagKeywordField(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	processor.AddCustomTagWithDefinition("get", GetVariableTag, GetVariableTagDefinition)
+	processor.SetStrictMode(true)
+
+	model := NewModel()
+	model.Put("item", map[string]interface{}{"type": "book", "range": 3})
+
+	html, err := processor.MergeHtml("<html><get var='item.type' />-<get var='item.range + 1' /></html>", model)
+	assert.NoError(t, err)
+	assert.Equal(t, "<html>book-4</html>", html)
+}
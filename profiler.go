@@ -0,0 +1,174 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// Aggregated timings for a single tag or template.
+//
+type ProfileEntry struct {
+	Kind   EventKind
+	Name   string
+	Calls  int
+	Errors int
+	Total  time.Duration
+	Self   time.Duration
+	Max    time.Duration
+}
+
+//
+// A render observer that aggregates the time spent per custom tag
+// and per template, including included templates. Add it to a
+// processor using `AddObserver` and print the results using
+// `WriteReport`. It is safe for concurrent use.
+//
+type Profiler struct {
+	mutex   sync.Mutex
+	entries map[string]*ProfileEntry
+}
+
+//
+// Create a new profiler.
+//
+func NewProfiler() *Profiler {
+	return &Profiler{
+		entries: make(map[string]*ProfileEntry),
+	}
+}
+
+//
+// Nothing to do when an event begins.
+//
+func (profiler *Profiler) BeginEvent(event *RenderEvent) {
+}
+
+//
+// Aggregate the timings of the event.
+//
+func (profiler *Profiler) EndEvent(event *RenderEvent) {
+	kind := event.Kind
+	name := event.Name
+
+	switch kind {
+	case EventTag:
+		// aggregated per tag
+
+	case EventTemplate, EventInclude:
+		kind = EventTemplate
+		if name == "" {
+			name = "<inline>"
+		}
+
+	default:
+		return
+	}
+
+	profiler.mutex.Lock()
+	defer profiler.mutex.Unlock()
+
+	key := kind.String() + ":" + name
+	entry, exists := profiler.entries[key]
+	if !exists {
+		entry = &ProfileEntry{
+			Kind: kind,
+			Name: name,
+		}
+		profiler.entries[key] = entry
+	}
+
+	entry.Calls++
+	entry.Total += event.Duration
+	entry.Self += event.SelfDuration()
+	if event.Duration > entry.Max {
+		entry.Max = event.Duration
+	}
+
+	if event.Err != nil {
+		entry.Errors++
+	}
+}
+
+//
+// Return a copy of all entries sorted by self time, the
+// slowest first.
+//
+func (profiler *Profiler) Entries() []*ProfileEntry {
+	profiler.mutex.Lock()
+	defer profiler.mutex.Unlock()
+
+	entries := make([]*ProfileEntry, 0, len(profiler.entries))
+	for _, entry := range profiler.entries {
+		copied := *entry
+		entries = append(entries, &copied)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Self != entries[j].Self {
+			return entries[i].Self > entries[j].Self
+		}
+
+		if entries[i].Kind != entries[j].Kind {
+			return entries[i].Kind < entries[j].Kind
+		}
+
+		return entries[i].Name < entries[j].Name
+	})
+
+	return entries
+}
+
+//
+// Clear all aggregated timings.
+//
+func (profiler *Profiler) Reset() {
+	profiler.mutex.Lock()
+	defer profiler.mutex.Unlock()
+
+	profiler.entries = make(map[string]*ProfileEntry)
+}
+
+//
+// Write a flat report of all entries, the slowest first.
+//
+func (profiler *Profiler) WriteReport(writer io.Writer) error {
+	if writer == nil {
+		return errors.New("Writer is required to write report")
+	}
+
+	entries := profiler.Entries()
+
+	width := len("Name")
+	for _, entry := range entries {
+		if len(entry.Name) > width {
+			width = len(entry.Name)
+		}
+	}
+
+	builder := strings.Builder{}
+	format := "%-8s  %-" + fmt.Sprint(width) + "s  %8s  %6s  %12s  %12s  %12s\n"
+	builder.WriteString(fmt.Sprintf(format, "Kind", "Name", "Calls", "Errors", "Total", "Self", "Max"))
+	for _, entry := range entries {
+		builder.WriteString(fmt.Sprintf(format, entry.Kind.String(), entry.Name, fmt.Sprint(entry.Calls), fmt.Sprint(entry.Errors), entry.Total.String(), entry.Self.String(), entry.Max.String()))
+	}
+
+	_, err := io.WriteString(writer, builder.String())
+	return err
+}
@@ -0,0 +1,82 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfiler(t *testing.T) {
+	profiler := NewProfiler()
+
+	template := &RenderEvent{Kind: EventTemplate, Name: "", Duration: 10 * time.Millisecond}
+	outer := &RenderEvent{Kind: EventTag, Name: "c:foreach", Parent: template, Duration: 8 * time.Millisecond, children: 6 * time.Millisecond}
+	inner := &RenderEvent{Kind: EventTag, Name: "c:get", Parent: outer, Duration: 3 * time.Millisecond}
+	failed := &RenderEvent{Kind: EventTag, Name: "c:get", Parent: outer, Duration: 3 * time.Millisecond, Err: assert.AnError}
+	expression := &RenderEvent{Kind: EventExpression, Name: "a", Duration: time.Millisecond}
+
+	for _, event := range []*RenderEvent{inner, failed, outer, template, expression} {
+		profiler.BeginEvent(event)
+		profiler.EndEvent(event)
+	}
+
+	entries := profiler.Entries()
+	assert.Equal(t, 3, len(entries))
+
+	assert.Equal(t, "<inline>", entries[0].Name)
+	assert.Equal(t, EventTemplate, entries[0].Kind)
+	assert.Equal(t, 10*time.Millisecond, entries[0].Self)
+
+	assert.Equal(t, "c:get", entries[1].Name)
+	assert.Equal(t, 2, entries[1].Calls)
+	assert.Equal(t, 1, entries[1].Errors)
+	assert.Equal(t, 6*time.Millisecond, entries[1].Total)
+	assert.Equal(t, 3*time.Millisecond, entries[1].Max)
+
+	assert.Equal(t, "c:foreach", entries[2].Name)
+	assert.Equal(t, 2*time.Millisecond, entries[2].Self)
+
+	builder := strings.Builder{}
+	assert.NoError(t, profiler.WriteReport(&builder))
+	lines := strings.Split(strings.TrimSpace(builder.String()), "\n")
+	assert.Equal(t, 4, len(lines))
+	assert.True(t, strings.HasPrefix(lines[0], "Kind      Name"))
+	assert.True(t, strings.HasPrefix(lines[1], "template  <inline>"))
+	assert.True(t, strings.HasPrefix(lines[2], "tag       c:get"))
+	assert.Error(t, profiler.WriteReport(nil))
+
+	profiler.Reset()
+	assert.Equal(t, 0, len(profiler.Entries()))
+}
+
+func TestProfilerWithProcessor(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "c")
+
+	profiler := NewProfiler()
+	processor.AddObserver(profiler)
+
+	template, _ := ParseTemplate("page.html", "<p><c:get var='1' /><c:get var='2' /></p>")
+	processor.MergeTemplate(template, NewModel())
+	processor.MergeTemplate(template, NewModel())
+
+	calls := make(map[string]int)
+	for _, entry := range profiler.Entries() {
+		calls[entry.Name] = entry.Calls
+	}
+
+	assert.Equal(t, map[string]int{"page.html": 2, "c:get": 4}, calls)
+}
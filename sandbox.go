@@ -0,0 +1,496 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sangupta/lhtml"
+)
+
+//
+// Restricts what templates rendered by a processor may do, so that
+// templates written by untrusted users can be rendered safely. Tags,
+// attributes and expression functions must be allowed explicitly,
+// and an empty list allows none. `<script>` elements, inline event
+// handlers, `javascript:` URLs and model values with methods, such as
+// a `String` method that is called when the value is written, are
+// always blocked.
+//
+// Tags are allowed by the name they are registered with, such as
+// `s:get` or `get`. Attributes are allowed on all HTML elements, and a
+// name ending with `*` allows all attributes with that prefix, such as
+// `data-*`. The attributes of custom tags are governed by their tag.
+//
+type SandboxPolicy struct {
+	AllowedTags       []string
+	AllowedAttributes []string
+	AllowedFunctions  []string
+
+	// The maximum time a merge may take, or `0` for no limit.
+	MaxDuration time.Duration
+
+	// The maximum size of the output in bytes, or `0` for no limit.
+	MaxOutputSize int
+
+	// The maximum depth of nested elements, including the elements of
+	// included templates, or `0` for no limit.
+	MaxDepth int
+}
+
+//
+// Returned when a template does something the sandbox policy of the
+// processor does not allow, or exceeds one of its limits. Sandbox
+// errors abort the merge even if the processor is not in strict mode.
+//
+type SandboxError struct {
+	Message string
+}
+
+func (err *SandboxError) Error() string {
+	return "Sandbox: " + err.Message
+}
+
+//
+// HTML elements allowed by the default sandbox policy: text formatting,
+// lists, tables and images, as used in emails and simple pages.
+//
+var defaultSandboxElements = []string{
+	"a", "abbr", "address", "article", "aside", "b", "bdi", "bdo", "blockquote",
+	"body", "br", "caption", "center", "cite", "code", "col", "colgroup", "dd",
+	"del", "details", "dfn", "div", "dl", "dt", "em", "figcaption", "figure",
+	"font", "footer", "h1", "h2", "h3", "h4", "h5", "h6", "head", "header", "hr",
+	"html", "i", "img", "ins", "kbd", "li", "main", "mark", "nav", "ol", "p",
+	"pre", "q", "s", "samp", "section", "small", "span", "strong", "sub",
+	"summary", "sup", "table", "tbody", "td", "tfoot", "th", "thead", "time",
+	"title", "tr", "u", "ul", "var", "wbr",
+}
+
+//
+// Attributes allowed by the default sandbox policy.
+//
+var defaultSandboxAttributes = []string{
+	"align", "alt", "aria-*", "bgcolor", "border", "cellpadding", "cellspacing",
+	"cite", "class", "color", "colspan", "data-*", "datetime", "dir", "height",
+	"href", "id", "lang", "rel", "role", "rowspan", "s:dir", "s:lang", "src",
+	"start", "target", "title", "valign", "width",
+}
+
+//
+// Attributes whose value is a URL, which must not run scripts.
+//
+var urlAttributes = map[string]bool{
+	"action":     true,
+	"background": true,
+	"cite":       true,
+	"data":       true,
+	"formaction": true,
+	"href":       true,
+	"poster":     true,
+	"src":        true,
+	"xlink:href": true,
+}
+
+//
+// Create a sandbox policy that allows common formatting elements and
+// attributes, the tags and functions of the standard, include, i18n,
+// URL, sanitize and JSON libraries, with or without the `s` prefix,
+// and renders for at most one second, with at most 1 MB of output and
+// 64 levels of nested elements. The markdown tags must be added to the
+// allowed tags explicitly.
+//
+func NewSandboxPolicy() *SandboxPolicy {
+	libraries := []*TagLibrary{
+		StandardLibrary(),
+		IncludeLibrary(),
+		I18nLibrary(),
+		URLLibrary(),
+		SanitizeLibrary(),
+		JSONLibrary(),
+	}
+
+	tags := append([]string{}, defaultSandboxElements...)
+	functions := []string{}
+	for _, library := range libraries {
+		for _, name := range library.TagNames() {
+			tags = append(tags, name, prefixedName(library.Prefix, name))
+		}
+
+		for name := range library._functions {
+			functions = append(functions, name)
+		}
+	}
+
+	return &SandboxPolicy{
+		AllowedTags:       tags,
+		AllowedAttributes: append([]string{}, defaultSandboxAttributes...),
+		AllowedFunctions:  functions,
+		MaxDuration:       time.Second,
+		MaxOutputSize:     1 << 20,
+		MaxDepth:          64,
+	}
+}
+
+//
+// A sandbox policy prepared for quick lookups.
+//
+type sandbox struct {
+	policy     SandboxPolicy
+	tags       map[string]bool
+	attributes map[string]bool
+	prefixes   []string
+	functions  map[string]bool
+}
+
+//
+// Render all templates of the processor within the given sandbox
+// policy. The policy is copied, thus later changes to it have no
+// effect unless it is set again. A `nil` policy removes the sandbox.
+//
+func (pageProcessor *HtmlPageProcessor) SetSandboxPolicy(policy *SandboxPolicy) {
+	if policy == nil {
+		pageProcessor._sandbox = nil
+		return
+	}
+
+	prepared := &sandbox{
+		policy:     *policy,
+		tags:       make(map[string]bool),
+		attributes: make(map[string]bool),
+		functions:  make(map[string]bool),
+	}
+
+	for _, name := range policy.AllowedTags {
+		prepared.tags[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+
+	for _, name := range policy.AllowedAttributes {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if strings.HasSuffix(name, "*") {
+			prepared.prefixes = append(prepared.prefixes, strings.TrimSuffix(name, "*"))
+			continue
+		}
+		prepared.attributes[name] = true
+	}
+
+	for _, name := range policy.AllowedFunctions {
+		prepared.functions[strings.TrimSpace(name)] = true
+	}
+
+	pageProcessor._sandbox = prepared
+}
+
+//
+// Return a copy of the sandbox policy of this processor, if any.
+//
+func (pageProcessor *HtmlPageProcessor) GetSandboxPolicy() *SandboxPolicy {
+	if pageProcessor._sandbox == nil {
+		return nil
+	}
+
+	policy := pageProcessor._sandbox.policy
+	return &policy
+}
+
+func newSandboxError(message string) error {
+	return &SandboxError{Message: message}
+}
+
+func (sandbox *sandbox) isAllowedAttribute(name string) bool {
+	name = strings.ToLower(name)
+	if sandbox.attributes[name] {
+		return true
+	}
+
+	for _, prefix := range sandbox.prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//
+// Check an element before it is evaluated: the tag must be allowed,
+// and for HTML elements, all of its attributes.
+//
+func (sandbox *sandbox) checkNode(node *lhtml.HtmlNode, isCustomTag bool) error {
+	if node.NodeType != lhtml.ElementNode {
+		return nil
+	}
+
+	name := node.NodeName()
+	if name == "script" {
+		return newSandboxError("Element <script> is not allowed")
+	}
+
+	if !sandbox.tags[name] {
+		return newSandboxError("Tag <" + name + "> is not allowed")
+	}
+
+	if isCustomTag {
+		return nil
+	}
+
+	for _, attr := range node.Attributes {
+		err := sandbox.checkAttribute(name, strings.TrimPrefix(attr.Name, PREFIX), "")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//
+// Check an attribute of an HTML element, and its value if it is known.
+//
+func (sandbox *sandbox) checkAttribute(elementName string, name string, value string) error {
+	if isEventHandler(name) {
+		return newSandboxError("Event handler '" + name + "' of <" + elementName + "> is not allowed")
+	}
+
+	if !sandbox.isAllowedAttribute(name) {
+		return newSandboxError("Attribute '" + name + "' of <" + elementName + "> is not allowed")
+	}
+
+	if urlAttributes[strings.ToLower(name)] && isScriptURL(value) {
+		return newSandboxError("Script URL in '" + name + "' of <" + elementName + "> is not allowed")
+	}
+
+	return nil
+}
+
+//
+// Check the functions an expression calls.
+//
+func (sandbox *sandbox) checkExpression(expr string) error {
+	info, err := parseExpression(expr)
+	if err != nil {
+		// the functions and variables of an expression that cannot be
+		// analysed are unknown, thus it must not be evaluated
+		return newSandboxError("Expression cannot be analysed: " + err.Error())
+	}
+
+	for _, function := range info.Functions {
+		if !sandbox.functions[function] {
+			return newSandboxError("Function '" + function + "' is not allowed")
+		}
+	}
+
+	return nil
+}
+
+//
+// Check the functions an expression calls, and the values of the
+// variables it reads.
+//
+func (evaluator *Evaluator) checkSandboxExpression(expr string, values map[string]interface{}) error {
+	err := evaluator.processor._sandbox.checkExpression(expr)
+	if err != nil {
+		return err
+	}
+
+	for _, variable := range evaluator.variables[expr] {
+		err := checkSandboxValue(values[variable])
+		if err != nil {
+			return newSandboxError("Variable '" + variable + "': " + err.(*SandboxError).Message)
+		}
+	}
+
+	return nil
+}
+
+//
+// Check the limits of the sandbox at the start of each element.
+//
+func (evaluator *Evaluator) checkSandboxLimits() error {
+	policy := &evaluator.processor._sandbox.policy
+
+	if policy.MaxDuration > 0 && time.Since(evaluator.started) > policy.MaxDuration {
+		return newSandboxError("Merge took longer than " + policy.MaxDuration.String())
+	}
+
+	if policy.MaxOutputSize > 0 && evaluator.builder.Len() > policy.MaxOutputSize {
+		return newSandboxError("Output is larger than " + strconv.Itoa(policy.MaxOutputSize) + " bytes")
+	}
+
+	if policy.MaxDepth > 0 && evaluator.nesting > policy.MaxDepth {
+		return newSandboxError("Elements are nested deeper than " + strconv.Itoa(policy.MaxDepth) + " levels")
+	}
+
+	return nil
+}
+
+//
+// Check the final output of a merge, which includes the markup that
+// custom tags wrote and markup that entities in the template turned
+// into, against the policy.
+//
+func (sandbox *sandbox) checkOutput(output string) error {
+	if sandbox.policy.MaxOutputSize > 0 && len(output) > sandbox.policy.MaxOutputSize {
+		return newSandboxError("Output is larger than " + strconv.Itoa(sandbox.policy.MaxOutputSize) + " bytes")
+	}
+
+	elements, err := lhtml.ParseHtmlString(output)
+	if err != nil {
+		return newSandboxError("Output cannot be parsed: " + err.Error())
+	}
+
+	return sandbox.checkOutputNodes(elements.Nodes())
+}
+
+func (sandbox *sandbox) checkOutputNodes(nodes []*lhtml.HtmlNode) error {
+	for _, node := range nodes {
+		if node.NodeType != lhtml.ElementNode {
+			continue
+		}
+
+		name := node.NodeName()
+		if name == "script" {
+			return newSandboxError("Element <script> is not allowed")
+		}
+
+		if !sandbox.tags[name] {
+			return newSandboxError("Element <" + name + "> is not allowed")
+		}
+
+		for _, attr := range node.Attributes {
+			err := sandbox.checkAttribute(name, attr.Name, attr.Value)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := sandbox.checkOutputNodes(node.Children())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//
+// Check if the URL runs a script when followed. Browsers ignore
+// whitespace and control characters within the scheme.
+//
+func isScriptURL(value string) bool {
+	scheme := strings.Builder{}
+	for _, r := range value {
+		if r <= ' ' {
+			continue
+		}
+
+		if r == ':' {
+			break
+		}
+
+		scheme.WriteRune(r)
+	}
+
+	switch strings.ToLower(scheme.String()) {
+	case "javascript", "vbscript":
+		return true
+	}
+
+	return false
+}
+
+var (
+	stringerType   = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	goStringerType = reflect.TypeOf((*fmt.GoStringer)(nil)).Elem()
+	formatterType  = reflect.TypeOf((*fmt.Formatter)(nil)).Elem()
+	errorType      = reflect.TypeOf((*error)(nil)).Elem()
+	timeType       = reflect.TypeOf(time.Time{})
+	durationType   = reflect.TypeOf(time.Duration(0))
+)
+
+//
+// Check that a value does not have methods that writing it would call,
+// such as `String`, nor is a function. Times and durations are allowed.
+//
+func checkSandboxValue(value interface{}) error {
+	return checkSandboxReflectValue(reflect.ValueOf(value), 0)
+}
+
+func checkSandboxReflectValue(value reflect.Value, depth int) error {
+	if !value.IsValid() {
+		return nil
+	}
+
+	if depth > 32 {
+		return newSandboxError("Value is nested too deep")
+	}
+
+	valueType := value.Type()
+	if valueType == timeType || valueType == durationType {
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return newSandboxError("Values of type " + valueType.String() + " are not allowed")
+	}
+
+	if valueType.Implements(stringerType) || valueType.Implements(goStringerType) ||
+		valueType.Implements(formatterType) || valueType.Implements(errorType) {
+		return newSandboxError("Values of type " + valueType.String() + " have methods and are not allowed")
+	}
+
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+		return checkSandboxReflectValue(value.Elem(), depth+1)
+
+	case reflect.Slice, reflect.Array:
+		for index := 0; index < value.Len(); index++ {
+			err := checkSandboxReflectValue(value.Index(index), depth+1)
+			if err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		iterator := value.MapRange()
+		for iterator.Next() {
+			err := checkSandboxReflectValue(iterator.Key(), depth+1)
+			if err == nil {
+				err = checkSandboxReflectValue(iterator.Value(), depth+1)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+	case reflect.Struct:
+		for index := 0; index < value.NumField(); index++ {
+			if !valueType.Field(index).IsExported() {
+				continue
+			}
+
+			err := checkSandboxReflectValue(value.Field(index), depth+1)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,186 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type sandboxSecret struct {
+	Value string
+}
+
+func (secret sandboxSecret) String() string {
+	return "called"
+}
+
+type sandboxProduct struct {
+	Name  string
+	Price float64
+}
+
+func newSandboxedProcessor() *HtmlPageProcessor {
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "")
+	processor.Import(I18nLibrary(), "")
+	processor.ImportWithDefaultPrefix(AssetLibrary())
+	processor.SetSandboxPolicy(NewSandboxPolicy())
+	return processor
+}
+
+func TestSandboxAllowed(t *testing.T) {
+	processor := newSandboxedProcessor()
+
+	model := NewModel()
+	model.Put("name", "Ann")
+	model.Put("items", []interface{}{"a", "b"})
+	model.Put("when", time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC))
+	model.Put("product", sandboxProduct{Name: "Tea", Price: 2})
+
+	html, err := processor.MergeHtml(`<table class="x" data-id="1"><tr><td><get var="upper(name)" /></td></tr></table>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<table class="x" data-id="1"><tr><td>ANN</td></tr></table>`, html)
+
+	html, err = processor.MergeHtml(`<ul><foreach collection="items" var="item"><li><get var="item" /></li></foreach></ul>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<ul><li>a</li><li>b</li></ul>`, html)
+
+	html, err = processor.MergeHtml(`<p><a href="https://example.com">x</a><formatDate value="when" pattern="yyyy" /></p>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<p><a href="https://example.com">x</a>2022</p>`, html)
+
+	// fields named like go keywords
+	model.Put("book", map[string]interface{}{"type": "novel"})
+	html, err = processor.MergeHtml(`<p expr:title="upper(book.type)">x</p>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<p title="NOVEL">x</p>`, html)
+
+	// plain structs without methods are fine
+	html, err = processor.MergeHtml(`<p><get var="product" /></p>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<p>{Tea 2}</p>`, html)
+
+	policy := processor.GetSandboxPolicy()
+	assert.NotNil(t, policy)
+	assert.Equal(t, time.Second, policy.MaxDuration)
+
+	processor.SetSandboxPolicy(nil)
+	assert.Nil(t, processor.GetSandboxPolicy())
+}
+
+func TestSandboxBlocked(t *testing.T) {
+	processor := newSandboxedProcessor()
+	processor.AddFunction("secret", func(evaluator *Evaluator, args ...interface{}) (interface{}, error) {
+		return "s3cret", nil
+	})
+
+	model := NewModel()
+	model.Put("secret", sandboxSecret{Value: "x"})
+	model.Put("secrets", []interface{}{map[string]interface{}{"a": &sandboxSecret{}}})
+	model.Put("callback", func() string { return "x" })
+	model.Put("link", " java\tscript:alert(1)")
+	model.Put("item", map[string]interface{}{"type": "book", "range": 1})
+
+	tests := []string{
+		// script elements, even in allowed tags
+		`<p><script>alert(1)</script></p>`,
+		// tags and attributes that are not allowed
+		`<p><iframe src="https://example.com"></iframe></p>`,
+		`<p><asset:script src="app.js" /></p>`,
+		`<p style="color: red">x</p>`,
+		`<p onclick="alert(1)">x</p>`,
+		`<p expr:onmouseover="link">x</p>`,
+		// script URLs
+		`<p><a href="javascript:alert(1)">x</a></p>`,
+		`<p><a href=" JavaScript:alert(1)">x</a></p>`,
+		`<p><a expr:href="link">x</a></p>`,
+		`<p><img src="vbscript:x" /></p>`,
+		// functions that are not allowed
+		`<p><get var='secret()' /></p>`,
+		`<p><get var='assetIntegrity("app.js")' /></p>`,
+		`<p expr:title="secret(item.type)">x</p>`,
+		`<p expr:title="item.range + secret()">x</p>`,
+		// expressions that cannot be analysed
+		`<p expr:title="item.">x</p>`,
+		// values with methods
+		`<p><get var="secret" /></p>`,
+		`<p><get var="secrets" /></p>`,
+		`<p><get var="callback" /></p>`,
+		// markup from entities and values
+		`<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>`,
+		`<p><get var='"<img src=x onerror=alert(1)>"' /></p>`,
+		`<p title="a &quot; onclick=&quot;alert(1)">x</p>`,
+	}
+
+	for _, test := range tests {
+		_, err := processor.MergeHtml(test, model)
+
+		var sandboxError *SandboxError
+		assert.True(t, errors.As(err, &sandboxError), test)
+	}
+}
+
+func TestSandboxLimits(t *testing.T) {
+	processor := newSandboxedProcessor()
+
+	policy := NewSandboxPolicy()
+	policy.MaxDepth = 3
+	processor.SetSandboxPolicy(policy)
+
+	_, err := processor.MergeHtml(`<div><div><div>x</div></div></div>`, nil)
+	assert.NoError(t, err)
+
+	_, err = processor.MergeHtml(`<div><div><div><div>x</div></div></div></div>`, nil)
+	assert.Error(t, err)
+
+	// output size, even in lenient mode
+	policy = NewSandboxPolicy()
+	policy.MaxOutputSize = 100
+	processor.SetSandboxPolicy(policy)
+
+	model := NewModel()
+	model.Put("items", make([]interface{}, 100))
+
+	_, err = processor.MergeHtml(`<div><foreach collection="items" var="item"><p>item</p></foreach></div>`, model)
+	assert.ErrorContains(t, err, "Output is larger than 100 bytes")
+
+	_, err = processor.MergeHtml(`<div>`+strings.Repeat("x", 200)+`</div>`, model)
+	assert.Error(t, err)
+
+	// duration
+	policy = NewSandboxPolicy()
+	policy.MaxDuration = time.Millisecond
+	processor.SetSandboxPolicy(policy)
+	processor.AddFunction("slow", func(evaluator *Evaluator, args ...interface{}) (interface{}, error) {
+		time.Sleep(2 * time.Millisecond)
+		return "", nil
+	})
+	policy.AllowedFunctions = append(policy.AllowedFunctions, "slow")
+	processor.SetSandboxPolicy(policy)
+
+	_, err = processor.MergeHtml(`<div><get var="slow()" /><p>x</p></div>`, nil)
+	assert.ErrorContains(t, err, "Merge took longer than")
+}
+
+func TestIsScriptURL(t *testing.T) {
+	assert.True(t, isScriptURL("javascript:alert(1)"))
+	assert.True(t, isScriptURL(" JAVA\nSCRIPT:x"))
+	assert.True(t, isScriptURL("vbscript:x"))
+	assert.False(t, isScriptURL("https://example.com/javascript:x"))
+	assert.False(t, isScriptURL("/path"))
+	assert.False(t, isScriptURL(""))
+}
@@ -0,0 +1,328 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"errors"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/sangupta/lhtml"
+)
+
+//
+// The names of the sanitize policies that are always available.
+//
+const (
+	SanitizePolicyBasic    = "basic"
+	SanitizePolicyStrict   = "strict"
+	SanitizePolicyMarkdown = "markdown"
+)
+
+//
+// Marks the whitespace between two tags, such as the space in
+// `<b>a</b> <i>b</i>`, which the parser would drop otherwise. It is a
+// character from the private use area of Unicode.
+//
+const whitespaceMarker = "\ue000"
+
+var whitespaceBetweenTags = regexp.MustCompile(`>(\s+)<`)
+
+//
+// Describes the markup that is kept when user-authored HTML, such as
+// comments and bios, is sanitized. Elements that are not allowed are
+// removed, but their content is kept, except for elements such as
+// `<script>` and `<style>` whose content is removed as well. Comments
+// are always removed.
+//
+type SanitizePolicy struct {
+	// The elements to keep, such as `p` and `a`.
+	AllowedElements []string
+
+	// The attributes to keep, by element name. The attributes listed
+	// under `*` are kept on all allowed elements.
+	AllowedAttributes map[string][]string
+
+	// The schemes that URLs in attributes such as `href` and `src` may
+	// have, such as `https` and `mailto`. Relative URLs are always
+	// allowed, and attributes with other URLs are removed.
+	AllowedURLSchemes []string
+
+	// Add `rel="nofollow"` to all links, so that search engines do not
+	// reward links that users post.
+	RequireNoFollow bool
+}
+
+//
+// Elements whose content is removed along with the element.
+//
+var sanitizeRemovedContent = map[string]bool{
+	"iframe":   true,
+	"noembed":  true,
+	"noframes": true,
+	"noscript": true,
+	"object":   true,
+	"embed":    true,
+	"script":   true,
+	"style":    true,
+	"template": true,
+	"textarea": true,
+	"title":    true,
+	"xmp":      true,
+}
+
+//
+// Elements that cannot have content.
+//
+var voidElements = map[string]bool{
+	"area":   true,
+	"base":   true,
+	"br":     true,
+	"col":    true,
+	"embed":  true,
+	"hr":     true,
+	"img":    true,
+	"input":  true,
+	"link":   true,
+	"meta":   true,
+	"source": true,
+	"track":  true,
+	"wbr":    true,
+}
+
+//
+// Create the `basic` sanitize policy, for comments and similar short
+// texts: paragraphs, emphasis, lists, quotes, code and links to web
+// and email addresses, which get `rel="nofollow"`.
+//
+func NewBasicSanitizePolicy() *SanitizePolicy {
+	return &SanitizePolicy{
+		AllowedElements: []string{
+			"a", "b", "blockquote", "br", "code", "del", "em", "i", "li", "ol",
+			"p", "pre", "q", "s", "strong", "sub", "sup", "u", "ul",
+		},
+		AllowedAttributes: map[string][]string{
+			"a": {"href", "title"},
+		},
+		AllowedURLSchemes: []string{"http", "https", "mailto"},
+		RequireNoFollow:   true,
+	}
+}
+
+//
+// Create the `strict` sanitize policy, which removes all markup and
+// keeps only the text.
+//
+func NewStrictSanitizePolicy() *SanitizePolicy {
+	return &SanitizePolicy{}
+}
+
+//
+// Sanitize the HTML, returning only the markup that the policy allows.
+// The HTML is parsed using the lenient `lhtml` parser, and all text and
+// attribute values are escaped in the result.
+//
+func (policy *SanitizePolicy) Sanitize(source string) (string, error) {
+	source = strings.ReplaceAll(source, whitespaceMarker, "")
+	source = whitespaceBetweenTags.ReplaceAllString(source, ">"+whitespaceMarker+"$1<")
+
+	elements, err := lhtml.ParseHtmlString(source)
+	if err != nil {
+		return "", err
+	}
+
+	sanitizer := &htmlSanitizer{
+		policy:     policy,
+		elements:   toSet(policy.AllowedElements),
+		attributes: make(map[string]map[string]bool, len(policy.AllowedAttributes)),
+		schemes:    toSet(policy.AllowedURLSchemes),
+	}
+
+	for element, attributes := range policy.AllowedAttributes {
+		sanitizer.attributes[strings.ToLower(element)] = toSet(attributes)
+	}
+
+	sanitizer.writeNodes(elements.Nodes())
+	return strings.ReplaceAll(sanitizer.builder.String(), whitespaceMarker, ""), nil
+}
+
+//
+// The state of a single run of a sanitize policy.
+//
+type htmlSanitizer struct {
+	policy     *SanitizePolicy
+	elements   map[string]bool
+	attributes map[string]map[string]bool
+	schemes    map[string]bool
+	builder    strings.Builder
+}
+
+func (sanitizer *htmlSanitizer) writeNodes(nodes []*lhtml.HtmlNode) {
+	for _, node := range nodes {
+		sanitizer.writeNode(node)
+	}
+}
+
+func (sanitizer *htmlSanitizer) writeNode(node *lhtml.HtmlNode) {
+	switch node.NodeType {
+	case lhtml.TextNode:
+		sanitizer.builder.WriteString(html.EscapeString(node.Data))
+		return
+
+	case lhtml.ElementNode:
+		// handled below
+
+	default:
+		// comments and doctypes
+		return
+	}
+
+	name := node.NodeName()
+	if sanitizeRemovedContent[name] {
+		return
+	}
+
+	if !sanitizer.elements[name] {
+		sanitizer.writeNodes(node.Children())
+		return
+	}
+
+	builder := &sanitizer.builder
+	builder.WriteString("<" + name)
+
+	rel := ""
+	for _, attr := range node.Attributes {
+		if !sanitizer.isAllowedAttribute(name, attr.Name) {
+			continue
+		}
+
+		if urlAttributes[attr.Name] && !sanitizer.isAllowedURL(attr.Value) {
+			continue
+		}
+
+		if name == "a" && attr.Name == "rel" {
+			rel = attr.Value
+			continue
+		}
+
+		builder.WriteString(" " + attr.Name + "=\"" + html.EscapeString(attr.Value) + "\"")
+	}
+
+	if name == "a" && sanitizer.policy.RequireNoFollow && !containsString(strings.Fields(strings.ToLower(rel)), "nofollow") {
+		rel = strings.TrimSpace(rel + " nofollow")
+	}
+
+	if rel != "" {
+		builder.WriteString(" rel=\"" + html.EscapeString(rel) + "\"")
+	}
+
+	// the parser nests the content that follows a void element, such
+	// as `<br>`, inside the element
+	if voidElements[name] {
+		builder.WriteString(" />")
+		sanitizer.writeNodes(node.Children())
+		return
+	}
+
+	builder.WriteString(">")
+	sanitizer.writeNodes(node.Children())
+	builder.WriteString("</" + name + ">")
+}
+
+func (sanitizer *htmlSanitizer) isAllowedAttribute(element string, name string) bool {
+	if isEventHandler(name) {
+		return false
+	}
+
+	return sanitizer.attributes[element][name] || sanitizer.attributes["*"][name]
+}
+
+//
+// Check if the URL is relative, or has one of the allowed schemes.
+//
+func (sanitizer *htmlSanitizer) isAllowedURL(value string) bool {
+	scheme := strings.Builder{}
+	for _, r := range value {
+		if r <= ' ' {
+			continue
+		}
+
+		if r == ':' {
+			return sanitizer.schemes[strings.ToLower(scheme.String())]
+		}
+
+		if r == '/' || r == '?' || r == '#' {
+			break
+		}
+
+		scheme.WriteRune(r)
+	}
+
+	return true
+}
+
+//
+// Return a set of the trimmed and lower-cased values.
+//
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[strings.ToLower(strings.TrimSpace(value))] = true
+	}
+
+	return set
+}
+
+//
+// Register a named sanitize policy for the `sanitize` and `markdown`
+// tags, replacing any policy with the same name, including the `basic`,
+// `strict` and `markdown` policies. A `nil` policy removes the registered policy.
+//
+func (pageProcessor *HtmlPageProcessor) SetSanitizePolicy(name string, policy *SanitizePolicy) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("Sanitize policy name cannot be empty")
+	}
+
+	if policy == nil {
+		delete(pageProcessor._sanitizers, name)
+		return nil
+	}
+
+	pageProcessor._sanitizers[name] = policy
+	return nil
+}
+
+//
+// Return the named sanitize policy: either a registered policy, or
+// one of the `basic`, `strict` and `markdown` policies.
+//
+func (pageProcessor *HtmlPageProcessor) GetSanitizePolicy(name string) (*SanitizePolicy, bool) {
+	policy, exists := pageProcessor._sanitizers[name]
+	if exists {
+		return policy, true
+	}
+
+	switch name {
+	case SanitizePolicyBasic:
+		return NewBasicSanitizePolicy(), true
+
+	case SanitizePolicyStrict:
+		return NewStrictSanitizePolicy(), true
+
+	case SanitizePolicyMarkdown:
+		return NewMarkdownSanitizePolicy(), true
+	}
+
+	return nil, false
+}
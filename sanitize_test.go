@@ -0,0 +1,111 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBasicSanitizePolicy(t *testing.T) {
+	policy := NewBasicSanitizePolicy()
+
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{`Hello <b>world</b>`, `Hello <b>world</b>`},
+		{`<b>a</b> <i>b</i>`, `<b>a</b> <i>b</i>`},
+		{"<p>one</p>\n<p>two</p>", "<p>one</p>\n<p>two</p>"},
+		{`<p onclick="alert(1)" class="x">Hi</p>`, `<p>Hi</p>`},
+		{`<script>alert(1)</script>ok`, `ok`},
+		{`<style>p { color: red }</style><div>text</div>`, `text`},
+		{`<a href="https://example.com" target="_blank">x</a>`, `<a href="https://example.com" rel="nofollow">x</a>`},
+		{`<a href="/about" title="a &quot;b&quot;">x</a>`, `<a href="/about" title="a &#34;b&#34;" rel="nofollow">x</a>`},
+		{`<a href="javascript:alert(1)">x</a>`, `<a rel="nofollow">x</a>`},
+		{`<a href=" java&#x09;script:alert(1)">x</a>`, `<a rel="nofollow">x</a>`},
+		{`<a href="mailto:a@example.com">x</a>`, `<a href="mailto:a@example.com" rel="nofollow">x</a>`},
+		{`a<br>b<img src="x.png">c`, `a<br />bc`},
+		{`&lt;script&gt; &amp; <!-- comment -->`, `&lt;script&gt; &amp; `},
+		{`<ul><li>one<li>two</ul>`, `<ul><li>one<li>two</li></li></ul>`},
+	}
+
+	for _, test := range tests {
+		result, err := policy.Sanitize(test.source)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, result, test.source)
+	}
+}
+
+func TestCustomSanitizePolicy(t *testing.T) {
+	policy := &SanitizePolicy{
+		AllowedElements: []string{"a", "img", "p"},
+		AllowedAttributes: map[string][]string{
+			"*":   {"class"},
+			"a":   {"href", "rel"},
+			"img": {"src", "alt"},
+		},
+		AllowedURLSchemes: []string{"https"},
+		RequireNoFollow:   true,
+	}
+
+	result, err := policy.Sanitize(`<p class="intro"><a href="http://example.com" rel="author" class="x">x</a><img src="https://example.com/a.png" alt="A" onerror="alert(1)" /></p>`)
+	assert.NoError(t, err)
+	assert.Equal(t, `<p class="intro"><a class="x" rel="author nofollow">x</a><img src="https://example.com/a.png" alt="A" /></p>`, result)
+
+	result, _ = policy.Sanitize(`<a href="https://example.com" rel="NoFollow">x</a>`)
+	assert.Equal(t, `<a href="https://example.com" rel="NoFollow">x</a>`, result)
+
+	result, _ = NewStrictSanitizePolicy().Sanitize(`<p>Hello <b>world</b></p>`)
+	assert.Equal(t, `Hello world`, result)
+}
+
+func TestSanitizeTag(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "")
+	processor.Import(SanitizeLibrary(), "")
+	processor.SetStrictMode(true)
+
+	model := NewModel()
+	model.Put("comment", map[string]interface{}{
+		"body": `<p>Nice <em>post</em>!<script>alert(1)</script></p>`,
+	})
+
+	html, err := processor.MergeHtml(`<div><sanitize var="comment.body" /></div>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<div><p>Nice <em>post</em>!</p></div>`, html)
+
+	html, err = processor.MergeHtml(`<div><sanitize var="comment.body" policy="strict" /></div>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<div>Nice post!</div>`, html)
+
+	// registered policies, which may replace the built-in ones
+	assert.NoError(t, processor.SetSanitizePolicy("bio", &SanitizePolicy{AllowedElements: []string{"em"}}))
+	html, err = processor.MergeHtml(`<div><sanitize var="comment.body" policy="bio" /></div>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<div>Nice <em>post</em>!</div>`, html)
+
+	assert.NoError(t, processor.SetSanitizePolicy("basic", NewStrictSanitizePolicy()))
+	html, _ = processor.MergeHtml(`<div><sanitize var="comment.body" /></div>`, model)
+	assert.Equal(t, `<div>Nice post!</div>`, html)
+
+	assert.NoError(t, processor.SetSanitizePolicy("basic", nil))
+	policy, exists := processor.GetSanitizePolicy("basic")
+	assert.True(t, exists)
+	assert.True(t, policy.RequireNoFollow)
+
+	assert.Error(t, processor.SetSanitizePolicy(" ", NewStrictSanitizePolicy()))
+
+	_, err = processor.MergeHtml(`<div><sanitize var="comment.body" policy="missing" /></div>`, model)
+	assert.Error(t, err)
+}
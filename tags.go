@@ -13,7 +13,11 @@ package snowmark
 
 import (
 	"errors"
+	"html"
 	"reflect"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/sangupta/berry"
 	"github.com/sangupta/lhtml"
@@ -21,8 +25,10 @@ import (
 
 //
 // A simple tag to get the value of any variable inside the model.
+// An optional list of filters can be applied to the value.
 //
-//   <get value="name" />
+//   <get var="name" />
+//   <get var="name" filter="trim|upper" />
 //
 func GetVariableTag(node *lhtml.HtmlNode, model *Model, evaluator *Evaluator) error {
 	value, err := evaluator.GetAttributeValueAsString(node, "var", model)
@@ -30,12 +36,20 @@ func GetVariableTag(node *lhtml.HtmlNode, model *Model, evaluator *Evaluator) er
 		return err
 	}
 
-	value, err = evaluator.EvaluateExpressionAsString(value, model)
+	result, err := evaluator.EvaluateExpression(value, model)
 	if err != nil {
 		return err
 	}
 
-	evaluator.builder.WriteString(value)
+	filters, err := node.GetAttributeValue("filter")
+	if err == nil {
+		result, err = evaluator.processor.ApplyFilters(result, filters)
+		if err != nil {
+			return err
+		}
+	}
+
+	evaluator.builder.WriteString(berry.ConvertToString(result))
 	return nil
 }
 
@@ -73,6 +87,10 @@ func SetVariableTag(node *lhtml.HtmlNode, model *Model, evaluator *Evaluator) er
 		return err
 	}
 
+	if model.IsFrozen() {
+		return ErrModelFrozen
+	}
+
 	// preserve old value
 	olderValue, olderValueExists := model.Get(variableName)
 
@@ -118,7 +136,7 @@ func IfElseTag(node *lhtml.HtmlNode, model *Model, evaluator *Evaluator) error {
 	}
 
 	if conditionBool {
-		thenClause := node.GetChildByName("then")
+		thenClause := getClause(node, "then")
 		if thenClause == nil {
 			return errors.New("If tag does not have a 'then' clause")
 		}
@@ -127,7 +145,7 @@ func IfElseTag(node *lhtml.HtmlNode, model *Model, evaluator *Evaluator) error {
 	}
 
 	// do else part
-	elseClause := node.GetChildByName("else")
+	elseClause := getClause(node, "else")
 	if elseClause == nil {
 		return nil
 	}
@@ -177,6 +195,10 @@ func ForEachTag(node *lhtml.HtmlNode, model *Model, evaluator *Evaluator) error
 		return nil
 	}
 
+	if model.IsFrozen() {
+		return ErrModelFrozen
+	}
+
 	// preserve old value
 	olderValue, olderValueExists := model.Get(variableName)
 
@@ -185,13 +207,18 @@ func ForEachTag(node *lhtml.HtmlNode, model *Model, evaluator *Evaluator) error
 	case reflect.Slice:
 		slice := reflect.ValueOf(collection)
 		for index := 0; index < slice.Len(); index++ {
-			item := slice.Index(index)
+			item := slice.Index(index).Interface()
 
 			// now run the nodes with this value
+			event := evaluator.BeginEvent(EventLoopIteration, variableName, node)
 			model.Put(variableName, item)
 
 			// evaluate all child nodes
-			evaluator.EvaluateNodes(node.Children(), model)
+			err = evaluator.EvaluateNodes(node.Children(), model)
+			evaluator.EndEvent(event, err)
+			if err != nil {
+				break
+			}
 		}
 
 	case reflect.Map:
@@ -201,13 +228,18 @@ func ForEachTag(node *lhtml.HtmlNode, model *Model, evaluator *Evaluator) error
 
 			// put this in model
 			pair := map[string]interface{}{
-				"key":   key,
-				"value": value,
+				"key":   key.Interface(),
+				"value": value.Interface(),
 			}
+			event := evaluator.BeginEvent(EventLoopIteration, variableName, node)
 			model.Put(variableName, pair)
 
 			// evaluate all child nodes
-			evaluator.EvaluateNodes(node.Children(), model)
+			err = evaluator.EvaluateNodes(node.Children(), model)
+			evaluator.EndEvent(event, err)
+			if err != nil {
+				break
+			}
 		}
 	}
 
@@ -219,5 +251,633 @@ func ForEachTag(node *lhtml.HtmlNode, model *Model, evaluator *Evaluator) error
 	}
 
 	// all done
+	return err
+}
+
+//
+// Return the first child of the node that represents the clause with
+// the given name. Clauses may carry the same prefix as their parent
+// tag, thus both `<then>` and `<custom:then>` match the `then` clause.
+//
+func getClause(node *lhtml.HtmlNode, name string) *lhtml.HtmlNode {
+	for _, child := range node.Children() {
+		if matchesClause(child.NodeName(), name) {
+			return child
+		}
+	}
+
+	return nil
+}
+
+//
+// Check if the node name represents the clause with the given name.
+//
+func matchesClause(nodeName string, name string) bool {
+	nodeName = strings.ToLower(nodeName)
+	return nodeName == name || strings.HasSuffix(nodeName, ":"+name)
+}
+
+//
+// Include another template, loaded by name using the template loader
+// of the processor. The included template is merged with the same
+// model, in place of the tag.
+//
+//  <include template="partials/header.html" />
+//  <include expr:template='"partials/" + section + ".html"' />
+//
+func IncludeTag(node *lhtml.HtmlNode, model *Model, evaluator *Evaluator) error {
+	name, err := evaluator.GetAttributeValueAsString(node, "template", model)
+	if err != nil {
+		return err
+	}
+
+	template, err := evaluator.processor.LoadTemplate(name)
+	if err != nil {
+		return err
+	}
+
+	tracker, ok := evaluator.processor.GetTemplateLoader().(DependencyTracker)
+	if ok && evaluator.template != nil && evaluator.template.Name != "" {
+		tracker.AddDependency(evaluator.template.Name, template.Name)
+	}
+
+	return evaluator.EvaluateTemplate(template, model, EventInclude)
+}
+
+//
+// Write a message of the message bundle of the processor, translated
+// into the locale of the merge, or the locale given by the `locale`
+// attribute. The `count` expression selects the plural form, and the
+// `args` expression returns the values of the named placeholders,
+// which are escaped.
+//
+//  <msg key="checkout.title" />
+//  <msg key="cart.items" count="cart.size" args='{"name": user.name}' />
+//
+func MessageTag(node *lhtml.HtmlNode, model *Model, evaluator *Evaluator) error {
+	key, err := evaluator.GetAttributeValueAsString(node, "key", model)
+	if err != nil {
+		return err
+	}
+
+	args := make(map[string]interface{})
+	expr, err := node.GetAttributeValue("args")
+	if err == nil && strings.TrimSpace(expr) != "" {
+		value, err := evaluator.EvaluateExpression(expr, model)
+		if err != nil {
+			return err
+		}
+
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return errors.New("Arguments of message '" + key + "' must be an object")
+		}
+
+		for name, item := range values {
+			args[name] = escapeMessageArgument(item)
+		}
+	}
+
+	expr, err = node.GetAttributeValue("count")
+	if err == nil && strings.TrimSpace(expr) != "" {
+		count, err := evaluator.EvaluateExpression(expr, model)
+		if err != nil {
+			return err
+		}
+		args["count"] = escapeMessageArgument(count)
+	}
+
+	locale := evaluator.Locale()
+	if node.GetAttribute("locale") != nil || node.GetAttribute(PREFIX+"locale") != nil {
+		locale, err = evaluator.GetAttributeValueAsString(node, "locale", model)
+		if err != nil {
+			return err
+		}
+	}
+
+	text, err := evaluator.processor.Translate(locale, key, args)
+	if err != nil {
+		return err
+	}
+
+	evaluator.builder.WriteString(text)
+	return nil
+}
+
+//
+// Escape a placeholder value of a message, which is model data, while
+// numbers are kept as they are to select the plural form.
+//
+func escapeMessageArgument(value interface{}) interface{} {
+	switch value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, bool:
+		return value
+	}
+
+	return html.EscapeString(berry.ConvertToString(value))
+}
+
+//
+// Write a number formatted for the locale of the merge, such as
+// `1,234.5` in English. The `decimals` attribute fixes the number of
+// fraction digits, and `style="percent"` writes a fraction as a
+// percentage.
+//
+//  <formatNumber value="order.weight" />
+//  <formatNumber value="order.discount" style="percent" decimals="1" />
+//
+func FormatNumberTag(node *lhtml.HtmlNode, model *Model, evaluator *Evaluator) error {
+	value, err := evaluateValueAttribute(node, model, evaluator)
+	if err != nil {
+		return err
+	}
+
+	decimals := -1
+	attr := node.GetAttribute("decimals")
+	if attr != nil {
+		decimals, err = strconv.Atoi(strings.TrimSpace(attr.Value))
+		if err != nil {
+			return errors.New("Attribute 'decimals' must be a number")
+		}
+	}
+
+	var result string
+	style, _ := node.GetAttributeValue("style")
+	switch strings.TrimSpace(style) {
+	case "", "decimal":
+		result, err = FormatNumber(evaluator.Locale(), value, decimals)
+
+	case "percent":
+		result, err = FormatPercent(evaluator.Locale(), value, decimals)
+
+	default:
+		return errors.New("Unknown number style: " + style)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	evaluator.builder.WriteString(result)
+	return nil
+}
+
+//
+// Write an amount of money in the given currency, formatted for the
+// locale of the merge, such as `€1,234.50` in English and `1.234,50 €`
+// in German.
+//
+//  <formatCurrency value="cart.total" code="EUR" />
+//  <formatCurrency value="cart.total" expr:code="cart.currency" />
+//
+func FormatCurrencyTag(node *lhtml.HtmlNode, model *Model, evaluator *Evaluator) error {
+	value, err := evaluateValueAttribute(node, model, evaluator)
+	if err != nil {
+		return err
+	}
+
+	code, err := evaluator.GetAttributeValueAsString(node, "code", model)
+	if err != nil {
+		return err
+	}
+
+	result, err := FormatCurrency(evaluator.Locale(), value, code)
+	if err != nil {
+		return err
+	}
+
+	evaluator.builder.WriteString(result)
+	return nil
+}
+
+//
+// Write a date formatted for the locale and in the time zone of the
+// merge, using a CLDR pattern or one of the styles `short`, `medium`,
+// `long` and `full`. See `FormatDate` for the pattern fields.
+//
+//  <formatDate value="order.placed" />
+//  <formatDate value="order.placed" pattern="EEEE, d MMMM y HH:mm" />
+//
+func FormatDateTag(node *lhtml.HtmlNode, model *Model, evaluator *Evaluator) error {
+	value, err := evaluateValueAttribute(node, model, evaluator)
+	if err != nil {
+		return err
+	}
+
+	date, err := evaluator.toLocalTime(value)
+	if err != nil {
+		return err
+	}
+
+	pattern := ""
+	if node.GetAttribute("pattern") != nil || node.GetAttribute(PREFIX+"pattern") != nil {
+		pattern, err = evaluator.GetAttributeValueAsString(node, "pattern", model)
+		if err != nil {
+			return err
+		}
+	}
+
+	evaluator.builder.WriteString(FormatDate(evaluator.Locale(), date, pattern))
+	return nil
+}
+
+//
+// Write a date relative to now in the locale of the merge, such as
+// `in 3 days` or `2 hours ago` in English. The optional `now`
+// expression gives the time to compare with.
+//
+//  <formatRelativeTime value="comment.posted" />
+//
+func FormatRelativeTimeTag(node *lhtml.HtmlNode, model *Model, evaluator *Evaluator) error {
+	value, err := evaluateValueAttribute(node, model, evaluator)
+	if err != nil {
+		return err
+	}
+
+	date, err := evaluator.toLocalTime(value)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expr, err := node.GetAttributeValue("now")
+	if err == nil && strings.TrimSpace(expr) != "" {
+		value, err := evaluator.EvaluateExpression(expr, model)
+		if err != nil {
+			return err
+		}
+
+		now, err = evaluator.toLocalTime(value)
+		if err != nil {
+			return err
+		}
+	}
+
+	evaluator.builder.WriteString(FormatRelativeTime(evaluator.Locale(), date, now))
+	return nil
+}
+
+//
+// Evaluate the expression of the `value` attribute.
+//
+func evaluateValueAttribute(node *lhtml.HtmlNode, model *Model, evaluator *Evaluator) (interface{}, error) {
+	expr, err := node.GetAttributeValue("value")
+	if err != nil {
+		return nil, err
+	}
+
+	return evaluator.EvaluateExpression(expr, model)
+}
+
+//
+// Isolate text whose direction may differ from the page, such as user
+// names and comments, so that it does not garble the text around it.
+// The text is the value of the `var` expression, which is HTML-escaped,
+// or else the body of the tag. It is wrapped in a `<bdi>` element, or,
+// with `mode="unicode"`, in Unicode isolate characters that also work
+// where markup does not, such as in the `title` of an element. The
+// direction is detected from the text unless `dir` is `ltr` or `rtl`.
+//
+//  <bidi var="comment.author" />
+//  <bidi dir="rtl"><get var="title" /></bidi>
+//
+func BidiTag(node *lhtml.HtmlNode, model *Model, evaluator *Evaluator) error {
+	dir := DirectionAuto
+	attr := node.GetAttribute("dir")
+	if attr != nil && strings.TrimSpace(attr.Value) != "" {
+		dir = strings.ToLower(strings.TrimSpace(attr.Value))
+	}
+
+	if dir != DirectionAuto && dir != DirectionLeftToRight && dir != DirectionRightToLeft {
+		return errors.New("Unknown direction: " + dir)
+	}
+
+	var start, end string
+	mode, _ := node.GetAttributeValue("mode")
+	switch strings.TrimSpace(mode) {
+	case "", "markup":
+		start, end = "<bdi>", "</bdi>"
+		if dir != DirectionAuto {
+			start = "<bdi dir=\"" + dir + "\">"
+		}
+
+	case "unicode":
+		// first strong isolate, or left-to-right and right-to-left isolate
+		start, end = "\u2068", "\u2069"
+		switch dir {
+		case DirectionLeftToRight:
+			start = "\u2066"
+
+		case DirectionRightToLeft:
+			start = "\u2067"
+		}
+
+	default:
+		return errors.New("Unknown bidi mode: " + mode)
+	}
+
+	expr, err := node.GetAttributeValue("var")
+	if err == nil && strings.TrimSpace(expr) != "" {
+		value, err := evaluator.EvaluateExpressionAsString(expr, model)
+		if err != nil {
+			return err
+		}
+
+		evaluator.builder.WriteString(start)
+		evaluator.builder.WriteString(html.EscapeString(value))
+		evaluator.builder.WriteString(end)
+		return nil
+	}
+
+	evaluator.builder.WriteString(start)
+	err = evaluator.EvaluateNodes(node.Children(), model)
+	evaluator.builder.WriteString(end)
+	return err
+}
+
+//
+// Build a URL from a path or a named route of the processor, with the
+// parameters given by the `params` expression. Parameters fill the
+// placeholders of the path, such as `{id}`, and all others are added to
+// the query string. The base path of the processor is prefixed to
+// absolute paths. The URL is written HTML-escaped, or stored in the
+// model under the name given by `var`.
+//
+//  <url path="/search" params='{"q": query, "page": 2}' />
+//  <url route="product" params='{"id": product.id}' var="link" />
+//
+func URLTag(node *lhtml.HtmlNode, model *Model, evaluator *Evaluator) error {
+	params := make(map[string]interface{})
+	expr, err := node.GetAttributeValue("params")
+	if err == nil && strings.TrimSpace(expr) != "" {
+		value, err := evaluator.EvaluateExpression(expr, model)
+		if err != nil {
+			return err
+		}
+
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return errors.New("URL parameters must be an object")
+		}
+		params = values
+	}
+
+	hasRoute := node.GetAttribute("route") != nil || node.GetAttribute(PREFIX+"route") != nil
+	hasPath := node.GetAttribute("path") != nil || node.GetAttribute(PREFIX+"path") != nil
+	if hasRoute == hasPath {
+		return errors.New("Tag <" + node.NodeName() + "> requires either a 'path' or a 'route' attribute")
+	}
+
+	var result string
+	if hasRoute {
+		name, err := evaluator.GetAttributeValueAsString(node, "route", model)
+		if err != nil {
+			return err
+		}
+		result, err = evaluator.processor.BuildRoute(name, params)
+		if err != nil {
+			return err
+		}
+	} else {
+		path, err := evaluator.GetAttributeValueAsString(node, "path", model)
+		if err != nil {
+			return err
+		}
+		result, err = evaluator.processor.BuildURL(path, params)
+		if err != nil {
+			return err
+		}
+	}
+
+	variableName, err := node.GetAttributeValue("var")
+	if err == nil && strings.TrimSpace(variableName) != "" {
+		if model.IsFrozen() {
+			return ErrModelFrozen
+		}
+
+		model.Put(strings.TrimSpace(variableName), result)
+		return nil
+	}
+
+	evaluator.builder.WriteString(html.EscapeString(result))
+	return nil
+}
+
+//
+// Write a `<script>` element for the asset whose logical name is given
+// by `src`, with its fingerprinted URL and Subresource Integrity hash.
+// All other attributes are written as they are.
+//
+//  <asset:script src="app.js" defer />
+//
+func AssetScriptTag(node *lhtml.HtmlNode, model *Model, evaluator *Evaluator) error {
+	return writeAssetElement(node, model, evaluator, "script", "src")
+}
+
+//
+// Write a stylesheet `<link>` element for the asset whose logical name
+// is given by `href`, with its fingerprinted URL and Subresource
+// Integrity hash. All other attributes are written as they are.
+//
+//  <asset:style href="app.css" media="screen" />
+//
+func AssetStyleTag(node *lhtml.HtmlNode, model *Model, evaluator *Evaluator) error {
+	return writeAssetElement(node, model, evaluator, "link", "href")
+}
+
+//
+// Write the element for an asset. The `crossorigin` attribute that
+// browsers require to check the integrity of files from other origins
+// is added, unless present.
+//
+func writeAssetElement(node *lhtml.HtmlNode, model *Model, evaluator *Evaluator, elementName string, urlAttribute string) error {
+	name, err := evaluator.GetAttributeValueAsString(node, urlAttribute, model)
+	if err != nil {
+		return err
+	}
+
+	result, asset, err := evaluator.processor.resolveAsset(name)
+	if err != nil {
+		return err
+	}
+
+	builder := evaluator.builder
+	builder.WriteString("<" + elementName)
+	if elementName == "link" && !node.HasAttribute("rel") && !node.HasAttribute(PREFIX+"rel") {
+		builder.WriteString(" rel=\"stylesheet\"")
+	}
+	builder.WriteString(" " + urlAttribute + "=\"" + html.EscapeString(result) + "\"")
+
+	hasCrossOrigin := false
+	for _, attr := range node.Attributes {
+		attributeName := attr.Name
+		value := attr.Value
+
+		if strings.HasPrefix(attributeName, PREFIX) {
+			value, err = evaluator.EvaluateExpressionAsString(value, model)
+			if err != nil {
+				return err
+			}
+			attributeName = strings.TrimPrefix(attributeName, PREFIX)
+		}
+
+		if attributeName == urlAttribute || attributeName == "integrity" {
+			continue
+		}
+
+		if attributeName == "crossorigin" {
+			hasCrossOrigin = true
+		}
+
+		builder.WriteString(" " + attributeName + "=\"" + html.EscapeString(value) + "\"")
+	}
+
+	if asset.Integrity != "" {
+		builder.WriteString(" integrity=\"" + html.EscapeString(asset.Integrity) + "\"")
+		if !hasCrossOrigin {
+			builder.WriteString(" crossorigin=\"anonymous\"")
+		}
+	}
+
+	if elementName == "script" {
+		builder.WriteString("></script>")
+	} else {
+		builder.WriteString(" />")
+	}
+
+	return nil
+}
+
+//
+// Write user-authored HTML, given by the `var` expression, with only
+// the markup that the named sanitize policy allows. The policy is one
+// registered with the processor, or `basic` or `strict`.
+//
+//  <sanitize var="comment.body" policy="basic" />
+//
+func SanitizeTag(node *lhtml.HtmlNode, model *Model, evaluator *Evaluator) error {
+	name := SanitizePolicyBasic
+	attr := node.GetAttribute("policy")
+	if attr != nil && strings.TrimSpace(attr.Value) != "" {
+		name = strings.TrimSpace(attr.Value)
+	}
+
+	policy, exists := evaluator.processor.GetSanitizePolicy(name)
+	if !exists {
+		return errors.New("Sanitize policy not found: " + name)
+	}
+
+	expr, err := node.GetAttributeValue("var")
+	if err != nil {
+		return err
+	}
+
+	value, err := evaluator.EvaluateExpressionAsString(expr, model)
+	if err != nil {
+		return err
+	}
+
+	result, err := policy.Sanitize(value)
+	if err != nil {
+		return err
+	}
+
+	evaluator.builder.WriteString(result)
+	return nil
+}
+
+//
+// Convert Markdown to HTML, using the Markdown options of the processor.
+// The Markdown is the value of the `var` expression, or else the body of
+// the tag as written in the template, with custom tags replaced by their
+// output and the common indentation removed. The `policy` attribute
+// names a sanitize policy, such as `markdown`, to sanitize the result
+// with, including embedded HTML.
+//
+//  <markdown var="post.body" />
+//  <markdown policy="markdown">
+//    # Hello <get var="user.name" />
+//  </markdown>
+//
+func MarkdownTag(node *lhtml.HtmlNode, model *Model, evaluator *Evaluator) error {
+	options := evaluator.processor.GetMarkdownOptions()
+	if options == nil {
+		options = NewMarkdownOptions()
+	}
+
+	attr := node.GetAttribute("policy")
+	if attr != nil && strings.TrimSpace(attr.Value) != "" {
+		name := strings.TrimSpace(attr.Value)
+		policy, exists := evaluator.processor.GetSanitizePolicy(name)
+		if !exists {
+			return errors.New("Sanitize policy not found: " + name)
+		}
+
+		copied := *options
+		copied.Sanitize = policy
+		options = &copied
+	}
+
+	var source string
+	expr, err := node.GetAttributeValue("var")
+	if err == nil && strings.TrimSpace(expr) != "" {
+		source, err = evaluator.EvaluateExpressionAsString(expr, model)
+	} else {
+		source, err = evaluator.captureMarkdown(node, model)
+		source = dedent(source)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	result, err := RenderMarkdown(source, options)
+	if err != nil {
+		return err
+	}
+
+	evaluator.builder.WriteString(result)
+	return nil
+}
+
+//
+// Write the value of the `var` expression as JSON that is safe to
+// embed in HTML, such as for the data of front-end widgets. With an
+// `id`, the JSON is written as a `<script type="application/json">`
+// block that scripts can read by its id.
+//
+//  <json var="cart" />
+//  <json var="cart" id="cart-data" />
+//
+func JSONTag(node *lhtml.HtmlNode, model *Model, evaluator *Evaluator) error {
+	expr, err := node.GetAttributeValue("var")
+	if err != nil {
+		return err
+	}
+
+	value, err := evaluator.EvaluateExpression(expr, model)
+	if err != nil {
+		return err
+	}
+
+	result, err := EncodeJSON(value)
+	if err != nil {
+		return err
+	}
+
+	if node.GetAttribute("id") == nil && node.GetAttribute(PREFIX+"id") == nil {
+		evaluator.builder.WriteString(result)
+		return nil
+	}
+
+	id, err := evaluator.GetAttributeValueAsString(node, "id", model)
+	if err != nil {
+		return err
+	}
+
+	builder := evaluator.builder
+	builder.WriteString("<script type=\"application/json\" id=\"" + html.EscapeString(id) + "\">")
+	builder.WriteString(result)
+	builder.WriteString("</script>")
 	return nil
 }
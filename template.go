@@ -0,0 +1,341 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/sangupta/lhtml"
+	"golang.org/x/net/html"
+)
+
+//
+// A parsed template along with its source, so that errors raised
+// while merging can report the location in the template.
+//
+type Template struct {
+	Name     string
+	Source   string
+	elements *lhtml.HtmlElements
+	index    *lineIndex
+	nodes    map[*lhtml.HtmlNode]*nodeSource
+	mutex    sync.Mutex
+	prepared map[*HtmlPageProcessor]bool
+}
+
+//
+// Location of a node inside the template source: the offset of the
+// node, and for element nodes, the raw text of the start tag.
+//
+type nodeSource struct {
+	offset int
+	raw    string
+}
+
+//
+// An error raised while parsing or merging a template, along with
+// the location inside the template where it happened.
+//
+type TemplateError struct {
+	Position Position
+	Snippet  string
+	Err      error
+}
+
+//
+// Return the error message prefixed with the location, followed
+// by the snippet of the template, if available.
+//
+func (err *TemplateError) Error() string {
+	message := err.Err.Error()
+	if err.Position.IsValid() || err.Position.File != "" {
+		message = err.Position.String() + ": " + message
+	}
+
+	if err.Snippet != "" {
+		message += "\n" + err.Snippet
+	}
+
+	return message
+}
+
+//
+// Return the wrapped error.
+//
+func (err *TemplateError) Unwrap() error {
+	return err.Err
+}
+
+//
+// Parse the given template source. The name is used when reporting
+// positions, and is usually the file name of the template.
+//
+func ParseTemplate(name string, source string) (*Template, error) {
+	template := &Template{
+		Name:   name,
+		Source: source,
+		index:  newLineIndex(source),
+		nodes:  make(map[*lhtml.HtmlNode]*nodeSource),
+	}
+
+	elements, err := lhtml.ParseHtmlString(source)
+	if err != nil {
+		return nil, template.newError(findParseErrorOffset(source), err)
+	}
+
+	template.elements = elements
+	template.locateNodes()
+	return template, nil
+}
+
+//
+// Return the parsed elements of the template.
+//
+func (template *Template) Elements() *lhtml.HtmlElements {
+	return template.elements
+}
+
+//
+// Prepare the template to be merged by the given processor, by
+// adding the default attribute values of custom tags to the nodes.
+// This happens once per processor, before the first merge, so that
+// the nodes are not modified while the template is being merged
+// concurrently.
+//
+func (template *Template) prepare(pageProcessor *HtmlPageProcessor) {
+	template.mutex.Lock()
+	defer template.mutex.Unlock()
+
+	if template.prepared[pageProcessor] {
+		return
+	}
+
+	template.elements.Traverse(func(node *lhtml.HtmlNode) bool {
+		if node.NodeType == lhtml.ElementNode {
+			definition, exists := pageProcessor.GetTagDefinition(node.NodeName())
+			if exists {
+				definition.ApplyDefaults(node)
+			}
+		}
+		return true
+	})
+
+	if template.prepared == nil {
+		template.prepared = make(map[*HtmlPageProcessor]bool)
+	}
+	template.prepared[pageProcessor] = true
+}
+
+//
+// Return the position of the given node inside the template. An
+// invalid position is returned if the node does not belong to
+// this template.
+//
+func (template *Template) PositionOf(node *lhtml.HtmlNode) Position {
+	if template == nil {
+		return Position{}
+	}
+
+	source, exists := template.nodes[node]
+	if !exists {
+		return Position{}
+	}
+
+	return template.position(source.offset)
+}
+
+//
+// Return the position of the value of the given attribute of a
+// node. Falls back to the position of the node if the attribute
+// cannot be found.
+//
+func (template *Template) attributePosition(node *lhtml.HtmlNode, attributeName string) (int, bool) {
+	if template == nil {
+		return 0, false
+	}
+
+	source, exists := template.nodes[node]
+	if !exists {
+		return 0, false
+	}
+
+	if attributeName != "" {
+		valueOffset := findAttributeValueOffset(source.raw, attributeName)
+		if valueOffset >= 0 {
+			return source.offset + valueOffset, true
+		}
+	}
+
+	return source.offset, true
+}
+
+//
+// Return the span of the given element node inside the template: the
+// offsets where its body starts and ends, and where the element ends,
+// after its end tag. The body of a self-closing element is empty.
+//
+func (template *Template) elementSpan(node *lhtml.HtmlNode) (int, int, int, bool) {
+	if template == nil {
+		return 0, 0, 0, false
+	}
+
+	source, exists := template.nodes[node]
+	if !exists || source.raw == "" {
+		return 0, 0, 0, false
+	}
+
+	bodyStart := source.offset + len(source.raw)
+	if strings.HasSuffix(source.raw, "/>") {
+		return bodyStart, bodyStart, bodyStart, true
+	}
+
+	// find the matching end tag
+	name := node.NodeName()
+	depth := 1
+	tokenizer := html.NewTokenizer(strings.NewReader(template.Source[bodyStart:]))
+	offset := bodyStart
+	for {
+		tokenType := tokenizer.Next()
+		start := offset
+		offset += len(tokenizer.Raw())
+
+		switch tokenType {
+		case html.ErrorToken:
+			// not closed, thus the body runs to the end of the template
+			return bodyStart, len(template.Source), len(template.Source), true
+
+		case html.StartTagToken, html.EndTagToken:
+			tagName, _ := tokenizer.TagName()
+			if string(tagName) != name {
+				continue
+			}
+
+			if tokenType == html.StartTagToken {
+				depth++
+				continue
+			}
+
+			depth--
+			if depth == 0 {
+				return bodyStart, start, offset, true
+			}
+		}
+	}
+}
+
+func (template *Template) position(offset int) Position {
+	position := template.index.position(offset)
+	position.File = template.Name
+	return position
+}
+
+//
+// Create a template error for the given offset, along with a
+// snippet of the offending line and a caret under the location.
+//
+func (template *Template) newError(offset int, err error) *TemplateError {
+	if offset < 0 {
+		return &TemplateError{
+			Position: Position{File: template.Name},
+			Err:      err,
+		}
+	}
+
+	position := template.position(offset)
+	return &TemplateError{
+		Position: position,
+		Snippet:  template.index.snippet(position),
+		Err:      err,
+	}
+}
+
+//
+// Walk the template source along with the parsed nodes and record
+// where every element and text node starts. The parser adds element
+// and text nodes in the order of their tokens, thus a pre-order walk
+// of the tree visits them in the same order.
+//
+func (template *Template) locateNodes() {
+	sources := make([]*nodeSource, 0)
+
+	tokenizer := html.NewTokenizer(strings.NewReader(template.Source))
+	offset := 0
+	for {
+		tokenType := tokenizer.Next()
+		raw := string(tokenizer.Raw())
+		start := offset
+		offset += len(raw)
+
+		if tokenType == html.ErrorToken {
+			break
+		}
+
+		switch tokenType {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			if string(name) == "title" {
+				tokenizer.NextIsNotRawText()
+			}
+
+			sources = append(sources, &nodeSource{offset: start, raw: raw})
+
+		case html.TextToken:
+			text := string(tokenizer.Text())
+			if strings.TrimLeft(text, " \t\r\n\f") == "" {
+				continue
+			}
+
+			// skip leading white space so that the position points to the text
+			leading := len(raw) - len(strings.TrimLeft(raw, " \t\r\n\f"))
+			sources = append(sources, &nodeSource{offset: start + leading, raw: ""})
+		}
+	}
+
+	index := 0
+	template.elements.Traverse(func(node *lhtml.HtmlNode) bool {
+		if node.NodeType != lhtml.ElementNode && node.NodeType != lhtml.TextNode {
+			return true
+		}
+
+		if index >= len(sources) {
+			return false
+		}
+
+		template.nodes[node] = sources[index]
+		index++
+		return true
+	})
+}
+
+//
+// Find the offset of the token that the tokenizer fails on, or `-1`
+// if the whole source can be read.
+//
+func findParseErrorOffset(source string) int {
+	tokenizer := html.NewTokenizer(strings.NewReader(source))
+	offset := 0
+
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			if errors.Is(tokenizer.Err(), io.EOF) {
+				return -1
+			}
+			return offset
+		}
+
+		offset += len(tokenizer.Raw())
+	}
+}
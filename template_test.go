@@ -0,0 +1,128 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTemplatePositions(t *testing.T) {
+	source := "<!DOCTYPE html>\n<html>\n  <body>\n    <p>héllo <b>world</b></p>\n  </body>\n</html>"
+	template, err := ParseTemplate("page.html", source)
+	assert.NoError(t, err)
+	assert.Equal(t, "page.html", template.Name)
+
+	html := template.Elements().GetElementsByName("html").First()
+	assert.Equal(t, Position{File: "page.html", Line: 2, Column: 1}, template.PositionOf(html))
+
+	p := template.Elements().GetElementsByName("p").First()
+	assert.Equal(t, Position{File: "page.html", Line: 4, Column: 5}, template.PositionOf(p))
+	assert.Equal(t, Position{File: "page.html", Line: 4, Column: 8}, template.PositionOf(p.First()))
+
+	b := template.Elements().GetElementsByName("b").First()
+	assert.Equal(t, Position{File: "page.html", Line: 4, Column: 14}, template.PositionOf(b))
+	assert.Equal(t, "page.html:4:14", template.PositionOf(b).String())
+
+	assert.False(t, template.PositionOf(nil).IsValid())
+
+	var nilTemplate *Template
+	assert.False(t, nilTemplate.PositionOf(b).IsValid())
+}
+
+func TestParseTemplateError(t *testing.T) {
+	assert.Equal(t, -1, findParseErrorOffset("<div>\n  <span></div>"))
+
+	template := &Template{Name: "bad.html", index: newLineIndex("<div>\n  <span></div>")}
+	err := template.newError(14, errors.New("failed"))
+	assert.Equal(t, Position{File: "bad.html", Line: 2, Column: 9}, err.Position)
+	assert.Equal(t, "  <span></div>\n        ^", err.Snippet)
+	assert.Equal(t, "bad.html:2:9: failed\n  <span></div>\n        ^", err.Error())
+
+	err = template.newError(-1, errors.New("failed"))
+	assert.Equal(t, "bad.html: failed", err.Error())
+}
+
+func TestMergeErrorPosition(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "c")
+	processor.SetStrictMode(true)
+	assert.True(t, processor.IsStrictMode())
+
+	template, err := ParseTemplate("list.html", "<ul>\n\t<li><c:get var=\"user.name\" /></li>\n</ul>")
+	assert.NoError(t, err)
+
+	_, err = processor.MergeTemplate(template, NewModel())
+	assert.Error(t, err)
+
+	var templateError *TemplateError
+	assert.True(t, errors.As(err, &templateError))
+	assert.Equal(t, Position{File: "list.html", Line: 2, Column: 18}, templateError.Position)
+	assert.Equal(t, "list.html:2:18: var error: variable \"user\" does not exist\n\t<li><c:get var=\"user.name\" /></li>\n\t                ^", err.Error())
+
+	// the nodes without an expression point to the tag
+	_, err = processor.MergeHtml("<p>\n <c:if condition='true'><c:else /></c:if></p>", NewModel())
+	assert.True(t, errors.As(err, &templateError))
+	assert.Equal(t, Position{Line: 2, Column: 2}, templateError.Position)
+	assert.Equal(t, "Tag <c:if> does not have a 'then' clause", templateError.Unwrap().Error())
+
+	// attribute expressions on normal nodes
+	_, err = processor.MergeHtml("<a expr:href=\"base + \">x</a>", NewModel())
+	assert.True(t, errors.As(err, &templateError))
+	assert.Equal(t, Position{Line: 1, Column: 15}, templateError.Position)
+
+	// without a template no position is available
+	elements := template.Elements()
+	_, err = processor.Merge(elements, NewModel())
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &templateError))
+
+	_, err = processor.MergeTemplate(nil, NewModel())
+	assert.Error(t, err)
+}
+
+func TestMergeLenientMode(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "c")
+
+	html, err := processor.MergeHtml("<p><c:get var='missing' />ok</p>", NewModel())
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>ok</p>", html)
+
+	// skipped errors are reported as warnings with their location
+	warnings := make([]error, 0)
+	options := &MergeOptions{OnWarning: func(err error) {
+		warnings = append(warnings, err)
+	}}
+
+	html, err = processor.MergeHtmlWithOptions("<p><c:get var='missing' />ok\n<a expr:href='base + 1'>x</a></p>", NewModel(), options)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>ok\n</p>", html)
+	assert.Equal(t, 2, len(warnings))
+
+	var templateError *TemplateError
+	assert.True(t, errors.As(warnings[0], &templateError))
+	assert.Equal(t, Position{Line: 1, Column: 16}, templateError.Position)
+	assert.True(t, errors.As(warnings[1], &templateError))
+	assert.Equal(t, 2, templateError.Position.Line)
+}
+
+func TestMissingAttributeMessage(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	template, _ := ParseTemplate("", "<x />")
+
+	evaluator := &Evaluator{processor: processor, template: template}
+	_, err := evaluator.GetAttributeValue(template.Elements().First(), "title", NewModel())
+	assert.EqualError(t, err, "Missing attribute 'title'")
+}
@@ -0,0 +1,298 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/sangupta/berry"
+)
+
+//
+// A named route, such as `product` for `/products/{id}`.
+//
+type route struct {
+	pattern string
+	parts   []*routePart
+}
+
+//
+// A part of a route pattern: either literal text, or a placeholder
+// that is replaced by a parameter.
+//
+type routePart struct {
+	literal  string
+	param    string
+	wildcard bool
+}
+
+//
+// Add a named route that templates can build URLs for, using the
+// `route` function or the `route` attribute of the `url` tag. The
+// pattern is a path with placeholders for parameters, such as
+// `/products/{id}`. Parameters are escaped as a single path segment,
+// except for wildcards like `{path...}` that may span several segments.
+//
+func (pageProcessor *HtmlPageProcessor) AddRoute(name string, pattern string) (bool, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return false, errors.New("Route name cannot be empty")
+	}
+
+	parts, err := parseRoute(pattern)
+	if err != nil {
+		return false, err
+	}
+
+	_, exists := pageProcessor._routes[name]
+	if exists {
+		return false, errors.New("Route already exists")
+	}
+
+	pageProcessor._routes[name] = &route{
+		pattern: pattern,
+		parts:   parts,
+	}
+	return true, nil
+}
+
+//
+// Return the pattern of the named route, if any.
+//
+func (pageProcessor *HtmlPageProcessor) GetRoute(name string) (string, bool) {
+	named, exists := pageProcessor._routes[name]
+	if !exists {
+		return "", false
+	}
+
+	return named.pattern, true
+}
+
+//
+// Set the base path, or context root, that is prefixed to all URLs
+// built for absolute paths, such as `/shop` when the site is served
+// under `https://example.com/shop/`.
+//
+func (pageProcessor *HtmlPageProcessor) SetBasePath(basePath string) {
+	basePath = strings.TrimRight(strings.TrimSpace(basePath), "/")
+	if basePath != "" && !strings.HasPrefix(basePath, "/") {
+		basePath = "/" + basePath
+	}
+
+	pageProcessor._basePath = basePath
+}
+
+//
+// Return the base path of this processor.
+//
+func (pageProcessor *HtmlPageProcessor) GetBasePath() string {
+	return pageProcessor._basePath
+}
+
+//
+// Build the URL of the named route with the given parameters. The
+// parameters that are not used by the route are added as query
+// parameters.
+//
+//   processor.BuildRoute("product", map[string]interface{}{"id": 42, "tab": "reviews"})
+//   // "/products/42?tab=reviews"
+//
+func (pageProcessor *HtmlPageProcessor) BuildRoute(name string, params map[string]interface{}) (string, error) {
+	named, exists := pageProcessor._routes[name]
+	if !exists {
+		return "", errors.New("Route not found: " + name)
+	}
+
+	return pageProcessor.buildURL(named.parts, params)
+}
+
+//
+// Build a URL from a path, which may contain placeholders such as
+// `/products/{id}`, and parameters. Parameters for placeholders are
+// escaped as path segments, or as query values for placeholders after
+// the `?`, such as `/search?q={term}`. Placeholders in the path, and the
+// segments of wildcard placeholders such as `{path...}`, cannot be `.`
+// or `..`. All other parameters are added to the query string, sorted
+// by name; a slice adds the parameter once for each of its values, and
+// `nil` values are skipped. The base path of the processor is prefixed
+// to absolute paths, while relative paths and URLs with a scheme or
+// host are kept as they are.
+//
+func (pageProcessor *HtmlPageProcessor) BuildURL(path string, params map[string]interface{}) (string, error) {
+	parts, err := parseRoute(path)
+	if err != nil {
+		return "", err
+	}
+
+	return pageProcessor.buildURL(parts, params)
+}
+
+func (pageProcessor *HtmlPageProcessor) buildURL(parts []*routePart, params map[string]interface{}) (string, error) {
+	used := make(map[string]bool)
+	inQuery := false
+
+	builder := strings.Builder{}
+	for _, part := range parts {
+		if part.param == "" {
+			builder.WriteString(part.literal)
+			if strings.Contains(part.literal, "?") {
+				inQuery = true
+			}
+			continue
+		}
+
+		value, exists := params[part.param]
+		if !exists || value == nil {
+			return "", errors.New("Missing URL parameter: " + part.param)
+		}
+		used[part.param] = true
+
+		text := berry.ConvertToString(value)
+		if inQuery {
+			builder.WriteString(url.QueryEscape(text))
+			continue
+		}
+
+		segments := []string{text}
+		if part.wildcard {
+			segments = strings.Split(text, "/")
+		}
+
+		for index, segment := range segments {
+			if segment == "." || segment == ".." {
+				return "", errors.New("URL parameter cannot contain '" + segment + "' segments: " + part.param)
+			}
+			segments[index] = url.PathEscape(segment)
+		}
+		builder.WriteString(strings.Join(segments, "/"))
+	}
+
+	result := builder.String()
+
+	// keep the fragment at the end
+	fragment := ""
+	index := strings.IndexByte(result, '#')
+	if index >= 0 {
+		result, fragment = result[:index], result[index:]
+	}
+
+	query := buildQuery(params, used)
+	if query != "" {
+		if strings.Contains(result, "?") {
+			result += "&" + query
+		} else {
+			result += "?" + query
+		}
+	}
+
+	return pageProcessor.withBasePath(result) + fragment, nil
+}
+
+//
+// Prefix the base path of the processor to an absolute path, keeping
+// relative paths and URLs with a host as they are.
+//
+func (pageProcessor *HtmlPageProcessor) withBasePath(result string) string {
+	if strings.HasPrefix(result, "/") && !strings.HasPrefix(result, "//") {
+		return pageProcessor._basePath + result
+	}
+
+	return result
+}
+
+//
+// Build the query string of the parameters that are not used by the
+// path, sorted by name.
+//
+func buildQuery(params map[string]interface{}, used map[string]bool) string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		if !used[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		value := params[name]
+		if value == nil {
+			continue
+		}
+
+		reflected := reflect.ValueOf(value)
+		if reflected.Kind() == reflect.Slice || reflected.Kind() == reflect.Array {
+			if _, isBytes := value.([]byte); !isBytes {
+				for index := 0; index < reflected.Len(); index++ {
+					item := reflected.Index(index).Interface()
+					if item != nil {
+						pairs = append(pairs, url.QueryEscape(name)+"="+url.QueryEscape(berry.ConvertToString(item)))
+					}
+				}
+				continue
+			}
+		}
+
+		pairs = append(pairs, url.QueryEscape(name)+"="+url.QueryEscape(berry.ConvertToString(value)))
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+//
+// Split a route pattern into literal text and placeholders.
+//
+func parseRoute(pattern string) ([]*routePart, error) {
+	parts := make([]*routePart, 0)
+	rest := pattern
+
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			break
+		}
+
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, errors.New("Unclosed placeholder in URL: " + pattern)
+		}
+		end += start
+
+		if start > 0 {
+			parts = append(parts, &routePart{literal: rest[:start]})
+		}
+
+		name := strings.TrimSpace(rest[start+1 : end])
+		wildcard := strings.HasSuffix(name, "...")
+		name = strings.TrimSuffix(name, "...")
+		if name == "" || strings.ContainsAny(name, "{/") {
+			return nil, errors.New("Invalid placeholder in URL: " + pattern)
+		}
+
+		parts = append(parts, &routePart{param: name, wildcard: wildcard})
+		rest = rest[end+1:]
+	}
+
+	if strings.ContainsRune(rest, '}') {
+		return nil, errors.New("Invalid placeholder in URL: " + pattern)
+	}
+
+	if rest != "" {
+		parts = append(parts, &routePart{literal: rest})
+	}
+
+	return parts, nil
+}
@@ -0,0 +1,158 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildURL(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+
+	tests := []struct {
+		path     string
+		params   map[string]interface{}
+		expected string
+	}{
+		{"/search", nil, "/search"},
+		{"/search", map[string]interface{}{"q": "a&b c", "page": 2}, "/search?page=2&q=a%26b+c"},
+		{"/search?sort=asc", map[string]interface{}{"q": "x"}, "/search?sort=asc&q=x"},
+		{"/search#results", map[string]interface{}{"q": "x"}, "/search?q=x#results"},
+		{"/products/{id}", map[string]interface{}{"id": "a/b c"}, "/products/a%2Fb%20c"},
+		{"/files/{path...}", map[string]interface{}{"path": "docs/a b.pdf"}, "/files/docs/a%20b.pdf"},
+		{"/search?q={term}", map[string]interface{}{"term": "a&b=c+d e", "page": 2}, "/search?q=a%26b%3Dc%2Bd+e&page=2"},
+		{"/users/{name}?tab={tab}", map[string]interface{}{"name": "a b", "tab": "x/y"}, "/users/a%20b?tab=x%2Fy"},
+		{"/tags", map[string]interface{}{"tag": []interface{}{"go", "html", nil}, "skip": nil}, "/tags?tag=go&tag=html"},
+		{"https://example.com/a", map[string]interface{}{"x": true}, "https://example.com/a?x=true"},
+		{"relative/path", nil, "relative/path"},
+	}
+
+	for _, test := range tests {
+		result, err := processor.BuildURL(test.path, test.params)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, result, test.path)
+	}
+
+	_, err := processor.BuildURL("/products/{id}", nil)
+	assert.Error(t, err)
+
+	// placeholders cannot leave their path
+	for _, path := range []string{"../secret", "docs/../../secret", "docs/./a", ".."} {
+		_, err = processor.BuildURL("/files/{path...}", map[string]interface{}{"path": path})
+		assert.Error(t, err, path)
+	}
+
+	_, err = processor.BuildURL("/products/{id}/edit", map[string]interface{}{"id": ".."})
+	assert.Error(t, err)
+
+	_, err = processor.BuildURL("/products/{id", nil)
+	assert.Error(t, err)
+
+	_, err = processor.BuildURL("/products/{}", nil)
+	assert.Error(t, err)
+
+	_, err = processor.BuildURL("/products/id}", nil)
+	assert.Error(t, err)
+}
+
+func TestRoutesAndBasePath(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+
+	added, err := processor.AddRoute("product", "/products/{id}")
+	assert.True(t, added)
+	assert.NoError(t, err)
+
+	_, err = processor.AddRoute("product", "/other")
+	assert.Error(t, err)
+
+	_, err = processor.AddRoute("", "/other")
+	assert.Error(t, err)
+
+	_, err = processor.AddRoute("broken", "/other/{id")
+	assert.Error(t, err)
+
+	pattern, exists := processor.GetRoute("product")
+	assert.True(t, exists)
+	assert.Equal(t, "/products/{id}", pattern)
+
+	result, err := processor.BuildRoute("product", map[string]interface{}{"id": 42, "tab": "reviews"})
+	assert.NoError(t, err)
+	assert.Equal(t, "/products/42?tab=reviews", result)
+
+	_, err = processor.BuildRoute("missing", nil)
+	assert.Error(t, err)
+
+	// base path for absolute paths only
+	processor.SetBasePath("shop/")
+	assert.Equal(t, "/shop", processor.GetBasePath())
+
+	result, _ = processor.BuildRoute("product", map[string]interface{}{"id": 42})
+	assert.Equal(t, "/shop/products/42", result)
+
+	result, _ = processor.BuildURL("//cdn.example.com/a.js", nil)
+	assert.Equal(t, "//cdn.example.com/a.js", result)
+
+	result, _ = processor.BuildURL("about", nil)
+	assert.Equal(t, "about", result)
+
+	processor.SetBasePath("/")
+	assert.Equal(t, "", processor.GetBasePath())
+}
+
+func TestURLTagAndFunctions(t *testing.T) {
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "")
+	processor.Import(URLLibrary(), "")
+	processor.SetStrictMode(true)
+	processor.AddRoute("product", "/products/{id}")
+	processor.SetBasePath("/shop")
+
+	model := NewModel()
+	model.Put("p", map[string]interface{}{"id": 7})
+	model.Put("query", "tea & cake")
+
+	html, err := processor.MergeHtml(`<p><url path="/search" params='{"q": query, "page": 2}' /></p>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<p>/shop/search?page=2&amp;q=tea+%26+cake</p>`, html)
+
+	html, err = processor.MergeHtml(`<p><url route="product" params='{"id": p.id}' var="link" /><a expr:href="link">x</a></p>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<p><a href="/shop/products/7">x</a></p>`, html)
+
+	html, err = processor.MergeHtml(`<p><a expr:href='route("product", {"id": p.id})'>x</a><a expr:href='url("/search", {"q": query})'>y</a></p>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<p><a href="/shop/products/7">x</a><a href="/shop/search?q=tea+%26+cake">y</a></p>`, html)
+
+	html, err = processor.MergeHtml(`<p><a expr:href='url("/about")'>x</a></p>`, model)
+	assert.NoError(t, err)
+	assert.Equal(t, `<p><a href="/shop/about">x</a></p>`, html)
+
+	for _, template := range []string{
+		`<p><url /></p>`,
+		`<p><url path="/a" route="product" /></p>`,
+		`<p><url route="product" /></p>`,
+		`<p><url path="/a" params="query" /></p>`,
+		`<p><get var='url(1)' /></p>`,
+		`<p><get var='url("/a", 1)' /></p>`,
+		`<p><get var='route("missing")' /></p>`,
+		`<p><get var='route()' /></p>`,
+	} {
+		_, err = processor.MergeHtml(template, model)
+		assert.Error(t, err, template)
+	}
+
+	model.Freeze()
+	_, err = processor.MergeHtml(`<p><url path="/a" var="link" /></p>`, model)
+	assert.ErrorIs(t, err, ErrModelFrozen)
+}
@@ -0,0 +1,469 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+//
+// Enum to define the severity of a diagnostic.
+//
+type DiagnosticSeverity uint32
+
+// Enumeration
+const (
+	SeverityError DiagnosticSeverity = iota
+	SeverityWarning
+)
+
+//
+// Return the severity as a lower-case string.
+//
+func (severity DiagnosticSeverity) String() string {
+	if severity == SeverityWarning {
+		return "warning"
+	}
+
+	return "error"
+}
+
+//
+// A single problem found while validating a template.
+//
+type Diagnostic struct {
+	Severity DiagnosticSeverity
+	Position Position
+	Tag      string
+	Message  string
+}
+
+//
+// Return the diagnostic as `line:column: severity: message`.
+//
+func (diagnostic *Diagnostic) String() string {
+	return diagnostic.Position.String() + ": " + diagnostic.Severity.String() + ": " + diagnostic.Message
+}
+
+//
+// Return the prefixes (the part before `:`) of all registered
+// custom tags.
+//
+func (pageProcessor *HtmlPageProcessor) getTagPrefixes() map[string]bool {
+	prefixes := make(map[string]bool)
+	for name := range pageProcessor._tags {
+		index := strings.Index(name, ":")
+		if index > 0 {
+			prefixes[name[:index]] = true
+		}
+	}
+
+	return prefixes
+}
+
+//
+// An element that has been opened but not yet closed.
+//
+type openElement struct {
+	name       string
+	position   Position
+	definition *TagDefinition
+	children   []string
+}
+
+//
+// Keeps the state of a single validation run.
+//
+type templateValidator struct {
+	name        string
+	processor   *HtmlPageProcessor
+	index       *lineIndex
+	prefixes    map[string]bool
+	stack       []*openElement
+	diagnostics []*Diagnostic
+}
+
+//
+// Validate the given HTML template without a model. The following
+// problems are reported:
+//
+//  - unknown tags that use the prefix of a registered custom tag
+//  - usage of custom tags that does not match their definition, such
+//    as missing required attributes or a missing `then` clause
+//  - syntactically invalid expressions in `expr:` attributes and in the
+//    `s:lang` and `s:dir` directives
+//  - unclosed custom tags and misnested closing tags
+//
+// An empty slice is returned if no problems were found.
+//
+func (pageProcessor *HtmlPageProcessor) Validate(template string) []*Diagnostic {
+	return pageProcessor.ValidateTemplate("", template)
+}
+
+//
+// Validate the given HTML template like `Validate`, reporting the
+// given name as the file of every diagnostic.
+//
+func (pageProcessor *HtmlPageProcessor) ValidateTemplate(name string, template string) []*Diagnostic {
+	validator := &templateValidator{
+		name:        name,
+		processor:   pageProcessor,
+		index:       newLineIndex(template),
+		prefixes:    pageProcessor.getTagPrefixes(),
+		diagnostics: make([]*Diagnostic, 0),
+	}
+
+	validator.run(template)
+	return validator.diagnostics
+}
+
+func (validator *templateValidator) run(template string) {
+	tokenizer := html.NewTokenizer(strings.NewReader(template))
+	offset := 0
+
+	for {
+		tokenType := tokenizer.Next()
+		raw := string(tokenizer.Raw())
+		start := offset
+		offset += len(raw)
+
+		switch tokenType {
+		case html.ErrorToken:
+			if !errors.Is(tokenizer.Err(), io.EOF) {
+				validator.report(start, "", "Unable to read template: "+tokenizer.Err().Error())
+			}
+
+			validator.finish(start)
+			return
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			nodeName := string(name)
+			if nodeName == "title" {
+				tokenizer.NextIsNotRawText()
+			}
+
+			attributes := readAttributes(tokenizer)
+			element := validator.startElement(nodeName, raw, start, attributes)
+			if tokenType == html.StartTagToken {
+				validator.stack = append(validator.stack, element)
+			} else {
+				validator.endElement(element, start)
+			}
+
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			validator.closeElement(string(name), start)
+		}
+	}
+}
+
+//
+// Read all attributes of the current tag as name/value pairs.
+//
+func readAttributes(tokenizer *html.Tokenizer) [][2]string {
+	attributes := make([][2]string, 0)
+	for {
+		key, value, more := tokenizer.TagAttr()
+		if key != nil {
+			attributes = append(attributes, [2]string{string(key), string(value)})
+		}
+
+		if !more {
+			return attributes
+		}
+	}
+}
+
+func (validator *templateValidator) startElement(name string, raw string, start int, attributes [][2]string) *openElement {
+	element := &openElement{
+		name:     name,
+		position: validator.position(start),
+	}
+
+	// register with the parent
+	parent := validator.parent()
+	if parent != nil {
+		parent.children = append(parent.children, name)
+	}
+
+	// check for unknown tags
+	isClause := parent != nil && parent.definition != nil && parent.definition.GetChild(name) != nil
+	if !isClause && parent != nil && parent.definition != nil && len(parent.definition.Children) > 0 {
+		validator.report(start, name, "Tag <"+parent.name+"> does not allow child <"+name+">")
+	} else if !isClause && !validator.processor.HasCustomTag(name) {
+		index := strings.Index(name, ":")
+		if index > 0 && validator.prefixes[name[:index]] {
+			validator.report(start, name, "Unknown custom tag <"+name+">")
+		}
+	}
+
+	element.definition, _ = validator.processor.GetTagDefinition(name)
+
+	// check attributes
+	present := make(map[string]bool)
+	for _, attribute := range attributes {
+		attributeName := attribute[0]
+		expressionName := strings.TrimPrefix(attributeName, PREFIX)
+		isExpression := expressionName != attributeName
+		present[expressionName] = true
+
+		var attributeDefinition *AttributeDefinition
+		if element.definition != nil {
+			attributeDefinition = element.definition.GetAttribute(expressionName)
+			if attributeDefinition == nil {
+				validator.reportWarning(start, name, "Tag <"+name+"> does not define attribute '"+expressionName+"'")
+			} else if isExpression && !attributeDefinition.AllowExpression {
+				validator.report(start, name, "Attribute '"+expressionName+"' of tag <"+name+"> does not allow expressions")
+			}
+		}
+
+		if element.definition == nil && validator.processor._rejectInlineHandlers && isEventHandler(attributeName) {
+			validator.report(start, name, "Inline event handler '"+attributeName+"' is not allowed")
+		}
+
+		isDirective := element.definition == nil && (attributeName == LangDirective || attributeName == DirDirective)
+		if isDirective && strings.TrimSpace(attribute[1]) == "" {
+			continue
+		}
+
+		if isExpression || isDirective || (attributeDefinition != nil && attributeDefinition.Type == AttributeExpression) {
+			validator.checkExpression(name, raw, start, attributeName, attribute[1])
+			continue
+		}
+
+		if attributeDefinition != nil {
+			err := attributeDefinition.checkValue(attribute[1])
+			if err != nil {
+				validator.report(start, name, "Attribute '"+attributeName+"' of tag <"+name+"> "+err.Error())
+			}
+		}
+	}
+
+	if element.definition != nil {
+		for _, attributeDefinition := range element.definition.Attributes {
+			if attributeDefinition.Required && attributeDefinition.Default == "" && !present[attributeDefinition.Name] {
+				validator.report(start, name, "Tag <"+name+"> is missing required attribute '"+attributeDefinition.Name+"'")
+			}
+		}
+	}
+
+	return element
+}
+
+//
+// Check the syntax of an expression attribute and report a
+// diagnostic pointing inside the attribute value if it is invalid.
+//
+func (validator *templateValidator) checkExpression(tagName string, raw string, start int, attributeName string, expression string) {
+	err := CheckExpressionSyntax(expression)
+	if err == nil {
+		return
+	}
+
+	offset := start
+	valueOffset := findAttributeValueOffset(raw, attributeName)
+	if valueOffset >= 0 {
+		offset = start + valueOffset
+
+		var syntaxError *ExpressionSyntaxError
+		if errors.As(err, &syntaxError) {
+			offset += syntaxError.Offset
+		}
+	}
+
+	validator.report(offset, tagName, "Invalid expression in attribute '"+attributeName+"': "+err.Error())
+}
+
+//
+// Handle the closing tag with the given name.
+//
+func (validator *templateValidator) closeElement(name string, start int) {
+	top := validator.parent()
+	if top == nil {
+		validator.report(start, name, "Closing tag </"+name+"> has no matching opening tag")
+		return
+	}
+
+	if top.name != name {
+		validator.report(start, name, "Closing tag </"+name+"> does not match opening tag <"+top.name+"> at "+top.position.String())
+
+		// recover if the tag was opened further down the stack
+		for index := len(validator.stack) - 2; index >= 0; index-- {
+			if validator.stack[index].name == name {
+				for len(validator.stack) > index {
+					validator.popElement(start)
+				}
+				return
+			}
+		}
+		return
+	}
+
+	validator.stack = validator.stack[:len(validator.stack)-1]
+	validator.endElement(top, start)
+}
+
+//
+// Pop an element that is implicitly closed because an outer
+// tag was closed.
+//
+func (validator *templateValidator) popElement(start int) {
+	element := validator.parent()
+	validator.stack = validator.stack[:len(validator.stack)-1]
+	validator.reportUnclosed(element)
+	validator.endElement(element, start)
+}
+
+//
+// Run the checks that need all children of the element.
+//
+func (validator *templateValidator) endElement(element *openElement, start int) {
+	if element.definition == nil {
+		return
+	}
+
+	for _, child := range element.definition.Children {
+		if !child.Required {
+			continue
+		}
+
+		found := false
+		for _, name := range element.children {
+			if matchesClause(name, child.Name) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			validator.reportAt(element.position, element.name, "Tag <"+element.name+"> does not have a '"+child.Name+"' clause")
+		}
+	}
+}
+
+//
+// Report all elements left open at the end of the template.
+//
+func (validator *templateValidator) finish(end int) {
+	for len(validator.stack) > 0 {
+		validator.popElement(end)
+	}
+}
+
+func (validator *templateValidator) reportUnclosed(element *openElement) {
+	if !validator.processor.HasCustomTag(element.name) {
+		return
+	}
+
+	validator.reportAt(element.position, element.name, "Custom tag <"+element.name+"> is not closed")
+}
+
+func (validator *templateValidator) parent() *openElement {
+	if len(validator.stack) == 0 {
+		return nil
+	}
+
+	return validator.stack[len(validator.stack)-1]
+}
+
+func (validator *templateValidator) report(offset int, tag string, message string) {
+	validator.reportAt(validator.position(offset), tag, message)
+}
+
+func (validator *templateValidator) position(offset int) Position {
+	position := validator.index.position(offset)
+	position.File = validator.name
+	return position
+}
+
+func (validator *templateValidator) reportWarning(offset int, tag string, message string) {
+	validator.diagnostics = append(validator.diagnostics, &Diagnostic{
+		Severity: SeverityWarning,
+		Position: validator.position(offset),
+		Tag:      tag,
+		Message:  message,
+	})
+}
+
+func (validator *templateValidator) reportAt(position Position, tag string, message string) {
+	validator.diagnostics = append(validator.diagnostics, &Diagnostic{
+		Severity: SeverityError,
+		Position: position,
+		Tag:      tag,
+		Message:  message,
+	})
+}
+
+//
+// Find the byte offset of the value of the given attribute inside
+// the raw text of a tag. Returns `-1` if the attribute cannot be found.
+//
+func findAttributeValueOffset(raw string, name string) int {
+	lower := strings.ToLower(raw)
+	name = strings.ToLower(name)
+
+	from := 0
+	for {
+		index := strings.Index(lower[from:], name)
+		if index < 0 {
+			return -1
+		}
+
+		index += from
+		from = index + len(name)
+
+		// must be preceded by whitespace
+		if index == 0 || !isSpace(lower[index-1]) {
+			continue
+		}
+
+		// must be followed by `=`
+		position := from
+		for position < len(lower) && isSpace(lower[position]) {
+			position++
+		}
+
+		if position >= len(lower) || lower[position] != '=' {
+			continue
+		}
+
+		position++
+		for position < len(lower) && isSpace(lower[position]) {
+			position++
+		}
+
+		if position < len(lower) && (lower[position] == '"' || lower[position] == '\'') {
+			position++
+		}
+
+		return position
+	}
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
+
+func containsString(values []string, value string) bool {
+	for _, existing := range values {
+		if existing == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,115 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getValidationProcessor() *HtmlPageProcessor {
+	processor := NewHtmlPageProcessor()
+	processor.AddCustomTag("c:if", IfElseTag)
+	processor.AddCustomTag("c:get", GetVariableTag)
+	processor.AddCustomTag("c:set", SetVariableTag)
+	processor.AddCustomTag("c:foreach", ForEachTag)
+	return processor
+}
+
+func TestValidateValidTemplate(t *testing.T) {
+	processor := getValidationProcessor()
+	template := `<html>
+	<c:if condition="age > 10">
+		<c:then><c:get var="name" /></c:then>
+		<c:else><span expr:class='css + "-x"'>young</span></c:else>
+	</c:if>
+	<c:foreach collection="items" var="item"><c:get var="item" /></c:foreach>
+</html>`
+
+	diagnostics := processor.Validate(template)
+	assert.Equal(t, 0, len(diagnostics))
+
+	diagnostics = processor.Validate(`<c:if condition="age > 10"><c:then>ok</c:then></c:if>`)
+	assert.Equal(t, 0, len(diagnostics))
+
+	diagnostics = processor.Validate("")
+	assert.Equal(t, 0, len(diagnostics))
+}
+
+func TestValidateUnknownTag(t *testing.T) {
+	processor := getValidationProcessor()
+
+	diagnostics := processor.Validate("<div>\n  <c:iff condition='a'></c:iff><x:unknown /></div>")
+	assert.Equal(t, 1, len(diagnostics))
+	assert.Equal(t, "c:iff", diagnostics[0].Tag)
+	assert.Equal(t, Position{Line: 2, Column: 3}, diagnostics[0].Position)
+	assert.Equal(t, "2:3: error: Unknown custom tag <c:iff>", diagnostics[0].String())
+}
+
+func TestValidateMissingAttributes(t *testing.T) {
+	processor := getValidationProcessor()
+
+	diagnostics := processor.Validate("<c:get /><c:if><c:then /></c:if><c:foreach var='x'></c:foreach><c:set var='a' expr:value='1' />")
+	assert.Equal(t, 3, len(diagnostics))
+	assert.Equal(t, "Tag <c:get> is missing required attribute 'var'", diagnostics[0].Message)
+	assert.Equal(t, "Tag <c:if> is missing required attribute 'condition'", diagnostics[1].Message)
+	assert.Equal(t, "Tag <c:foreach> is missing required attribute 'collection'", diagnostics[2].Message)
+}
+
+func TestValidateInvalidExpression(t *testing.T) {
+	processor := getValidationProcessor()
+
+	diagnostics := processor.Validate("<p>\n<a expr:href=\"base + * 2\">link</a></p>")
+	assert.Equal(t, 1, len(diagnostics))
+	assert.Equal(t, Position{Line: 2, Column: 22}, diagnostics[0].Position)
+
+	diagnostics = processor.Validate("<c:if condition='age >'><c:then /></c:if>")
+	assert.Equal(t, 1, len(diagnostics))
+	assert.Contains(t, diagnostics[0].Message, "Invalid expression in attribute 'condition'")
+
+	// character literals are not supported
+	diagnostics = processor.Validate("<p>\n\t<span expr:class=\"css + '-x'\">young</span></p>")
+	assert.Equal(t, 1, len(diagnostics))
+	assert.Equal(t, Position{Line: 2, Column: 26}, diagnostics[0].Position)
+}
+
+func TestValidateIfWithoutThen(t *testing.T) {
+	processor := getValidationProcessor()
+
+	diagnostics := processor.Validate("<c:if condition='a'><c:else>no</c:else></c:if>")
+	assert.Equal(t, 1, len(diagnostics))
+	assert.Equal(t, "Tag <c:if> does not have a 'then' clause", diagnostics[0].Message)
+
+	diagnostics = processor.Validate("<c:if condition='a' />")
+	assert.Equal(t, 1, len(diagnostics))
+}
+
+func TestValidateNesting(t *testing.T) {
+	processor := getValidationProcessor()
+
+	diagnostics := processor.Validate("<div><c:foreach collection='a' var='b'></div>")
+	assert.Equal(t, 2, len(diagnostics))
+	assert.Equal(t, "Closing tag </div> does not match opening tag <c:foreach> at 1:6", diagnostics[0].Message)
+	assert.Equal(t, "Custom tag <c:foreach> is not closed", diagnostics[1].Message)
+
+	diagnostics = processor.Validate("<div><c:foreach collection='a' var='b'>")
+	assert.Equal(t, 1, len(diagnostics))
+	assert.Equal(t, Position{Line: 1, Column: 6}, diagnostics[0].Position)
+
+	diagnostics = processor.Validate("<div></span></div>")
+	assert.Equal(t, 1, len(diagnostics))
+
+	diagnostics = processor.Validate("</div>")
+	assert.Equal(t, 1, len(diagnostics))
+	assert.Equal(t, "Closing tag </div> has no matching opening tag", diagnostics[0].Message)
+}
@@ -0,0 +1,221 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+//
+// The model key under which the view engine exposes the data of
+// the current request, such as `request.path` or `request.query.page`.
+//
+const RequestModelKey = "request"
+
+//
+// Content type used by the view engine for rendered pages.
+//
+const DefaultContentType = "text/html; charset=utf-8"
+
+//
+// Renders named templates as responses of `net/http` handlers. Templates
+// are loaded using the template loader of the processor, and the page is
+// merged completely before anything is written so that errors can still
+// be reported with the right status code.
+//
+type ViewEngine struct {
+	processor    *HtmlPageProcessor
+	ContentType  string
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+//
+// Create a new view engine that renders templates using the given
+// processor. If the loader is not `nil`, it is set as the template
+// loader of the processor.
+//
+func NewViewEngine(pageProcessor *HtmlPageProcessor, loader TemplateLoader) (*ViewEngine, error) {
+	if pageProcessor == nil {
+		return nil, errors.New("Processor is required to create view engine")
+	}
+
+	if loader != nil {
+		pageProcessor.SetTemplateLoader(loader)
+	}
+
+	if pageProcessor.GetTemplateLoader() == nil {
+		return nil, errors.New("Template loader is required to create view engine")
+	}
+
+	return &ViewEngine{
+		processor:   pageProcessor,
+		ContentType: DefaultContentType,
+	}, nil
+}
+
+//
+// Return the processor used by this view engine.
+//
+func (engine *ViewEngine) Processor() *HtmlPageProcessor {
+	return engine.processor
+}
+
+//
+// Render the named template with the given model as the response
+// with status `200`. See `RenderStatus`.
+//
+func (engine *ViewEngine) Render(w http.ResponseWriter, r *http.Request, name string, model *Model) error {
+	return engine.RenderStatus(w, r, http.StatusOK, name, model)
+}
+
+//
+// Render the named template with the given model as the response with
+// the given status code. The data of the request is available under
+// `RequestModelKey`, in a model layered on top of the given model, which
+// is not changed. If the template does not exist, status `404` is sent,
+// and for all other errors status `500`, using the error handler if one
+// is set. Errors of tags are reported even if the processor is not in
+// strict mode. The error is returned in either case.
+//
+func (engine *ViewEngine) RenderStatus(w http.ResponseWriter, r *http.Request, status int, name string, model *Model) error {
+	page := NewModel()
+	if model != nil {
+		page.Overlay(model)
+	}
+
+	if r != nil {
+		page.Put(RequestModelKey, RequestData(r))
+	}
+
+	// the first error that the processor skips fails the response
+	var warning error
+	options := &MergeOptions{
+		OnWarning: func(err error) {
+			if warning == nil {
+				warning = err
+			}
+		},
+	}
+
+	html, err := engine.processor.MergeNamedWithOptions(name, page, options)
+	if err == nil {
+		err = warning
+	}
+
+	if err != nil {
+		engine.handleError(w, r, err)
+		return err
+	}
+
+	contentType := engine.ContentType
+	if contentType == "" {
+		contentType = DefaultContentType
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(html)))
+	w.WriteHeader(status)
+
+	if r != nil && r.Method == http.MethodHead {
+		return nil
+	}
+
+	_, err = w.Write([]byte(html))
+	return err
+}
+
+//
+// Return a handler that renders the named template. The model function
+// is called for every request to build the model, and may be `nil`.
+//
+func (engine *ViewEngine) Handler(name string, modelFunc func(r *http.Request) (*Model, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var model *Model
+		if modelFunc != nil {
+			var err error
+			model, err = modelFunc(r)
+			if err != nil {
+				engine.handleError(w, r, err)
+				return
+			}
+		}
+
+		engine.Render(w, r, name, model)
+	})
+}
+
+func (engine *ViewEngine) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	if engine.ErrorHandler != nil {
+		engine.ErrorHandler(w, r, err)
+		return
+	}
+
+	// do not leak template details to the client
+	status := StatusCodeOf(err)
+	http.Error(w, http.StatusText(status), status)
+}
+
+//
+// Return the status code that best describes the given render error:
+// `404` for missing templates, and `500` for everything else.
+//
+func StatusCodeOf(err error) int {
+	if errors.Is(err, ErrTemplateNotFound) {
+		return http.StatusNotFound
+	}
+
+	return http.StatusInternalServerError
+}
+
+//
+// Return the data of the request as exposed to templates. Query
+// parameters, headers and cookies are maps from their name to their
+// first value; header names are lower-cased.
+//
+//  request.method, request.path, request.host, request.url
+//  request.query.page, request.headers["user-agent"], request.cookies.session
+//
+func RequestData(r *http.Request) map[string]interface{} {
+	query := make(map[string]interface{})
+	for name, values := range r.URL.Query() {
+		if len(values) > 0 {
+			query[name] = values[0]
+		}
+	}
+
+	headers := make(map[string]interface{})
+	for name, values := range r.Header {
+		if len(values) > 0 {
+			headers[strings.ToLower(name)] = values[0]
+		}
+	}
+
+	cookies := make(map[string]interface{})
+	for _, cookie := range r.Cookies() {
+		if _, exists := cookies[cookie.Name]; !exists {
+			cookies[cookie.Name] = cookie.Value
+		}
+	}
+
+	return map[string]interface{}{
+		"method":  r.Method,
+		"path":    r.URL.Path,
+		"host":    r.Host,
+		"url":     r.URL.String(),
+		"query":   query,
+		"headers": headers,
+		"cookies": cookies,
+	}
+}
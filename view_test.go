@@ -0,0 +1,138 @@
+/**
+ * snowmark - HTML templates for Go.
+ *
+ * MIT License.
+ * Copyright (c) 2022, Sandeep Gupta.
+ * https://github.com/sangupta/snowmark
+ *
+ * Use of this source code is governed by a MIT style license
+ * that can be found in LICENSE file in the code repository:
+ */
+
+package snowmark
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestViewEngine(t *testing.T) *ViewEngine {
+	fsys := fstest.MapFS{
+		"hello.html":  &fstest.MapFile{Data: []byte("<p><get var='greeting' />, <get var='request.query.name' /> from <get var='request.path' /></p>")},
+		"agent.html":  &fstest.MapFile{Data: []byte("<p><get var='request.headers[\"user-agent\"]' />, <get var='request.cookies.session' /></p>")},
+		"broken.html": &fstest.MapFile{Data: []byte("<p><get expr:var='1 +' /></p>")},
+	}
+
+	processor := NewHtmlPageProcessor()
+	processor.Import(StandardLibrary(), "")
+	processor.SetStrictMode(true)
+
+	engine, err := NewViewEngine(processor, NewFileTemplateLoader(fsys))
+	assert.NoError(t, err)
+	return engine
+}
+
+func TestViewEngineRender(t *testing.T) {
+	engine := newTestViewEngine(t)
+
+	model := NewModel()
+	model.Put("greeting", "Hello")
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/greet?name=world", nil)
+	err := engine.Render(recorder, request, "hello.html", model)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/html; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "<p>Hello, world from /greet</p>", recorder.Body.String())
+}
+
+func TestViewEngineRequestData(t *testing.T) {
+	engine := newTestViewEngine(t)
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("User-Agent", "tester")
+	request.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+
+	err := engine.RenderStatus(recorder, request, http.StatusCreated, "agent.html", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	assert.Equal(t, "<p>tester, abc</p>", recorder.Body.String())
+}
+
+func TestViewEngineErrors(t *testing.T) {
+	engine := newTestViewEngine(t)
+
+	recorder := httptest.NewRecorder()
+	err := engine.Render(recorder, httptest.NewRequest(http.MethodGet, "/", nil), "missing.html", nil)
+	assert.True(t, errors.Is(err, ErrTemplateNotFound))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	err = engine.Render(recorder, httptest.NewRequest(http.MethodGet, "/", nil), "broken.html", nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.False(t, strings.Contains(recorder.Body.String(), "broken.html"))
+
+	// custom error handler
+	engine.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		w.WriteHeader(http.StatusTeapot)
+	}
+
+	recorder = httptest.NewRecorder()
+	engine.Render(recorder, httptest.NewRequest(http.MethodGet, "/", nil), "missing.html", nil)
+	assert.Equal(t, http.StatusTeapot, recorder.Code)
+
+	_, err = NewViewEngine(NewHtmlPageProcessor(), nil)
+	assert.Error(t, err)
+}
+
+func TestViewEngineLenientErrors(t *testing.T) {
+	engine := newTestViewEngine(t)
+	engine.Processor().SetStrictMode(false)
+
+	recorder := httptest.NewRecorder()
+	err := engine.Render(recorder, httptest.NewRequest(http.MethodGet, "/", nil), "broken.html", nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+}
+
+func TestViewEngineFrozenModel(t *testing.T) {
+	engine := newTestViewEngine(t)
+
+	model := NewModel()
+	model.Put("greeting", "Hello")
+	model.Freeze()
+
+	recorder := httptest.NewRecorder()
+	err := engine.Render(recorder, httptest.NewRequest(http.MethodGet, "/greet?name=world", nil), "hello.html", model)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>Hello, world from /greet</p>", recorder.Body.String())
+
+	// the model of the caller is not changed
+	_, exists := model.Get(RequestModelKey)
+	assert.False(t, exists)
+}
+
+func TestViewEngineHandler(t *testing.T) {
+	engine := newTestViewEngine(t)
+
+	handler := engine.Handler("hello.html", func(r *http.Request) (*Model, error) {
+		model := NewModel()
+		model.Put("greeting", "Hi")
+		return model, nil
+	})
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/x?name=you", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "<p>Hi, you from /x</p>", recorder.Body.String())
+}